@@ -0,0 +1,177 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// syntheticSchemas is the size of the benchmark spec: 80 schemas with five
+// CRUD operations each, 400 operations
+const syntheticSchemas = 80
+
+// writeSyntheticSpec writes a spec of schemas with the CRUD operations of
+// each, like a large real-world API, and returns its path
+func writeSyntheticSpec(tb testing.TB, schemas int) string {
+	tb.Helper()
+
+	var spec strings.Builder
+	spec.WriteString("openapi: 3.0.0\ninfo:\n  title: Synthetic API\n  version: 1.0.0\n")
+	spec.WriteString("components:\n  schemas:\n")
+	for i := range schemas {
+		fmt.Fprintf(&spec, `    Resource%[1]d:
+      type: object
+      required: [name]
+      properties:
+        id:
+          type: string
+          format: uuid
+        name:
+          type: string
+          minLength: 1
+        count:
+          type: integer
+          format: int32
+        tags:
+          type: array
+          items:
+            type: string
+        created_at:
+          type: string
+          format: date-time
+`, i)
+	}
+
+	spec.WriteString("paths:\n")
+	for i := range schemas {
+		fmt.Fprintf(&spec, `  /resources%[1]d:
+    get:
+      operationId: listResource%[1]d
+      tags: [Resource%[1]d]
+      responses:
+        '200':
+          description: Resources
+          content:
+            application/json:
+              schema:
+                type: array
+                items:
+                  $ref: '#/components/schemas/Resource%[1]d'
+    post:
+      operationId: createResource%[1]d
+      tags: [Resource%[1]d]
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/Resource%[1]d'
+      responses:
+        '201':
+          description: Created
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Resource%[1]d'
+  /resources%[1]d/{id}:
+    parameters:
+      - name: id
+        in: path
+        required: true
+        schema:
+          type: string
+    get:
+      operationId: getResource%[1]d
+      tags: [Resource%[1]d]
+      responses:
+        '200':
+          description: Resource
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Resource%[1]d'
+    put:
+      operationId: updateResource%[1]d
+      tags: [Resource%[1]d]
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/Resource%[1]d'
+      responses:
+        '200':
+          description: Updated
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Resource%[1]d'
+    delete:
+      operationId: deleteResource%[1]d
+      tags: [Resource%[1]d]
+      responses:
+        '204':
+          description: Deleted
+`, i)
+	}
+
+	specPath := filepath.Join(tb.TempDir(), "openapi.yaml")
+	require.NoError(tb, os.WriteFile(specPath, []byte(spec.String()), 0644))
+	return specPath
+}
+
+// benchmarkPlan benchmarks planning every component of the synthetic spec
+// with a number of workers
+func benchmarkPlan(b *testing.B, workers int) {
+	cfg := &GenerationConfig{
+		SpecFiles:   []string{writeSyntheticSpec(b, syntheticSchemas)},
+		OutputDir:   filepath.Join(b.TempDir(), "api"),
+		GenTypes:    true,
+		GenServices: true,
+		GenMongo:    true,
+		GenHTTP:     true,
+		InitProject: true,
+		DryRun:      true,
+		Workers:     workers,
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		pipeline, err := NewGenerationPipeline(cfg, templateFS)
+		require.NoError(b, err)
+		pipeline.SetOutput(io.Discard)
+		_, err = pipeline.Plan()
+		require.NoError(b, err)
+	}
+}
+
+func BenchmarkPlan_Sequential(b *testing.B) { benchmarkPlan(b, 1) }
+
+func BenchmarkPlan_Parallel(b *testing.B) { benchmarkPlan(b, 0) }
+
+// BenchmarkPlan_SplitTypes benchmarks planning one types file per schema
+func BenchmarkPlan_SplitTypes(b *testing.B) {
+	cfg := &GenerationConfig{
+		SpecFiles:  []string{writeSyntheticSpec(b, syntheticSchemas)},
+		OutputDir:  filepath.Join(b.TempDir(), "api"),
+		GenTypes:   true,
+		SplitTypes: true,
+		DryRun:     true,
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		pipeline, err := NewGenerationPipeline(cfg, templateFS)
+		require.NoError(b, err)
+		pipeline.SetOutput(io.Discard)
+		_, err = pipeline.Plan()
+		require.NoError(b, err)
+	}
+}
@@ -1,744 +1,148 @@
 package cli
 
 import (
-	"bytes"
 	"embed"
 	"flag"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
-	"os/exec"
-	"path/filepath"
 	"strings"
-	"text/template"
-
-	"github.com/zeek-r/goapigen/internal/config"
-	"github.com/zeek-r/goapigen/internal/generator"
-	"github.com/zeek-r/goapigen/internal/parser"
 )
 
 //go:embed templates
 var templateFS embed.FS
 
-// Run executes the CLI application
-func Run() {
-	// Command line flags
-	var (
-		specFile    = flag.String("spec", "", "Path to OpenAPI specification file")
-		outputDir   = flag.String("output", ".", "Output directory for generated code")
-		packageName = flag.String("package", config.DefaultAPIPackage, "Package name for generated code")
-		genTypes    = flag.Bool("types", true, "Generate type definitions")
-		genServices = flag.Bool("services", false, "Generate service layer")
-		genMongo    = flag.Bool("mongo", false, "Generate MongoDB repositories")
-		genHTTP     = flag.Bool("http", false, "Generate HTTP handlers")
-		httpPackage = flag.String("http-package", config.DefaultHandlerPackage, "Package name for HTTP handlers")
-		schemaName  = flag.String("schema", "", "Generate code for specific schema (if empty, generates for all schemas)")
-		initProject = flag.Bool("init", false, "Initialize a new project with full directory structure and main.go")
-		overwrite   = flag.Bool("overwrite", false, "Overwrite existing files (default: false)")
-	)
-
-	flag.Parse()
-
-	// Validate inputs
-	if *specFile == "" {
-		fmt.Println("Error: OpenAPI specification file is required")
-		flag.Usage()
-		os.Exit(1)
-	}
-
-	// Parse the OpenAPI spec
-	apiParser, err := parser.NewOpenAPIParser(*specFile)
-	if err != nil {
-		fmt.Printf("Error parsing OpenAPI spec: %v\n", err)
-		os.Exit(1)
-	}
-
-	// Get all schemas or filter by name
-	var schemaNames []string
-	if *schemaName != "" {
-		if _, exists := apiParser.GetSchemaByName(*schemaName); exists {
-			schemaNames = []string{*schemaName}
-		} else {
-			fmt.Printf("Error: Schema %q not found in spec\n", *schemaName)
-			os.Exit(1)
-		}
-	} else {
-		schemas := apiParser.GetSchemas()
-		schemaNames = make([]string, 0, len(schemas))
-		for name := range schemas {
-			schemaNames = append(schemaNames, name)
-		}
-	}
-
-	// Create output directory if it doesn't exist
-	if err := os.MkdirAll(*outputDir, 0755); err != nil {
-		fmt.Printf("Error creating output directory: %v\n", err)
-		os.Exit(1)
-	}
-
-	// Check if go.mod exists in output directory, if not run go mod init
-	goModPath := filepath.Join(*outputDir, config.GoModFile)
-	var targetModuleName string
-
-	if _, err := os.Stat(goModPath); os.IsNotExist(err) {
-		// No go.mod found, run go mod init
-		fmt.Printf("No go.mod found in output directory. Running go mod init...\n")
-
-		// Use the directory name as the module name
-		moduleName := filepath.Base(*outputDir)
-		cmd := exec.Command("go", "mod", "init", moduleName)
-		cmd.Dir = *outputDir
-		if err := cmd.Run(); err != nil {
-			fmt.Printf("Error running go mod init: %v\n", err)
-			os.Exit(1)
-		}
-
-		// Read the module name from the created go.mod
-		detectedModule, err := getModuleNameFromPath(*outputDir)
-		if err != nil {
-			fmt.Printf("Warning: couldn't determine Go module name after go mod init: %v. Using fallback.\n", err)
-			targetModuleName = "generated-api"
-		} else {
-			targetModuleName = detectedModule
-		}
-	} else {
-		// go.mod exists, read the module name from it
-		detectedModule, err := getModuleNameFromPath(*outputDir)
-		if err != nil {
-			fmt.Printf("Warning: couldn't determine Go module name from %s: %v. Using fallback.\n", goModPath, err)
-			targetModuleName = "generated-api"
-		} else {
-			targetModuleName = detectedModule
-		}
-	}
-
-	// Build import paths
-	importPath := targetModuleName
-
-	// Initialize full project structure if requested
-	if *initProject {
-		fmt.Println("Initializing project structure...")
-
-		// Create all necessary directories
-		dirsToCreate := []string{
-			*outputDir,
-			filepath.Join(*outputDir, config.InternalDir),
-			filepath.Join(*outputDir, config.PkgDir),
-			filepath.Join(*outputDir, config.DomainDir),
-			filepath.Join(*outputDir, config.ServicesDir),
-			filepath.Join(*outputDir, config.AdaptersDir),
-			filepath.Join(*outputDir, config.HttpAdaptersDir),
-			filepath.Join(*outputDir, config.MongoAdaptersDir),
-			filepath.Join(*outputDir, config.HttpUtilDir),
-		}
-
-		for _, dir := range dirsToCreate {
-			if err := os.MkdirAll(dir, 0755); err != nil {
-				fmt.Printf("Error creating directory %s: %v\n", dir, err)
-				os.Exit(1)
-			}
-		}
-
-		// Generate main.go file
-		mainGen, err := generator.NewMainGenerator(apiParser, importPath, templateFS)
-		if err != nil {
-			fmt.Printf("Error creating main generator: %v\n", err)
-			os.Exit(1)
-		}
-
-		// Configure main generator
-		mainGen.SetMongoURI("mongodb://localhost:27017")   // Default for now, will be generated in .env
-		mainGen.SetDBName(filepath.Base(targetModuleName)) // Default for now, will be generated in .env
-		mainGen.SetDefaultPort("8080")                     // Default for now, will be generated in .env
-
-		// Create cmd directory
-		cmdDir := filepath.Join(*outputDir, "cmd", filepath.Base(targetModuleName))
-		if err := os.MkdirAll(cmdDir, 0755); err != nil {
-			fmt.Printf("Error creating cmd directory: %v\n", err)
-			os.Exit(1)
-		}
-
-		// Generate main.go and routes.go files with current feature flags
-		hasServices := *genServices || *genHTTP // HTTP handlers need services
-		files, err := mainGen.GenerateWithFeatures(*genMongo, *genMongo, hasServices, *genHTTP)
-		if err != nil {
-			fmt.Printf("Error generating main files: %v\n", err)
-			os.Exit(1)
-		}
-
-		// Write files
-		for filename, content := range files {
-			filePath := filepath.Join(cmdDir, filename)
-
-			// Ensure parent directory exists
-			if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
-				fmt.Printf("Error creating directory for %s: %v\n", filename, err)
-				continue
-			}
-
-			// For main.go, only write if it doesn't exist (stable file)
-			// For routes.go, always overwrite (regenerated file)
-			shouldWrite := *overwrite
-			if strings.HasSuffix(filename, "routes.go") {
-				shouldWrite = true // Always regenerate routes
-			} else if _, err := os.Stat(filePath); os.IsNotExist(err) {
-				shouldWrite = true // Write if doesn't exist
-			}
-
-			if shouldWrite {
-				if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
-					fmt.Printf("Error writing %s: %v\n", filename, err)
-					continue
-				}
-				fmt.Printf("Generated %s\n", filePath)
-			} else {
-				fmt.Printf("%s already exists. Skipping (use --overwrite to force overwrite)\n", filename)
-			}
-		}
-
-		// Add dependencies to go.mod
-		fmt.Println("Adding required dependencies...")
-		deps := []string{
-			"github.com/go-chi/chi/v5",
-			"github.com/go-chi/cors",
-			"github.com/joho/godotenv",
-			"go.mongodb.org/mongo-driver/mongo",
-			"github.com/bool64/ctxd",
-			"github.com/bool64/zapctxd",
-			"github.com/kelseyhightower/envconfig",
-		}
-
-		for _, dep := range deps {
-			cmd := exec.Command("go", "get", dep)
-			cmd.Dir = *outputDir
-			if err := cmd.Run(); err != nil {
-				fmt.Printf("Error adding dependency %s: %v\n", dep, err)
-			}
-		}
-	}
-
-	// Generate types if requested
-	if *genTypes {
-		// Create domain directory
-		domainDir := filepath.Join(*outputDir, config.DomainDir)
-		if err := os.MkdirAll(domainDir, 0755); err != nil {
-			fmt.Printf("Error creating domain directory: %v\n", err)
-			os.Exit(1)
-		}
-
-		typeGen := generator.NewTypeGenerator(apiParser, config.DomainPackage, templateFS)
-		typesCode, err := typeGen.GenerateTypes()
-		if err != nil {
-			fmt.Printf("Error generating types: %v\n", err)
-			os.Exit(1)
-		}
-
-		typesFilePath := filepath.Join(domainDir, config.TypesFile)
-		if _, err := os.Stat(typesFilePath); os.IsNotExist(err) || *overwrite {
-			if err := os.WriteFile(typesFilePath, []byte(typesCode), 0644); err != nil {
-				fmt.Printf("Error writing types file: %v\n", err)
-				os.Exit(1)
-			}
-			fmt.Printf("Generated types in %s\n", typesFilePath)
-		} else {
-			fmt.Printf("Types file already exists. Skipping (use --overwrite to force overwrite)\n")
-		}
-	}
-
-	// Create internal directory structure
-	internalDir := filepath.Join(*outputDir, config.InternalDir)
-	pkgDir := filepath.Join(*outputDir, config.PkgDir)
-	domainDir := filepath.Join(*outputDir, config.DomainDir)
-	servicesDir := filepath.Join(*outputDir, config.ServicesDir)
-	adaptersDir := filepath.Join(*outputDir, config.AdaptersDir)
-
-	// Create domain error types directory
-	if err := os.MkdirAll(domainDir, 0755); err != nil {
-		fmt.Printf("Error creating domain directory: %v\n", err)
-		os.Exit(1)
-	}
-
-	// Generate domain errors from template
-	domainErrorsTemplate, err := template.ParseFS(templateFS, config.DomainErrorsTemplate)
-	if err != nil {
-		fmt.Printf("Error parsing domain errors template: %v\n", err)
-	} else {
-		var buf bytes.Buffer
-		if err := domainErrorsTemplate.Execute(&buf, nil); err != nil {
-			fmt.Printf("Error executing domain errors template: %v\n", err)
-		} else {
-			errorsDest := filepath.Join(domainDir, config.ErrorsFile)
-			// Check if file exists, don't overwrite unless explicitly requested
-			if _, err := os.Stat(errorsDest); os.IsNotExist(err) || *overwrite {
-				if err := os.WriteFile(errorsDest, buf.Bytes(), 0644); err != nil {
-					fmt.Printf("Error writing domain errors file: %v\n", err)
-				} else {
-					fmt.Printf("Generated domain errors in %s\n", errorsDest)
-				}
-			} else {
-				fmt.Printf("Domain errors file already exists. Skipping (use --overwrite to force overwrite)\n")
-			}
-		}
-	}
-
-	// Generate config and logger packages (automatically with --init)
-	if *initProject {
-		// Template data for both config and logger
-		templateData := map[string]interface{}{
-			"ImportPath":  importPath,
-			"ProjectName": filepath.Base(targetModuleName),
-		}
-
-		// Generate config package
-		configDir := filepath.Join(*outputDir, config.ConfigDir)
-		if err := os.MkdirAll(configDir, 0755); err != nil {
-			fmt.Printf("Error creating config directory: %v\n", err)
-		} else {
-			// Generate config.go
-			configTemplate, err := template.ParseFS(templateFS, config.ConfigTemplate)
-			if err != nil {
-				fmt.Printf("Error parsing config template: %v\n", err)
-			} else {
-				var configBuf bytes.Buffer
-				if err := configTemplate.Execute(&configBuf, templateData); err != nil {
-					fmt.Printf("Error executing config template: %v\n", err)
-				} else {
-					configDest := filepath.Join(configDir, config.ConfigFile)
-					if _, err := os.Stat(configDest); os.IsNotExist(err) || *overwrite {
-						if err := os.WriteFile(configDest, configBuf.Bytes(), 0644); err != nil {
-							fmt.Printf("Error writing config file: %v\n", err)
-						} else {
-							fmt.Printf("Generated config in %s\n", configDest)
-						}
-					} else {
-						fmt.Printf("Config file already exists. Skipping (use --overwrite to force overwrite)\n")
-					}
-				}
-			}
-
-			// Generate config_test.go
-			configTestTemplate, err := template.ParseFS(templateFS, config.ConfigTestTemplate)
-			if err != nil {
-				fmt.Printf("Error parsing config test template: %v\n", err)
-			} else {
-				var configTestBuf bytes.Buffer
-				if err := configTestTemplate.Execute(&configTestBuf, templateData); err != nil {
-					fmt.Printf("Error executing config test template: %v\n", err)
-				} else {
-					configTestDest := filepath.Join(configDir, config.ConfigTestFile)
-					if _, err := os.Stat(configTestDest); os.IsNotExist(err) || *overwrite {
-						if err := os.WriteFile(configTestDest, configTestBuf.Bytes(), 0644); err != nil {
-							fmt.Printf("Error writing config test file: %v\n", err)
-						} else {
-							fmt.Printf("Generated config tests in %s\n", configTestDest)
-						}
-					} else {
-						fmt.Printf("Config test file already exists. Skipping (use --overwrite to force overwrite)\n")
-					}
-				}
-			}
-		}
+// Templates returns the embedded templates, for NewGenerationPipeline
+func Templates() fs.FS {
+	return templateFS
+}
 
-		// Generate logger package
-		loggerDir := filepath.Join(*outputDir, config.LoggerDir)
-		if err := os.MkdirAll(loggerDir, 0755); err != nil {
-			fmt.Printf("Error creating logger directory: %v\n", err)
-		} else {
-			// Generate logger.go
-			loggerTemplate, err := template.ParseFS(templateFS, config.LoggerTemplate)
-			if err != nil {
-				fmt.Printf("Error parsing logger template: %v\n", err)
-			} else {
-				var loggerBuf bytes.Buffer
-				if err := loggerTemplate.Execute(&loggerBuf, templateData); err != nil {
-					fmt.Printf("Error executing logger template: %v\n", err)
-				} else {
-					loggerDest := filepath.Join(loggerDir, config.LoggerFile)
-					if _, err := os.Stat(loggerDest); os.IsNotExist(err) || *overwrite {
-						if err := os.WriteFile(loggerDest, loggerBuf.Bytes(), 0644); err != nil {
-							fmt.Printf("Error writing logger file: %v\n", err)
-						} else {
-							fmt.Printf("Generated logger in %s\n", loggerDest)
-						}
-					} else {
-						fmt.Printf("Logger file already exists. Skipping (use --overwrite to force overwrite)\n")
-					}
-				}
-			}
+// generatedHeader starts every file goapigen generates as DO NOT EDIT
+const generatedHeader = "// Code generated by goapigen. DO NOT EDIT."
 
-			// Generate logger_test.go
-			loggerTestTemplate, err := template.ParseFS(templateFS, config.LoggerTestTemplate)
-			if err != nil {
-				fmt.Printf("Error parsing logger test template: %v\n", err)
-			} else {
-				var loggerTestBuf bytes.Buffer
-				if err := loggerTestTemplate.Execute(&loggerTestBuf, templateData); err != nil {
-					fmt.Printf("Error executing logger test template: %v\n", err)
-				} else {
-					loggerTestDest := filepath.Join(loggerDir, config.LoggerTestFile)
-					if _, err := os.Stat(loggerTestDest); os.IsNotExist(err) || *overwrite {
-						if err := os.WriteFile(loggerTestDest, loggerTestBuf.Bytes(), 0644); err != nil {
-							fmt.Printf("Error writing logger test file: %v\n", err)
-						} else {
-							fmt.Printf("Generated logger tests in %s\n", loggerTestDest)
-						}
-					} else {
-						fmt.Printf("Logger test file already exists. Skipping (use --overwrite to force overwrite)\n")
-					}
-				}
-			}
-		}
-	}
+// Exit code of a command line that cannot be parsed
+const exitUsage = 2
 
-	// Generate services for each schema (if explicitly requested or if HTTP handlers need them)
-	if *genServices || *genHTTP {
-		if err := os.MkdirAll(servicesDir, 0755); err != nil {
-			fmt.Printf("Error creating services directory: %v\n", err)
-			os.Exit(1)
-		}
+// stringList collects the values of a repeatable flag
+type stringList []string
 
-		serviceGen, err := generator.NewServiceGenerator(apiParser, *packageName, importPath, templateFS)
-		if err != nil {
-			fmt.Printf("Error creating service generator: %v\n", err)
-		} else {
-			// Generate service for each schema
-			for _, name := range schemaNames {
-				serviceCode, err := serviceGen.GenerateService(name)
-				if err != nil {
-					fmt.Printf("Error generating service for %s: %v\n", name, err)
-					continue
-				}
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
 
-				// Create domain-specific directory for the service
-				schemaServiceDir := filepath.Join(servicesDir, strings.ToLower(name))
-				if err := os.MkdirAll(schemaServiceDir, 0755); err != nil {
-					fmt.Printf("Error creating directory for %s: %v\n", name, err)
-					continue
-				}
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
 
-				// Write service file
-				serviceFilename := strings.ToLower(name) + "_service.go"
-				serviceFilePath := filepath.Join(schemaServiceDir, serviceFilename)
+// command is a goapigen subcommand
+type command struct {
+	name    string
+	summary string
+	run     func(args []string, stdout, stderr io.Writer) int
+}
 
-				// Check if file exists, don't overwrite unless explicitly requested
-				if _, err := os.Stat(serviceFilePath); os.IsNotExist(err) || *overwrite {
-					if err := os.WriteFile(serviceFilePath, []byte(serviceCode), 0644); err != nil {
-						fmt.Printf("Error writing service file for %s: %v\n", name, err)
-						continue
-					}
-					fmt.Printf("Generated service for %s in %s\n", name, serviceFilePath)
-				} else {
-					fmt.Printf("Service file for %s already exists. Skipping (use --overwrite to force overwrite)\n", name)
-				}
+// commands lists the subcommands in the order of the help text
+var commands = []command{
+	{name: "init", summary: "Scaffold a new project: layout, main.go, config, logger and the generated components", run: runInit},
+	{name: "generate", summary: "Generate or regenerate types, services, repositories and HTTP handlers", run: runGenerate},
+	{name: "lint", summary: "Report spec problems that break or degrade the generated code", run: runLint},
+	{name: "diff", summary: "Show what generate would change in the output directory", run: runDiff},
+	{name: "templates", summary: "Export the embedded templates to customize them", run: runTemplates},
+	{name: "version", summary: "Print the version and build information", run: runVersion},
+}
 
-				// Generate service tests
-				serviceTestCode, err := serviceGen.GenerateServiceTests(name)
-				if err != nil {
-					fmt.Printf("Error generating service tests for %s: %v\n", name, err)
-				} else {
-					// Write service test file
-					testFilename := strings.ToLower(name) + "_service_test.go"
-					testFilePath := filepath.Join(schemaServiceDir, testFilename)
+// Run executes the CLI application
+func Run() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
 
-					// Check if file exists, don't overwrite unless explicitly requested
-					if _, err := os.Stat(testFilePath); os.IsNotExist(err) || *overwrite {
-						if err := os.WriteFile(testFilePath, []byte(serviceTestCode), 0644); err != nil {
-							fmt.Printf("Error writing service test file for %s: %v\n", name, err)
-						} else {
-							fmt.Printf("Generated service tests for %s in %s\n", name, testFilePath)
-						}
-					} else {
-						fmt.Printf("Service test file for %s already exists. Skipping (use --overwrite to force overwrite)\n", name)
-					}
-				}
-			}
-		}
+// run dispatches the command line to a subcommand and returns its exit code
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		printUsage(stderr)
+		return exitUsage
 	}
 
-	// Generate MongoDB repositories if requested
-	if *genMongo {
-		// Set up mongo directory
-		mongoDir := filepath.Join(*outputDir, config.MongoAdaptersDir)
-		if err := os.MkdirAll(mongoDir, 0755); err != nil {
-			fmt.Printf("Error creating mongo directory: %v\n", err)
-			os.Exit(1)
-		}
-
-		repoPackage := config.DefaultRepoPackage // Using just 'mongo' as the package name, following Go conventions
-		mongoImportPath := importPath
-
-		mongoGen, err := generator.NewMongoGenerator(apiParser, *packageName, repoPackage, mongoImportPath, templateFS)
-		if err != nil {
-			fmt.Printf("Error creating MongoDB generator: %v\n", err)
-			os.Exit(1)
-		}
-
-		// Generate repository and tests for each schema
-		for _, name := range schemaNames {
-			// Generate repository
-			repoCode, err := mongoGen.GenerateRepository(name)
-			if err != nil {
-				fmt.Printf("Error generating repository for %s: %v\n", name, err)
-				continue
-			}
-
-			// Create domain-specific directory for the repository
-			domainDir := filepath.Join(mongoDir, strings.ToLower(name))
-			if err := os.MkdirAll(domainDir, 0755); err != nil {
-				fmt.Printf("Error creating directory for %s: %v\n", name, err)
-				continue
-			}
-
-			// Write repository file
-			repoFilename := strings.ToLower(name) + "_repository.go"
-			repoFilePath := filepath.Join(domainDir, repoFilename)
-
-			// Check if file exists, don't overwrite unless explicitly requested
-			if _, err := os.Stat(repoFilePath); os.IsNotExist(err) || *overwrite {
-				if err := os.WriteFile(repoFilePath, []byte(repoCode), 0644); err != nil {
-					fmt.Printf("Error writing repository file for %s: %v\n", name, err)
-					continue
-				}
-				fmt.Printf("Generated MongoDB repository for %s in %s\n", name, repoFilePath)
-			} else {
-				fmt.Printf("Repository file for %s already exists. Skipping (use --overwrite to force overwrite)\n", name)
-			}
-
-			// Generate test file
-			testCode, err := mongoGen.GenerateRepositoryTests(name)
-			if err != nil {
-				fmt.Printf("Error generating repository tests for %s: %v\n", name, err)
-			} else {
-				// Write test file to the same domain directory
-				testFilename := strings.ToLower(name) + "_repository_test.go"
-				domainDir := filepath.Join(mongoDir, strings.ToLower(name))
-				testFilePath := filepath.Join(domainDir, testFilename)
-
-				// Check if file exists, don't overwrite unless explicitly requested
-				if _, err := os.Stat(testFilePath); os.IsNotExist(err) || *overwrite {
-					if err := os.WriteFile(testFilePath, []byte(testCode), 0644); err != nil {
-						fmt.Printf("Error writing repository test file for %s: %v\n", name, err)
-					} else {
-						fmt.Printf("Generated repository tests for %s in %s\n", name, testFilePath)
-					}
-				} else {
-					fmt.Printf("Repository test file for %s already exists. Skipping (use --overwrite to force overwrite)\n", name)
-				}
-			}
-		}
+	// Flags without a command are the command line of earlier versions,
+	// where --init selected the scaffolding
+	if strings.HasPrefix(args[0], "-") && args[0] != "-h" && args[0] != "-help" && args[0] != "--help" {
+		return runLegacy(args, stdout, stderr)
 	}
 
-	// Generate HTTP handlers if requested
-	if *genHTTP {
-		// Create internal directory structure if it doesn't exist
-		httpDir := filepath.Join(*outputDir, config.HttpAdaptersDir)
-		httpUtilDir := filepath.Join(*outputDir, config.HttpUtilDir)
-
-		// Create all required directories
-		dirs := []string{internalDir, adaptersDir, httpDir, pkgDir, httpUtilDir}
-		for _, dir := range dirs {
-			if err := os.MkdirAll(dir, 0755); err != nil {
-				fmt.Printf("Error creating directory %s: %v\n", dir, err)
-				os.Exit(1)
-			}
-		}
-
-		// Build import paths
-		modelImportPath := importPath
-		httpImportPath := importPath
-
-		httpGen, err := generator.NewHTTPGenerator(apiParser, *packageName, *httpPackage, httpImportPath, modelImportPath, templateFS)
-		if err != nil {
-			fmt.Printf("Error creating HTTP handler generator: %v\n", err)
-			os.Exit(1)
-		}
-
-		// Generate HTTP handlers
-		handlersCode, err := httpGen.GenerateHandlers()
-		if err != nil {
-			fmt.Printf("Error generating HTTP handlers: %v\n", err)
-			os.Exit(1)
-		}
-
-		// Write handler files
-		for filename, code := range handlersCode {
-			var handlerFilePath string
-
-			// Handle different file types based on prefix
-			if strings.HasPrefix(filename, "httputil/") {
-				// Write to internal/pkg/httputil directory
-				baseFilename := filepath.Base(filename)
-				handlerFilePath = filepath.Join(httpUtilDir, baseFilename)
-			} else if strings.HasPrefix(filename, "domain/") {
-				// Extract domain name from path like "domain/pet/handler.go"
-				parts := strings.Split(filename, "/")
-				if len(parts) >= 2 {
-					domain := parts[1]
-					// Create domain directory if it doesn't exist
-					domainDir := filepath.Join(httpDir, domain)
-					if err := os.MkdirAll(domainDir, 0755); err != nil {
-						fmt.Printf("Error creating domain directory %s: %v\n", domainDir, err)
-						continue
-					}
-
-					// Get filename without domain prefix
-					domainFilename := strings.Join(parts[2:], "/")
-					handlerFilePath = filepath.Join(domainDir, domainFilename)
-				} else {
-					// Fallback if path format is unexpected
-					handlerFilePath = filepath.Join(httpDir, filename)
-				}
-			} else {
-				// Write regular handler file to HTTP adapters directory
-				handlerFilePath = filepath.Join(httpDir, filename)
-			}
-
-			// Ensure parent directory exists
-			parentDir := filepath.Dir(handlerFilePath)
-			if err := os.MkdirAll(parentDir, 0755); err != nil {
-				fmt.Printf("Error creating directory %s: %v\n", parentDir, err)
-				continue
-			}
-
-			// Check if file exists, don't overwrite unless explicitly requested
-			if _, err := os.Stat(handlerFilePath); os.IsNotExist(err) || *overwrite {
-				// Write the file
-				if err := os.WriteFile(handlerFilePath, []byte(code), 0644); err != nil {
-					fmt.Printf("Error writing file %s: %v\n", handlerFilePath, err)
-					continue
-				}
-				fmt.Printf("Generated HTTP handler in %s\n", handlerFilePath)
-			} else {
-				fmt.Printf("HTTP handler file %s already exists. Skipping (use --overwrite to force overwrite)\n", handlerFilePath)
-			}
+	name, rest := args[0], args[1:]
+	switch name {
+	case "-h", "-help", "--help":
+		printUsage(stdout)
+		return 0
+	case "help":
+		if len(rest) == 0 {
+			printUsage(stdout)
+			return 0
 		}
+		// goapigen help <command> is goapigen <command> -h
+		name, rest = rest[0], []string{"-h"}
 	}
 
-	// Regenerate routes.go if any components were generated
-	if *genHTTP || *initProject {
-		// Create main generator for routes update
-		mainGen, err := generator.NewMainGenerator(apiParser, importPath, templateFS)
-		if err != nil {
-			fmt.Printf("Error creating main generator for routes: %v\n", err)
-		} else {
-			// Configure main generator
-			mainGen.SetMongoURI("mongodb://localhost:27017")
-			mainGen.SetDBName(filepath.Base(targetModuleName))
-			mainGen.SetDefaultPort("8080")
-
-			// Generate routes.go with current feature flags
-			// Check if services exist by looking for service files or if services flag was used
-			hasServices := *genServices || *genHTTP // HTTP handlers need services
-			files, err := mainGen.GenerateWithFeatures(*genMongo, *genMongo, hasServices, *genHTTP)
-			if err != nil {
-				fmt.Printf("Error generating routes: %v\n", err)
-			} else {
-				cmdDir := filepath.Join(*outputDir, "cmd", filepath.Base(targetModuleName))
-
-				// Write main.go (only if it doesn't exist, since it's stable)
-				if mainContent, exists := files["main.go"]; exists {
-					mainPath := filepath.Join(cmdDir, "main.go")
-					if _, err := os.Stat(mainPath); os.IsNotExist(err) || *overwrite {
-						if err := os.MkdirAll(cmdDir, 0755); err != nil {
-							fmt.Printf("Error creating directory for main.go: %v\n", err)
-						} else {
-							if err := os.WriteFile(mainPath, []byte(mainContent), 0644); err != nil {
-								fmt.Printf("Error writing main.go: %v\n", err)
-							} else {
-								fmt.Printf("Updated main.go in %s\n", mainPath)
-							}
-						}
-					}
-				}
-
-				// Write routes.go (always overwrite)
-				if routesContent, exists := files["routes.go"]; exists {
-					routesPath := filepath.Join(cmdDir, "routes.go")
-
-					// Ensure directory exists
-					if err := os.MkdirAll(cmdDir, 0755); err != nil {
-						fmt.Printf("Error creating directory for routes.go: %v\n", err)
-					} else {
-						if err := os.WriteFile(routesPath, []byte(routesContent), 0644); err != nil {
-							fmt.Printf("Error writing routes.go: %v\n", err)
-						} else {
-							fmt.Printf("Updated routes.go in %s\n", routesPath)
-						}
-					}
-				}
-
-				// Write database.go (always overwrite)
-				if databaseContent, exists := files["database.go"]; exists {
-					databasePath := filepath.Join(cmdDir, "database.go")
-
-					// Ensure directory exists
-					if err := os.MkdirAll(cmdDir, 0755); err != nil {
-						fmt.Printf("Error creating directory for database.go: %v\n", err)
-					} else {
-						if err := os.WriteFile(databasePath, []byte(databaseContent), 0644); err != nil {
-							fmt.Printf("Error writing database.go: %v\n", err)
-						} else {
-							fmt.Printf("Updated database.go in %s\n", databasePath)
-						}
-					}
-				}
-			}
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd.run(rest, stdout, stderr)
 		}
 	}
+	fmt.Fprintf(stderr, "Error: unknown command %q\n\n", name)
+	printUsage(stderr)
+	return exitUsage
+}
 
-	// Generate .env file
-	if *initProject {
-		envTemplate, err := template.ParseFS(templateFS, config.EnvTemplate)
-		if err != nil {
-			fmt.Printf("Error parsing .env template: %v\n", err)
-		} else {
-			var buf bytes.Buffer
-			dbNameDefault := filepath.Base(targetModuleName)
-			if dbNameDefault == "." {
-				dbNameDefault = "api"
-			}
-
-			if err := envTemplate.Execute(&buf, map[string]string{
-				"MongoURI":    "mongodb://localhost:27017",
-				"DBName":      dbNameDefault,
-				"DefaultPort": "8080",
-			}); err != nil {
-				fmt.Printf("Error executing .env template: %v\n", err)
-			} else {
-				envPath := filepath.Join(*outputDir, config.EnvFile)
-				if _, err := os.Stat(envPath); os.IsNotExist(err) || *overwrite {
-					if err := os.WriteFile(envPath, buf.Bytes(), 0644); err != nil {
-						fmt.Printf("Error writing .env file: %v\n", err)
-					} else {
-						fmt.Printf("Generated .env file in %s\n", envPath)
-					}
-				} else {
-					fmt.Printf(".env file already exists. Skipping (use --overwrite to force overwrite)\n")
-				}
-			}
-		}
-	}
+// printUsage prints the list of commands
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "goapigen generates Go services from OpenAPI specifications.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Usage: goapigen <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `Run "goapigen help <command>" for the flags and exit codes of a command.`)
 }
 
-// getModuleNameFromPath attempts to determine the Go module name from go.mod in specified directory
-func getModuleNameFromPath(dir string) (string, error) {
-	// Try to open go.mod in the specified directory
-	goModPath := filepath.Join(dir, "go.mod")
-	data, err := os.ReadFile(goModPath)
-	if err != nil {
-		return "", err
+// newFlagSet creates the flag set of a command. Its usage prints the
+// command line, a description and the flags to stderr, as after an error.
+func newFlagSet(name, usage, description string, stderr io.Writer) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: goapigen %s %s\n\n%s\n\nFlags:\n", name, usage, strings.TrimSpace(description))
+		flags.PrintDefaults()
 	}
+	return flags
+}
 
-	// Find the module line
-	lines := strings.Split(string(data), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "module") {
-			parts := strings.Fields(line)
-			if len(parts) >= 2 {
-				return parts[1], nil
-			}
-		}
-	}
+// parseFlags parses the flags of a command. The help asked for with -h or
+// -help is printed to stdout, and flag.ErrHelp returned; the usage after an
+// invalid flag is printed to stderr, after the error.
+func parseFlags(flags *flag.FlagSet, args []string, stdout io.Writer) error {
+	usage := flags.Usage
+	flags.Usage = func() {}
+	err := flags.Parse(args)
+	flags.Usage = usage
+
+	switch {
+	case err == flag.ErrHelp:
+		printHelp(flags, stdout)
+	case err != nil:
+		flags.Usage()
+	}
+	return err
+}
 
-	return "", fmt.Errorf("module declaration not found in go.mod")
+// printHelp prints the usage of a command to stdout, as asked for
+func printHelp(flags *flag.FlagSet, stdout io.Writer) {
+	stderr := flags.Output()
+	flags.SetOutput(stdout)
+	flags.Usage()
+	flags.SetOutput(stderr)
 }
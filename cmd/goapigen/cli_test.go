@@ -0,0 +1,150 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const petstoreSpec = "../../examples/petstore/openapi.yaml"
+
+func TestRun(t *testing.T) {
+	t.Run("usage", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, exitUsage, run(nil, &stdout, &stderr))
+		assert.Contains(t, stderr.String(), "Usage: goapigen <command>")
+
+		stderr.Reset()
+		assert.Equal(t, exitUsage, run([]string{"frobnicate"}, &stdout, &stderr))
+		assert.Contains(t, stderr.String(), `unknown command "frobnicate"`)
+	})
+
+	t.Run("help", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, 0, run([]string{"help"}, &stdout, &stderr))
+		for _, cmd := range commands {
+			assert.Contains(t, stdout.String(), cmd.name)
+		}
+
+		// The help of a command goes to stdout, as asked for
+		for _, tt := range []struct {
+			args  []string
+			usage string
+		}{
+			{[]string{"generate", "-h"}, "Usage: goapigen generate"},
+			{[]string{"templates", "--help"}, "Usage: goapigen templates"},
+			{[]string{"help", "diff"}, "Usage: goapigen diff"},
+		} {
+			stdout.Reset()
+			assert.Equal(t, 0, run(tt.args, &stdout, &stderr), tt.args)
+			assert.Contains(t, stdout.String(), tt.usage, tt.args)
+			assert.Contains(t, stdout.String(), "Flags:", tt.args)
+		}
+		assert.Contains(t, stdout.String(), "Exit codes:")
+		assert.Empty(t, stderr.String())
+	})
+
+	t.Run("flag_error", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, exitUsage, run([]string{"diff", "--frobnicate"}, &stdout, &stderr))
+		assert.Empty(t, stdout.String())
+		assert.Contains(t, stderr.String(), "flag provided but not defined: -frobnicate\nUsage: goapigen diff")
+	})
+
+	t.Run("generate", func(t *testing.T) {
+		outputDir := t.TempDir()
+		var stdout, stderr bytes.Buffer
+		code := run([]string{"generate", "--output", outputDir, petstoreSpec}, &stdout, &stderr)
+		require.Equal(t, generateExitOK, code, stderr.String())
+
+		assert.FileExists(t, filepath.Join(outputDir, "internal/pkg/domain/types.go"))
+		assert.NoFileExists(t, filepath.Join(outputDir, ".env"), "generate should not scaffold the project")
+	})
+
+	t.Run("generate_usage", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, generateExitUsage, run([]string{"generate"}, &stdout, &stderr))
+		assert.Equal(t, generateExitUsage, run([]string{"generate", "--openapi-validation", petstoreSpec}, &stdout, &stderr))
+		assert.Equal(t, generateExitUsage, run([]string{"generate", "--optional", "maybe", petstoreSpec}, &stdout, &stderr))
+		assert.Equal(t, generateExitUsage, run([]string{"generate", "--verify", "--dry-run", petstoreSpec}, &stdout, &stderr))
+		assert.Equal(t, generateExitUsage, run([]string{"generate", "--watch", "--dry-run", petstoreSpec}, &stdout, &stderr))
+		assert.Equal(t, generateExitUsage, run([]string{"init", "--watch", petstoreSpec}, &stdout, &stderr))
+		assert.Equal(t, generateExitFailed, run([]string{"generate", "--output", t.TempDir(), "missing.yaml"}, &stdout, &stderr))
+	})
+
+	t.Run("dry_run", func(t *testing.T) {
+		outputDir := t.TempDir()
+		var stdout, stderr bytes.Buffer
+		code := run([]string{"init", "--dry-run", "--output", outputDir, petstoreSpec}, &stdout, &stderr)
+		require.Equal(t, generateExitOK, code, stderr.String())
+
+		assert.Contains(t, stdout.String(), "create internal/pkg/domain/types.go\n")
+		assert.Contains(t, stdout.String(), "create .env\n")
+		assert.Regexp(t, `Dry run: \d+ file\(s\) would change\n$`, stdout.String())
+		entries, err := os.ReadDir(outputDir)
+		require.NoError(t, err)
+		assert.Empty(t, entries, "A dry run should not write")
+	})
+
+	t.Run("legacy_flags", func(t *testing.T) {
+		outputDir := t.TempDir()
+		var stdout, stderr bytes.Buffer
+		code := run([]string{"--spec", petstoreSpec, "--output", outputDir, "--init"}, &stdout, &stderr)
+		require.Equal(t, generateExitOK, code, stderr.String())
+
+		assert.Contains(t, stderr.String(), "deprecated")
+		assert.FileExists(t, filepath.Join(outputDir, ".env"))
+	})
+}
+
+func TestRunDiff(t *testing.T) {
+	outputDir := t.TempDir()
+	args := []string{"--output", outputDir, "--http", petstoreSpec}
+
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, diffExitDrift, runDiff(append([]string{"--name-status"}, args...), &stdout, &stderr))
+	assert.Contains(t, stdout.String(), "create internal/pkg/domain/types.go")
+	assert.Contains(t, stderr.String(), "generated code is out of date")
+	assert.NoFileExists(t, filepath.Join(outputDir, "go.mod"), "diff should not write")
+
+	// New files are diffed against /dev/null
+	stdout.Reset()
+	assert.Equal(t, diffExitDrift, runDiff(args, &stdout, &stderr))
+	assert.Contains(t, stdout.String(), "--- /dev/null\n+++ b/internal/pkg/domain/types.go\n@@ -0,0 +1,")
+
+	require.Equal(t, generateExitOK, runGenerate(args, &stdout, &stderr), stderr.String())
+
+	stdout.Reset()
+	assert.Equal(t, diffExitClean, runDiff(args, &stdout, &stderr), stdout.String())
+	assert.Empty(t, stdout.String())
+
+	// Regenerated files drift when edited
+	routes, err := filepath.Glob(filepath.Join(outputDir, "cmd", "*", "routes.go"))
+	require.NoError(t, err)
+	require.Len(t, routes, 1)
+	require.NoError(t, os.WriteFile(routes[0], []byte("package main\n"), 0644))
+
+	stdout.Reset()
+	assert.Equal(t, diffExitDrift, runDiff(append([]string{"--name-status"}, args...), &stdout, &stderr))
+	assert.Regexp(t, `^modify cmd/.*/routes.go\n$`, stdout.String())
+
+	stdout.Reset()
+	assert.Equal(t, diffExitDrift, runDiff(args, &stdout, &stderr))
+	assert.Regexp(t, `^--- a/cmd/.*/routes.go\n\+\+\+ b/cmd/.*/routes.go\n@@ -1 \+1,\d+ @@\n package main\n\+`, stdout.String())
+
+	assert.Equal(t, diffExitError, runDiff([]string{"missing.yaml"}, &stdout, &stderr))
+}
+
+func TestRunVersion(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 0, runVersion(nil, &stdout, &stderr))
+	assert.Regexp(t, `^goapigen \S+\n`, stdout.String())
+	assert.Contains(t, stdout.String(), runtime.Version())
+
+	assert.Equal(t, exitUsage, runVersion([]string{"extra"}, &stdout, &stderr))
+}
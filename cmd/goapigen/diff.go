@@ -0,0 +1,140 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// Exit codes of the diff command
+const (
+	diffExitClean = 0
+	diffExitDrift = 1
+	diffExitError = 2
+)
+
+// diffContext is the number of unchanged lines around each change
+const diffContext = 3
+
+const diffDescription = `
+Diff runs generate without writing anything and prints a unified diff from
+the files of the output directory to the files generate would write. It
+takes the flags of generate, so the same command line checks whether a
+project is up to date. --name-status lists the files that would be
+created, modified or deleted instead.
+
+Exit codes: 0 when the generated code is up to date, 1 when it is out of
+date, 2 when generation fails or the flags are invalid.`
+
+// runDiff runs the diff command with its arguments and returns the exit code
+func runDiff(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("diff", "[flags] [spec...]", diffDescription, stderr)
+	genFlags := addGenerationFlags(flags)
+	initProject := flags.Bool("init", false, "Compare with the files of goapigen init")
+	nameStatus := flags.Bool("name-status", false, "List the changed files instead of their diff")
+
+	if err := parseFlags(flags, args, stdout); err != nil {
+		if err == flag.ErrHelp {
+			return diffExitClean
+		}
+		return diffExitError
+	}
+
+	cfg, err := genFlags.Config(flags.Args())
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		flags.Usage()
+		return diffExitError
+	}
+	cfg.InitProject = *initProject
+	cfg.DryRun = true
+
+	pipeline, err := NewGenerationPipeline(cfg, templateFS)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return diffExitError
+	}
+	pipeline.SetOutput(stderr)
+
+	plan, err := pipeline.Plan()
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return diffExitError
+	}
+	changes, err := pipeline.Changes(plan)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return diffExitError
+	}
+
+	if *nameStatus {
+		printChanges(stdout, changes)
+	} else {
+		for _, change := range changes {
+			if err := pipeline.writeDiff(stdout, plan, change); err != nil {
+				fmt.Fprintf(stderr, "Error: %v\n", err)
+				return diffExitError
+			}
+		}
+	}
+	if len(changes) > 0 {
+		fmt.Fprintf(stderr, "generated code is out of date: %d file(s) would change\n", len(changes))
+		return diffExitDrift
+	}
+	return diffExitClean
+}
+
+// printChanges lists changes one per line, e.g. "modify cmd/api/routes.go"
+func printChanges(w io.Writer, changes []FileChange) {
+	for _, change := range changes {
+		fmt.Fprintf(w, "%-6s %s\n", change.Kind, change.Path)
+	}
+}
+
+// writeDiff writes the unified diff of a change, from the file on disk to
+// the file of the plan. Created and deleted files are compared with
+// /dev/null, as git does.
+func (p *GenerationPipeline) writeDiff(w io.Writer, plan *Plan, change FileChange) error {
+	var from, to []byte
+	fromName, toName := "a/"+change.Path, "b/"+change.Path
+	if change.Kind != ChangeCreate {
+		existing, err := os.ReadFile(p.outputPath(change.Path))
+		if err != nil {
+			return err
+		}
+		from = existing
+	} else {
+		fromName = "/dev/null"
+	}
+	if file, ok := plan.file(change.Path); ok && change.Kind != ChangeDelete {
+		to = file.Content
+	} else {
+		toName = "/dev/null"
+	}
+
+	return difflib.WriteUnifiedDiff(w, difflib.UnifiedDiff{
+		A:        diffLines(from),
+		B:        diffLines(to),
+		FromFile: fromName,
+		ToFile:   toName,
+		Context:  diffContext,
+	})
+}
+
+// diffLines splits content into lines that keep their line feed. A last
+// line without one gets it, so that it still prints on its own line.
+func diffLines(content []byte) []string {
+	if len(content) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		return lines[:len(lines)-1]
+	}
+	lines[len(lines)-1] += "\n"
+	return lines
+}
@@ -0,0 +1,305 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/zeek-r/goapigen/internal/config"
+	"github.com/zeek-r/goapigen/internal/generator"
+)
+
+// Exit codes of the init and generate commands
+const (
+	generateExitOK     = 0
+	generateExitFailed = 1
+	generateExitUsage  = exitUsage
+)
+
+const initDescription = `
+Init scaffolds a project in the output directory: the directories of the
+layout, cmd/<name>/main.go, routes.go and database.go, the config and
+logger packages and .env, along with the components selected by the flags.
+Existing files are kept unless --overwrite is given. --dry-run lists the
+files that would be created, modified or deleted without writing anything.
+--verify type-checks the project once written, see goapigen help generate.
+
+Exit codes: 0 on success, 1 when generation fails, 2 on invalid flags.`
+
+const generateDescription = `
+Generate writes the components selected by the flags into a project.
+Existing files are kept unless --overwrite is given, except routes.go,
+database.go, the handler.go of each resource and the embedded spec, which
+follow the spec on every run. Files with protected regions, such as
+services and their tests, are merged on every run: code between
+"// goapigen:keep begin <name>" and "// goapigen:keep end" is kept and the
+rest of the file follows the spec.
+
+Generated files are recorded in .goapigen/manifest.json. The files the spec
+no longer produces, such as the handlers of a deleted operation, are
+deleted, and generated files edited since they were written are neither
+overwritten nor deleted unless --force is given. --dry-run lists the files
+that would be created, modified or deleted without writing anything, and
+goapigen diff prints their diff.
+
+--verify type-checks the packages of the project, tests included, once
+written, and fails with the type errors found, each followed by the
+template and the schema or operation of the file it is in. Dependencies are
+read from the module cache and never downloaded.
+
+--watch generates the project, then generates it again whenever the spec
+files, the files they reference, the project file or the templates change,
+until interrupted. Changes are picked up once the files have been quiet
+for half a second. Only the schemas, operations and resources whose data
+changed are rendered again and only the files that changed are written.
+Generated files not edited since they were written follow the spec even
+without --overwrite, and edited ones are kept. Each generation prints the
+files it wrote and a summary, and errors, such as a spec that does not
+parse, are printed without stopping the watch.
+
+Exit codes: 0 on success, 1 when generation fails or the generated code
+does not compile with --verify, 2 on invalid flags.`
+
+// generationFlags are the flags of the commands running the pipeline
+type generationFlags struct {
+	flags      *flag.FlagSet
+	values     GenerationConfig
+	specs      stringList
+	formats    stringList
+	optional   string
+	layout     string
+	configFile string
+}
+
+// addGenerationFlags registers the generation flags on a flag set
+func addGenerationFlags(flags *flag.FlagSet) *generationFlags {
+	f := &generationFlags{flags: flags}
+	flags.Var(&f.specs, "spec", "OpenAPI specification: file, directory, URL, go:<module>/<file> or - for stdin (repeatable, documents are merged)")
+	flags.StringVar(&f.configFile, "config", "", "Project file (default: "+config.ProjectFile+" in the output directory)")
+	flags.StringVar(&f.values.OutputDir, "output", ".", "Output directory for generated code")
+	flags.StringVar(&f.values.PackageName, "package", config.DefaultAPIPackage, "Package name for generated code")
+	flags.StringVar(&f.values.HTTPPackage, "http-package", config.DefaultHandlerPackage, "Package name for HTTP handlers")
+	flags.StringVar(&f.values.SchemaName, "schema", "", "Generate code for specific schema (if empty, generates for all schemas)")
+	flags.BoolVar(&f.values.GenTypes, "types", true, "Generate type definitions")
+	flags.BoolVar(&f.values.GenServices, "services", false, "Generate service layer")
+	flags.BoolVar(&f.values.GenMongo, "mongo", false, "Generate MongoDB repositories")
+	flags.BoolVar(&f.values.GenHTTP, "http", false, "Generate HTTP handlers")
+	flags.BoolVar(&f.values.Overwrite, "overwrite", false, "Overwrite existing files (default: false)")
+	flags.BoolVar(&f.values.Force, "force", false, "Overwrite or delete generated files even when edited since they were generated (default: false)")
+	flags.StringVar(&f.optional, "optional", string(generator.OptionalValue), "Representation of optional and nullable fields: value, pointer or generic")
+	flags.BoolVar(&f.values.SplitTypes, "split-types", false, "Generate one domain types file per schema instead of a single types.go")
+	flags.BoolVar(&f.values.OpenAPIValidation, "openapi-validation", false, "Generate a middleware validating HTTP traffic against the embedded OpenAPI spec (requires -http)")
+	flags.StringVar(&f.layout, "layout", config.DefaultLayoutPreset, "Directory layout preset: "+strings.Join(config.LayoutPresets(), ", "))
+	flags.StringVar(&f.values.TemplatesDir, "templates", "", "Directory of templates replacing or adding to the embedded ones (see goapigen help templates)")
+	flags.Var(&f.formats, "format", "Map a string format to a Go type, e.g. decimal=github.com/cockroachdb/apd/v3.Decimal (repeatable)")
+	flags.IntVar(&f.values.Workers, "workers", 0, "Maximum number of files generated concurrently (default: number of CPUs)")
+	return f
+}
+
+// Config returns the configuration of the project file, if any, with the
+// flags set on the command line overriding its values. Specs may also be
+// given as arguments, e.g. goapigen generate --http api.yaml
+func (f *generationFlags) Config(args []string) (*GenerationConfig, error) {
+	cfg := f.values
+	optionalStrategy, err := generator.ParseOptionalStrategy(f.optional)
+	if err != nil {
+		return nil, err
+	}
+	cfg.OptionalStrategy = optionalStrategy
+
+	projectFile, err := findProject(f.configFile, f.values.OutputDir)
+	if err != nil {
+		return nil, err
+	}
+	if projectFile != "" {
+		project, err := config.LoadProject(projectFile)
+		if err != nil {
+			return nil, err
+		}
+		if err := applyProject(&cfg, project); err != nil {
+			return nil, fmt.Errorf("invalid project file %s: %w", projectFile, err)
+		}
+		cfg.ProjectFile = projectFile
+	}
+
+	// Flags set on the command line win over the project file
+	f.flags.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "output":
+			cfg.OutputDir = f.values.OutputDir
+		case "package":
+			cfg.PackageName = f.values.PackageName
+		case "http-package":
+			cfg.HTTPPackage = f.values.HTTPPackage
+		case "types":
+			cfg.GenTypes = f.values.GenTypes
+		case "services":
+			cfg.GenServices = f.values.GenServices
+		case "mongo":
+			cfg.GenMongo = f.values.GenMongo
+		case "http":
+			cfg.GenHTTP = f.values.GenHTTP
+		case "optional":
+			cfg.OptionalStrategy = optionalStrategy
+		case "split-types":
+			cfg.SplitTypes = f.values.SplitTypes
+		case "openapi-validation":
+			cfg.OpenAPIValidation = f.values.OpenAPIValidation
+		case "layout":
+			// The directories set by the project file still apply
+			cfg.Layout.Preset = f.layout
+		case "templates":
+			cfg.TemplatesDir = f.values.TemplatesDir
+		}
+	})
+	if specs := append(append([]string{}, f.specs...), args...); len(specs) > 0 {
+		cfg.SpecFiles = specs
+	}
+	cfg.FormatMappings = append(cfg.FormatMappings, f.formats...)
+
+	if len(cfg.SpecFiles) == 0 {
+		return nil, fmt.Errorf("OpenAPI specification file is required")
+	}
+	if cfg.OpenAPIValidation && !cfg.GenHTTP {
+		return nil, fmt.Errorf("--openapi-validation requires -http")
+	}
+	if _, err := cfg.Layout.Resolve(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// generationCommand is a command running the generation pipeline
+type generationCommand struct {
+	name        string
+	description string
+	initProject bool
+
+	// legacy accepts --init, as the command line without commands did
+	legacy bool
+}
+
+func runInit(args []string, stdout, stderr io.Writer) int {
+	return generationCommand{name: "init", description: initDescription, initProject: true}.run(args, stdout, stderr)
+}
+
+func runGenerate(args []string, stdout, stderr io.Writer) int {
+	return generationCommand{name: "generate", description: generateDescription}.run(args, stdout, stderr)
+}
+
+// runLegacy runs a command line of flags only, as accepted before commands
+func runLegacy(args []string, stdout, stderr io.Writer) int {
+	fmt.Fprintln(stderr, "Warning: flags without a command are deprecated, use goapigen init or goapigen generate")
+	return generationCommand{name: "generate", description: generateDescription, legacy: true}.run(args, stdout, stderr)
+}
+
+// run parses the command line, then runs the pipeline and returns the exit
+// code
+func (c generationCommand) run(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet(c.name, "[flags] [spec...]", c.description, stderr)
+	genFlags := addGenerationFlags(flags)
+	initProject := c.initProject
+	dryRun := flags.Bool("dry-run", false, "List the files that would be created, modified or deleted without writing anything")
+	verifyCode := flags.Bool("verify", false, "Type-check the generated project, offline, and fail on errors")
+	watch := false
+	if !c.initProject {
+		flags.BoolVar(&watch, "watch", false, "Generate again whenever the spec, the files it references, the project file or the templates change")
+	}
+	if c.legacy {
+		flags.BoolVar(&initProject, "init", false, "Initialize a new project with full directory structure and main.go")
+	}
+
+	if err := parseFlags(flags, args, stdout); err != nil {
+		if err == flag.ErrHelp {
+			return generateExitOK
+		}
+		return generateExitUsage
+	}
+
+	// The configuration is loaded again by every generation of watch mode,
+	// as the project file may change
+	load := func() (*GenerationConfig, error) {
+		cfg, err := genFlags.Config(flags.Args())
+		if err != nil {
+			return nil, err
+		}
+		cfg.InitProject = initProject
+		cfg.DryRun = *dryRun
+		cfg.Verify = *verifyCode
+		return cfg, nil
+	}
+	cfg, err := load()
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		flags.Usage()
+		return generateExitUsage
+	}
+	if *dryRun && *verifyCode {
+		fmt.Fprintln(stderr, "Error: --verify type-checks the written files and cannot be combined with --dry-run")
+		flags.Usage()
+		return generateExitUsage
+	}
+	if watch && *dryRun {
+		fmt.Fprintln(stderr, "Error: --watch writes the generated files and cannot be combined with --dry-run")
+		flags.Usage()
+		return generateExitUsage
+	}
+	if watch && !watchable(cfg.SpecFiles) {
+		fmt.Fprintln(stderr, "Error: --watch needs a spec file or directory on disk")
+		flags.Usage()
+		return generateExitUsage
+	}
+	if cfg.ProjectFile != "" {
+		fmt.Fprintf(stdout, "Using project file %s\n", cfg.ProjectFile)
+	}
+
+	if watch {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		newWatcher(cfg, load, stdout, stderr).run(ctx)
+		return generateExitOK
+	}
+
+	pipeline, err := NewGenerationPipeline(cfg, templateFS)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return generateExitFailed
+	}
+	pipeline.SetOutput(stdout)
+
+	if cfg.DryRun {
+		return runDryRun(pipeline, stdout, stderr)
+	}
+	if err := pipeline.Execute(); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return generateExitFailed
+	}
+	return generateExitOK
+}
+
+// runDryRun lists the changes a pipeline would make to the output directory
+// and returns the exit code the generation would have
+func runDryRun(pipeline *GenerationPipeline, stdout, stderr io.Writer) int {
+	plan, err := pipeline.Plan()
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return generateExitFailed
+	}
+	changes, err := pipeline.Changes(plan)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return generateExitFailed
+	}
+
+	printChanges(stdout, changes)
+	fmt.Fprintf(stdout, "Dry run: %d file(s) would change\n", len(changes))
+	if err := pipeline.CheckEdited(plan); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return generateExitFailed
+	}
+	return generateExitOK
+}
@@ -1,26 +1,54 @@
 package cli
 
 import (
-	"embed"
+	"bytes"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
+	"text/template"
 
 	"github.com/zeek-r/goapigen/internal/config"
 	"github.com/zeek-r/goapigen/internal/generator"
+	"github.com/zeek-r/goapigen/internal/gosource"
+	"github.com/zeek-r/goapigen/internal/manifest"
 	"github.com/zeek-r/goapigen/internal/parser"
+	"github.com/zeek-r/goapigen/internal/regions"
+	"github.com/zeek-r/goapigen/internal/verify"
+	"github.com/zeek-r/goapigen/internal/workpool"
 )
 
 // GenerationConfig holds all configuration for code generation
 type GenerationConfig struct {
-	SpecFile    string
+	SpecFiles   []string // Spec files or directories, merged in order
 	OutputDir   string
 	PackageName string
 	HTTPPackage string
 	SchemaName  string
 
+	// Generated code options
+	OptionalStrategy  generator.OptionalStrategy
+	FormatMappings    []string // format=Go type mappings, e.g. decimal=github.com/shopspring/decimal.Decimal
+	SplitTypes        bool
+	OpenAPIValidation bool
+
+	// Layout places the generated packages, the standard layout by default
+	Layout config.LayoutConfig
+
+	// TemplatesDir overlays the embedded templates, see loadTemplates
+	TemplatesDir string
+
+	// Schemas holds the overrides of single schemas by name
+	Schemas map[string]config.SchemaOverride
+
+	// ProjectFile is the goapigen.yaml the configuration was read from
+	ProjectFile string
+
 	// Generation flags
 	GenTypes    bool
 	GenServices bool
@@ -28,125 +56,706 @@ type GenerationConfig struct {
 	GenHTTP     bool
 	InitProject bool
 	Overwrite   bool
+
+	// Force writes and deletes generated files even when they were edited
+	// since they were generated
+	Force bool
+
+	// DryRun leaves the output directory untouched, even when it has no go.mod
+	DryRun bool
+
+	// Workers bounds how many files are generated concurrently, one per CPU
+	// when not positive. The plan is the same whatever the number.
+	Workers int
+
+	// Verify type-checks the output directory once written, see Verify
+	Verify bool
 }
 
 // GenerationPipeline handles the complete code generation process
 type GenerationPipeline struct {
 	config       *GenerationConfig
 	parser       *parser.OpenAPIParser
-	templateFS   embed.FS
+	formats      *generator.FormatRegistry
+	layout       config.Layout
+	templateFS   fs.FS
+	extras       map[string][]string // Added templates by directory
 	targetModule string
 	importPath   string
+	manifest     *manifest.Manifest
+	version      string // Version of goapigen recorded in the manifest
+	out          io.Writer
+	cache        *generator.RenderCache
+	refresh      bool // Write unedited generated files without Overwrite
+}
+
+// GeneratedFile is a file produced by the pipeline
+type GeneratedFile struct {
+	Path    string // Slash-separated path relative to the output directory
+	Content []byte
+	Scope   string // Part of the generation the file belongs to, see cover
+
+	// Regenerated files, such as routes.go, track the spec and are written on
+	// every run. The others are only written when missing or with Overwrite.
+	Regenerated bool
+
+	// Merged files keep the edited protected regions of the file on disk,
+	// and are written on every run. Conflicts names the edited regions the
+	// template no longer has, left between conflict markers.
+	Merged    bool
+	Conflicts []string
+
+	// Template is the template the file was rendered from, relative to the
+	// templates directory, and Subject the schema, operation or resource it
+	// was rendered for, if any. Verify reports type errors with them.
+	Template string
+	Subject  string
+}
+
+// Plan lists what a generation produces, computed without writing anything
+type Plan struct {
+	Files []GeneratedFile
+	// Removed lists the generated files made obsolete by this generation
+	Removed []string
+	// Directories of the project layout, created even when empty
+	Directories []string
+	// Dependencies are fetched at their latest version
+	Dependencies []string
+	// Requirements are module@version pairs recorded in go.mod even offline
+	Requirements []string
+
+	// complete holds the scopes this generation produces every file of
+	complete map[string]bool
+}
+
+// Scopes of the generated files, recorded in the manifest. Files of the
+// types, services, mongo and http scopes that a generation of the whole
+// scope no longer produces are stale and deleted. The other scopes are
+// written once and never deleted.
+const (
+	scopeProject  = "project"
+	scopeTypes    = "types"
+	scopeErrors   = "errors"
+	scopeServices = "services"
+	scopeMongo    = "mongo"
+	scopeHTTP     = "http"
+	scopeMain     = "main"
+	scopeEnv      = "env"
+)
+
+// Dependencies of a project created with InitProject
+var projectDependencies = []string{
+	"github.com/go-chi/chi/v5",
+	"github.com/go-chi/cors",
+	"github.com/joho/godotenv",
+	"go.mongodb.org/mongo-driver/mongo",
+	"github.com/bool64/ctxd",
+	"github.com/bool64/zapctxd",
+	"github.com/kelseyhightower/envconfig",
 }
 
+// Defaults written to main.go and .env
+const (
+	defaultMongoURI = "mongodb://localhost:27017"
+	defaultPort     = "8080"
+)
+
 // NewGenerationPipeline creates a new generation pipeline
-func NewGenerationPipeline(config *GenerationConfig, templateFS embed.FS) (*GenerationPipeline, error) {
+func NewGenerationPipeline(config *GenerationConfig, templateFS fs.FS) (*GenerationPipeline, error) {
+	if config.OpenAPIValidation && !config.GenHTTP {
+		return nil, fmt.Errorf("OpenAPI validation requires HTTP handler generation")
+	}
+
+	optionalStrategy := config.OptionalStrategy
+	if optionalStrategy == "" {
+		optionalStrategy = generator.OptionalValue
+	}
+	if _, err := generator.ParseOptionalStrategy(string(optionalStrategy)); err != nil {
+		return nil, err
+	}
+
+	layout, err := config.Layout.Resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	var extras map[string][]string
+	if config.TemplatesDir != "" {
+		templateFS, extras, err = loadTemplates(config.TemplatesDir, templateFS)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// Every template set is parsed once, however many generators use it
+	templateFS = generator.NewTemplateCache(templateFS)
+
+	formats := generator.NewFormatRegistry()
+	if err := formats.RegisterMappings(config.FormatMappings); err != nil {
+		return nil, err
+	}
+
 	// Parse OpenAPI spec
-	apiParser, err := parser.NewOpenAPIParser(config.SpecFile)
+	apiParser, err := parser.NewOpenAPIParser(config.SpecFiles...)
 	if err != nil {
 		return nil, fmt.Errorf("error parsing OpenAPI spec: %w", err)
 	}
+	formats.RegisterSpecImports(apiParser.Doc)
 
-	// Create output directory
-	if err := os.MkdirAll(config.OutputDir, 0755); err != nil {
-		return nil, fmt.Errorf("error creating output directory: %w", err)
+	for name := range config.Schemas {
+		if _, exists := apiParser.GetSchemaByName(name); !exists {
+			return nil, fmt.Errorf("schema override %q matches no schema in the spec", name)
+		}
 	}
 
-	// Initialize or detect Go module
-	targetModule, err := initializeGoModule(config.OutputDir)
+	// The module is created when the plan is applied
+	targetModule, err := detectGoModule(config.OutputDir)
 	if err != nil {
 		return nil, fmt.Errorf("error with Go module: %w", err)
 	}
 
+	generated, err := manifest.Load(config.OutputDir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading manifest: %w", err)
+	}
+
 	return &GenerationPipeline{
 		config:       config,
 		parser:       apiParser,
+		formats:      formats,
+		layout:       layout,
 		templateFS:   templateFS,
+		extras:       extras,
 		targetModule: targetModule,
 		importPath:   targetModule,
+		manifest:     generated,
+		version:      readBuildVersion().Version,
+		out:          os.Stdout,
 	}, nil
 }
 
+// SetOutput sets where progress messages are written, os.Stdout by default
+func (p *GenerationPipeline) SetOutput(out io.Writer) {
+	p.out = out
+}
+
+// SetRenderCache sets the code rendered by an earlier generation of the
+// same templates, so that only the types, services, repositories and
+// handlers whose schemas or operations changed are rendered again
+func (p *GenerationPipeline) SetRenderCache(cache *generator.RenderCache) {
+	p.cache = cache
+}
+
+// SetRefresh makes Apply write the files recorded in the manifest that were
+// not edited since they were generated, even without Overwrite, as watch
+// mode does. Edited files are still kept.
+func (p *GenerationPipeline) SetRefresh(refresh bool) {
+	p.refresh = refresh
+}
+
+// SpecFiles returns the files on disk the spec was read from, including
+// the files its references point to
+func (p *GenerationPipeline) SpecFiles() []string {
+	return p.parser.Files()
+}
+
 // Execute runs the complete generation pipeline
 func (p *GenerationPipeline) Execute() error {
+	plan, err := p.Plan()
+	if err != nil {
+		return err
+	}
+	if err := p.Apply(plan); err != nil {
+		return err
+	}
+	if p.config.Verify {
+		return p.Verify(plan)
+	}
+	return nil
+}
+
+// Plan renders every file of the generation without writing anything
+func (p *GenerationPipeline) Plan() (*Plan, error) {
 	// Get schemas to generate
 	schemaNames, err := p.getSchemaNames()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	plan := &Plan{}
+
 	// Initialize project structure if requested
 	if p.config.InitProject {
-		if err := p.initializeProject(); err != nil {
-			return fmt.Errorf("error initializing project: %w", err)
+		if err := p.planProject(plan); err != nil {
+			return nil, fmt.Errorf("error initializing project: %w", err)
 		}
+		plan.cover(scopeProject, false)
 	}
 
 	// Generate types
 	if p.config.GenTypes {
-		if err := p.generateTypes(); err != nil {
-			return fmt.Errorf("error generating types: %w", err)
+		if err := p.generateTypes(plan); err != nil {
+			return nil, fmt.Errorf("error generating types: %w", err)
 		}
+		plan.cover(scopeTypes, true)
 	}
 
+	// Domain errors are shared by every layer
+	errorsCode, err := p.renderTemplate(config.DomainErrorsTemplate, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error generating domain errors: %w", err)
+	}
+	plan.addFile(GeneratedFile{
+		Path:     path.Join(p.layout.Domain, config.ErrorsFile),
+		Content:  errorsCode,
+		Template: templateName(config.DomainErrorsTemplate),
+	})
+	plan.cover(scopeErrors, false)
+
 	// Generate services
 	if p.config.GenServices || p.config.GenHTTP {
-		if err := p.generateServices(schemaNames); err != nil {
-			return fmt.Errorf("error generating services: %w", err)
+		if err := p.generateServices(plan, schemaNames); err != nil {
+			return nil, fmt.Errorf("error generating services: %w", err)
 		}
+		// A single schema leaves the services of the others alone
+		plan.cover(scopeServices, p.config.SchemaName == "")
 	}
 
 	// Generate MongoDB repositories
 	if p.config.GenMongo {
-		if err := p.generateMongoRepositories(schemaNames); err != nil {
-			return fmt.Errorf("error generating repositories: %w", err)
+		if err := p.generateMongoRepositories(plan, schemaNames); err != nil {
+			return nil, fmt.Errorf("error generating repositories: %w", err)
 		}
+		plan.cover(scopeMongo, p.config.SchemaName == "")
 	}
 
 	// Generate HTTP handlers
 	if p.config.GenHTTP {
-		if err := p.generateHTTPHandlers(); err != nil {
-			return fmt.Errorf("error generating HTTP handlers: %w", err)
+		if err := p.generateHTTPHandlers(plan); err != nil {
+			return nil, fmt.Errorf("error generating HTTP handlers: %w", err)
 		}
+		plan.cover(scopeHTTP, true)
 	}
 
 	// Regenerate routes if needed
-	if p.config.GenServices || p.config.GenMongo || p.config.GenHTTP || p.config.InitProject {
-		if err := p.regenerateRoutes(); err != nil {
-			return fmt.Errorf("error regenerating routes: %w", err)
+	if p.config.GenHTTP || p.config.InitProject {
+		if err := p.generateMainFiles(plan); err != nil {
+			return nil, fmt.Errorf("error regenerating routes: %w", err)
 		}
+		plan.cover(scopeMain, false)
 	}
 
 	// Generate environment file
 	if p.config.InitProject {
-		if err := p.generateEnvFile(); err != nil {
-			return fmt.Errorf("error generating .env file: %w", err)
+		if err := p.generateEnvFile(plan); err != nil {
+			return nil, fmt.Errorf("error generating .env file: %w", err)
+		}
+		plan.cover(scopeEnv, false)
+	}
+
+	if err := p.mergeRegions(plan); err != nil {
+		return nil, err
+	}
+	if err := p.planStale(plan); err != nil {
+		return nil, fmt.Errorf("error listing stale files: %w", err)
+	}
+
+	sort.Slice(plan.Files, func(i, j int) bool {
+		return plan.Files[i].Path < plan.Files[j].Path
+	})
+	sort.Strings(plan.Removed)
+	return plan, nil
+}
+
+// planStale adds to the removed files those the manifest records in a scope
+// this generation covers completely but that it no longer produces, such as
+// the handlers of an operation deleted from the spec
+func (p *GenerationPipeline) planStale(plan *Plan) error {
+	planned := make(map[string]bool, len(plan.Files)+len(plan.Removed))
+	for _, file := range plan.Files {
+		planned[file.Path] = true
+	}
+	for _, name := range plan.Removed {
+		planned[name] = true
+	}
+
+	for name, entry := range p.manifest.Files {
+		if planned[name] || !plan.complete[entry.Scope] {
+			continue
 		}
+		_, err := os.Stat(p.outputPath(name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		plan.Removed = append(plan.Removed, name)
 	}
+	return nil
+}
 
+// CheckEdited returns an error listing the generated files edited since
+// they were generated that Apply would overwrite or delete, unless Force
+// is set
+func (p *GenerationPipeline) CheckEdited(plan *Plan) error {
+	if p.config.Force {
+		return nil
+	}
+	edited, err := p.editedFiles(plan)
+	if err != nil {
+		return fmt.Errorf("error checking generated files: %w", err)
+	}
+	if len(edited) > 0 {
+		return fmt.Errorf("generated files were edited since they were generated, use --force to overwrite or delete them anyway:\n  %s",
+			strings.Join(edited, "\n  "))
+	}
 	return nil
 }
 
-// initializeGoModule initializes or detects the Go module
-func initializeGoModule(outputDir string) (string, error) {
-	goModPath := filepath.Join(outputDir, config.GoModFile)
+// editedFiles returns the files Apply would overwrite or delete although
+// they changed since goapigen wrote them. Merged files are left out, as the
+// code written in their regions is kept.
+func (p *GenerationPipeline) editedFiles(plan *Plan) ([]string, error) {
+	var edited []string
+	for _, file := range plan.Files {
+		if file.Merged || !p.writes(file) {
+			continue
+		}
+		existing, err := os.ReadFile(p.outputPath(file.Path))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(existing, file.Content) && p.manifest.Edited(file.Path, existing) {
+			edited = append(edited, file.Path)
+		}
+	}
+	for _, name := range plan.Removed {
+		existing, err := os.ReadFile(p.outputPath(name))
+		if err != nil {
+			return nil, err
+		}
+		if p.manifest.Edited(name, existing) {
+			edited = append(edited, name)
+		}
+	}
+	sort.Strings(edited)
+	return edited, nil
+}
 
-	if _, err := os.Stat(goModPath); os.IsNotExist(err) {
-		// No go.mod found, run go mod init
-		fmt.Printf("No go.mod found in output directory. Running go mod init...\n")
+// mergeRegions stamps the protected regions of the planned files, and
+// merges the files on disk that have regions, such as services: the code
+// inside their edited regions is kept and the rest follows the spec.
+// Files on disk without regions are left to Overwrite as before.
+func (p *GenerationPipeline) mergeRegions(plan *Plan) error {
+	for i := range plan.Files {
+		file := &plan.Files[i]
+		if !regions.Has(file.Content) {
+			continue
+		}
 
-		moduleName := filepath.Base(outputDir)
-		cmd := exec.Command("go", "mod", "init", moduleName)
-		cmd.Dir = outputDir
-		if err := cmd.Run(); err != nil {
-			return "", fmt.Errorf("error running go mod init: %w", err)
+		existing, err := os.ReadFile(p.outputPath(file.Path))
+		if err != nil && !os.IsNotExist(err) {
+			return err
 		}
+		if file.Regenerated || !regions.Has(existing) {
+			stamped, err := regions.Stamp(file.Content)
+			if err != nil {
+				return fmt.Errorf("error reading protected regions of %s: %w", file.Path, err)
+			}
+			file.Content = stamped
+			continue
+		}
+
+		result, err := regions.Merge(existing, file.Content)
+		if err != nil {
+			return fmt.Errorf("error merging %s: %w", file.Path, err)
+		}
+		file.Content = result.Content
+		file.Merged = true
+		file.Conflicts = result.Conflicts
+	}
+	return nil
+}
+
+// Apply writes a plan to the output directory and adds its dependencies
+func (p *GenerationPipeline) Apply(plan *Plan) error {
+	if err := p.CheckEdited(plan); err != nil {
+		return err
+	}
+	if err := p.initializeGoModule(); err != nil {
+		return fmt.Errorf("error with Go module: %w", err)
+	}
+
+	if p.config.InitProject {
+		fmt.Fprintln(p.out, "Initializing project structure...")
+	}
+	for _, dir := range plan.Directories {
+		if err := os.MkdirAll(p.outputPath(dir), 0755); err != nil {
+			return fmt.Errorf("error creating directory %s: %w", dir, err)
+		}
+	}
+
+	for _, file := range plan.Files {
+		filePath := p.outputPath(file.Path)
+		if !p.writes(file) {
+			fmt.Fprintf(p.out, "%s already exists. Skipping (use --overwrite to force overwrite)\n", filePath)
+			continue
+		}
+		// Files already up to date keep their modification time and are not
+		// reported, whether they are merged, regenerated or overwritten
+		if existing, err := os.ReadFile(filePath); err == nil && bytes.Equal(existing, file.Content) {
+			if _, ok := p.manifest.Files[file.Path]; !ok {
+				p.manifest.Files[file.Path] = manifest.Entry{Scope: file.Scope, Hash: manifest.Hash(file.Content), Generator: p.version}
+			}
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			return fmt.Errorf("error creating directory for %s: %w", file.Path, err)
+		}
+		if err := os.WriteFile(filePath, file.Content, 0644); err != nil {
+			return fmt.Errorf("error writing %s: %w", file.Path, err)
+		}
+		p.manifest.Files[file.Path] = manifest.Entry{Scope: file.Scope, Hash: manifest.Hash(file.Content), Generator: p.version}
+		if file.Merged {
+			fmt.Fprintf(p.out, "Merged %s\n", filePath)
+		} else {
+			fmt.Fprintf(p.out, "Generated %s\n", filePath)
+		}
+		for _, conflict := range file.Conflicts {
+			fmt.Fprintf(p.out, "Conflict in %s: region %s is no longer generated. Move or delete its code between the conflict markers\n", filePath, conflict)
+		}
+	}
+
+	for _, name := range plan.Removed {
+		if err := os.Remove(p.outputPath(name)); err != nil {
+			return fmt.Errorf("error removing %s: %w", name, err)
+		}
+		fmt.Fprintf(p.out, "Removed %s\n", p.outputPath(name))
+		delete(p.manifest.Files, name)
+	}
+
+	if err := p.saveManifest(); err != nil {
+		return err
+	}
+	return p.addDependencies(plan)
+}
+
+// Verify type-checks the packages of the output directory, tests included,
+// and returns an error listing their problems, each with the template and
+// schema or operation of the generated file it is in. Dependencies are read
+// from the module cache, so it works offline once they are downloaded.
+func (p *GenerationPipeline) Verify(plan *Plan) error {
+	if _, err := os.Stat(filepath.Join(p.config.OutputDir, config.GoModFile)); err != nil {
+		return fmt.Errorf("cannot verify generated code without %s in the output directory", config.GoModFile)
+	}
+	fmt.Fprintln(p.out, "Verifying generated code...")
+
+	problems, err := verify.Module(p.config.OutputDir)
+	if err != nil {
+		return fmt.Errorf("error verifying generated code: %w", err)
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+
+	absDir, err := filepath.Abs(p.config.OutputDir)
+	if err != nil {
+		return err
+	}
+	lines := make([]string, 0, len(problems))
+	for _, problem := range problems {
+		lines = append(lines, p.describeProblem(plan, absDir, problem))
+	}
+	return fmt.Errorf("generated code does not compile:\n  %s", strings.Join(lines, "\n  "))
+}
+
+// describeProblem returns a problem with its position relative to the output
+// directory, followed by the origin of the generated file it is in
+func (p *GenerationPipeline) describeProblem(plan *Plan, absDir string, problem verify.Problem) string {
+	rel, err := filepath.Rel(absDir, problem.File)
+	if problem.File == "" || err != nil || strings.HasPrefix(rel, "..") {
+		return problem.String()
+	}
+	problem.File = filepath.ToSlash(rel)
+	description := problem.String()
+
+	file, planned := plan.file(problem.File)
+	switch {
+	case !planned:
+		return description
+	case file.Template != "" && file.Subject != "":
+		return fmt.Sprintf("%s (template %s for %s)", description, file.Template, file.Subject)
+	case file.Template != "":
+		return fmt.Sprintf("%s (template %s)", description, file.Template)
+	}
+	return description
+}
+
+// writes reports whether Apply writes a file of the plan
+func (p *GenerationPipeline) writes(file GeneratedFile) bool {
+	if file.Regenerated || file.Merged || p.config.Overwrite {
+		return true
+	}
+	existing, err := os.ReadFile(p.outputPath(file.Path))
+	if os.IsNotExist(err) {
+		return true
+	}
+	if !p.refresh || err != nil {
+		return false
+	}
+	_, generated := p.manifest.Files[file.Path]
+	return generated && !p.manifest.Edited(file.Path, existing)
+}
+
+// Kinds of FileChange
+const (
+	ChangeCreate = "create"
+	ChangeModify = "modify"
+	ChangeDelete = "delete"
+)
+
+// FileChange is a change Apply would make to the output directory
+type FileChange struct {
+	Kind string
+	Path string
+}
+
+// Changes compares a plan with the output directory and returns the files
+// Apply would create, modify or delete, in path order
+func (p *GenerationPipeline) Changes(plan *Plan) ([]FileChange, error) {
+	var changes []FileChange
+	for _, file := range plan.Files {
+		existing, err := os.ReadFile(p.outputPath(file.Path))
+		switch {
+		case os.IsNotExist(err):
+			changes = append(changes, FileChange{Kind: ChangeCreate, Path: file.Path})
+		case err != nil:
+			return nil, err
+		case p.writes(file) && !bytes.Equal(existing, file.Content):
+			changes = append(changes, FileChange{Kind: ChangeModify, Path: file.Path})
+		}
+	}
+	for _, name := range plan.Removed {
+		changes = append(changes, FileChange{Kind: ChangeDelete, Path: name})
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes, nil
+}
+
+// outputPath returns the path on disk of a file of the plan
+func (p *GenerationPipeline) outputPath(name string) string {
+	return filepath.Join(p.config.OutputDir, filepath.FromSlash(name))
+}
+
+// saveManifest writes the manifest, forgetting the files deleted by hand
+func (p *GenerationPipeline) saveManifest() error {
+	for name := range p.manifest.Files {
+		if _, err := os.Stat(p.outputPath(name)); os.IsNotExist(err) {
+			delete(p.manifest.Files, name)
+		}
+	}
+	if err := p.manifest.Save(p.config.OutputDir); err != nil {
+		return fmt.Errorf("error writing manifest: %w", err)
+	}
+	return nil
+}
+
+// cover assigns the files added since the last call to a scope. Complete
+// scopes have every file planned, so that the files of the manifest missing
+// from the plan are stale.
+func (plan *Plan) cover(scope string, complete bool) {
+	for i := range plan.Files {
+		if plan.Files[i].Scope == "" {
+			plan.Files[i].Scope = scope
+		}
+	}
+	if complete {
+		if plan.complete == nil {
+			plan.complete = make(map[string]bool)
+		}
+		plan.complete[scope] = true
 	}
+}
 
-	// Read module name from go.mod
+// file returns the file of the plan at a path
+func (plan *Plan) file(name string) (GeneratedFile, bool) {
+	for _, file := range plan.Files {
+		if file.Path == name {
+			return file, true
+		}
+	}
+	return GeneratedFile{}, false
+}
+
+// add adds a file to the plan, replacing a file planned at the same path
+func (plan *Plan) add(name string, content []byte, regenerated bool) {
+	plan.addFile(GeneratedFile{Path: name, Content: content, Regenerated: regenerated})
+}
+
+// addFile adds a file along with its origin, replacing a file planned at
+// the same path
+func (plan *Plan) addFile(file GeneratedFile) {
+	for i := range plan.Files {
+		if plan.Files[i].Path == file.Path {
+			plan.Files[i] = file
+			return
+		}
+	}
+	plan.Files = append(plan.Files, file)
+}
+
+// initializeGoModule creates the output directory and, when it has no
+// go.mod, runs go mod init for the module the plan was rendered for
+func (p *GenerationPipeline) initializeGoModule() error {
+	if err := os.MkdirAll(p.config.OutputDir, 0755); err != nil {
+		return fmt.Errorf("error creating output directory: %w", err)
+	}
+	if _, err := os.Stat(filepath.Join(p.config.OutputDir, config.GoModFile)); !os.IsNotExist(err) {
+		return err
+	}
+
+	fmt.Fprintln(p.out, "No go.mod found in output directory. Running go mod init...")
+	cmd := exec.Command("go", "mod", "init", p.targetModule)
+	cmd.Dir = p.config.OutputDir
+	cmd.Stdout = p.out
+	cmd.Stderr = p.out
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("error running go mod init: %w", err)
+	}
+	return nil
+}
+
+// detectGoModule returns the module of the output directory, or the module
+// initializeGoModule would create
+func detectGoModule(outputDir string) (string, error) {
+	if _, err := os.Stat(filepath.Join(outputDir, config.GoModFile)); os.IsNotExist(err) {
+		return defaultModuleName(outputDir)
+	}
 	return getModuleNameFromPath(outputDir)
 }
 
+// defaultModuleName names a new module after its directory
+func defaultModuleName(outputDir string) (string, error) {
+	absDir, err := filepath.Abs(outputDir)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Base(absDir), nil
+}
+
+// projectName is the last element of the module path, naming the command
+// directory and the default database
+func (p *GenerationPipeline) projectName() string {
+	return path.Base(p.targetModule)
+}
+
 // getSchemaNames returns the schemas to generate code for
 func (p *GenerationPipeline) getSchemaNames() ([]string, error) {
 	if p.config.SchemaName != "" {
@@ -157,160 +766,577 @@ func (p *GenerationPipeline) getSchemaNames() ([]string, error) {
 	}
 
 	// Return all schemas
+	return p.resourceNames(), nil
+}
+
+// resourceNames returns the schemas getting a service, a repository and
+// wiring in main.go: all of them but the skipped ones
+func (p *GenerationPipeline) resourceNames() []string {
 	schemas := p.parser.GetSchemas()
 	schemaNames := make([]string, 0, len(schemas))
 	for name := range schemas {
-		schemaNames = append(schemaNames, name)
+		if !p.config.Schemas[name].Skip {
+			schemaNames = append(schemaNames, name)
+		}
 	}
-	return schemaNames, nil
+	sort.Strings(schemaNames)
+	return schemaNames
 }
 
-// initializeProject creates the basic project structure and main files
-func (p *GenerationPipeline) initializeProject() error {
-	fmt.Println("Initializing project structure...")
+// planProject adds the directory structure, the config and logger packages
+// and the dependencies of a new project
+func (p *GenerationPipeline) planProject(plan *Plan) error {
+	plan.Directories = append(plan.Directories, p.layout.Dirs()...)
+	plan.Dependencies = append(plan.Dependencies, projectDependencies...)
 
-	// Create directory structure
-	dirsToCreate := []string{
-		p.config.OutputDir,
-		filepath.Join(p.config.OutputDir, config.InternalDir),
-		filepath.Join(p.config.OutputDir, config.PkgDir),
-		filepath.Join(p.config.OutputDir, config.DomainDir),
-		filepath.Join(p.config.OutputDir, config.ServicesDir),
-		filepath.Join(p.config.OutputDir, config.AdaptersDir),
-		filepath.Join(p.config.OutputDir, config.HttpAdaptersDir),
-		filepath.Join(p.config.OutputDir, config.MongoAdaptersDir),
-		filepath.Join(p.config.OutputDir, config.HttpUtilDir),
+	templateData := map[string]interface{}{
+		"ImportPath":  p.importPath,
+		"Packages":    p.layout.Imports(p.importPath),
+		"ProjectName": p.projectName(),
 	}
-
-	for _, dir := range dirsToCreate {
-		if err := os.MkdirAll(dir, 0755); err != nil {
-			return fmt.Errorf("error creating directory %s: %w", dir, err)
+	packages := []struct {
+		template string
+		file     string
+	}{
+		{config.ConfigTemplate, path.Join(p.layout.Config, config.ConfigFile)},
+		{config.ConfigTestTemplate, path.Join(p.layout.Config, config.ConfigTestFile)},
+		{config.LoggerTemplate, path.Join(p.layout.Logger, config.LoggerFile)},
+		{config.LoggerTestTemplate, path.Join(p.layout.Logger, config.LoggerTestFile)},
+	}
+	for _, pkg := range packages {
+		code, err := p.renderTemplate(pkg.template, templateData)
+		if err != nil {
+			return err
 		}
+		plan.addFile(GeneratedFile{Path: pkg.file, Content: code, Template: templateName(pkg.template)})
 	}
 
-	// Generate main application files
-	return p.generateMainFiles()
+	// The project file makes regeneration repeat the options of init
+	project, err := projectFor(p.config)
+	if err != nil {
+		return fmt.Errorf("error recording project file: %w", err)
+	}
+	projectCode, err := project.Marshal()
+	if err != nil {
+		return err
+	}
+	plan.add(config.ProjectFile, projectCode, false)
+	return nil
 }
 
 // generateMainFiles generates main.go, routes.go, and database.go
-func (p *GenerationPipeline) generateMainFiles() error {
+func (p *GenerationPipeline) generateMainFiles(plan *Plan) error {
 	mainGen, err := generator.NewMainGenerator(p.parser, p.importPath, p.templateFS)
 	if err != nil {
 		return fmt.Errorf("error creating main generator: %w", err)
 	}
 
 	// Configure generator
-	mainGen.SetMongoURI("mongodb://localhost:27017")
-	mainGen.SetDBName(filepath.Base(p.targetModule))
-	mainGen.SetDefaultPort("8080")
+	mainGen.SetMongoURI(defaultMongoURI)
+	mainGen.SetDBName(p.projectName())
+	mainGen.SetDefaultPort(defaultPort)
+	mainGen.SetOpenAPIValidation(p.config.OpenAPIValidation)
+	mainGen.SetLayout(p.layout)
 
-	// Create cmd directory
-	cmdDir := filepath.Join(p.config.OutputDir, "cmd", filepath.Base(p.targetModule))
-	if err := os.MkdirAll(cmdDir, 0755); err != nil {
-		return fmt.Errorf("error creating cmd directory: %w", err)
-	}
+	// Skipped schemas are not wired as resources
+	mainGen.SetResourceNames(p.resourceNames())
 
 	// Generate files with current features
-	hasServices := p.config.GenServices || p.config.GenHTTP
+	hasServices := p.config.GenServices || p.config.GenHTTP // HTTP handlers need services
 	files, err := mainGen.GenerateWithFeatures(p.config.GenMongo, p.config.GenMongo, hasServices, p.config.GenHTTP)
 	if err != nil {
 		return fmt.Errorf("error generating main files: %w", err)
 	}
 
-	// Write files
-	return p.writeMainFiles(cmdDir, files)
-}
-
-// writeMainFiles writes the generated main files to disk
-func (p *GenerationPipeline) writeMainFiles(cmdDir string, files map[string]string) error {
+	// main.go is stable, routes.go and database.go follow the components
+	cmdDir := path.Join("cmd", p.projectName())
 	for filename, content := range files {
-		filePath := filepath.Join(cmdDir, filename)
-
-		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
-			return fmt.Errorf("error creating directory for %s: %w", filename, err)
-		}
-
-		// For main.go, only write if it doesn't exist (stable file)
-		// For routes.go and database.go, always overwrite (regenerated files)
-		shouldWrite := p.config.Overwrite
-		if strings.HasSuffix(filename, "routes.go") || strings.HasSuffix(filename, "database.go") {
-			shouldWrite = true
-		} else if _, err := os.Stat(filePath); os.IsNotExist(err) {
-			shouldWrite = true
-		}
-
-		if shouldWrite {
-			if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
-				return fmt.Errorf("error writing %s: %w", filename, err)
-			}
-			fmt.Printf("Generated %s\n", filePath)
-		} else {
-			fmt.Printf("%s already exists. Skipping (use --overwrite to force overwrite)\n", filename)
-		}
+		plan.addFile(GeneratedFile{
+			Path:        path.Join(cmdDir, filename),
+			Content:     []byte(content),
+			Regenerated: filename == "routes.go" || filename == "database.go",
+			Template:    "cmd/" + filename + ".tmpl",
+		})
 	}
-
-	// Add required dependencies
-	return p.addDependencies()
+	return nil
 }
 
 // addDependencies adds required Go module dependencies
-func (p *GenerationPipeline) addDependencies() error {
-	fmt.Println("Adding required dependencies...")
-	deps := []string{
-		"github.com/go-chi/chi/v5",
-		"github.com/go-chi/cors",
-		"github.com/joho/godotenv",
-		"go.mongodb.org/mongo-driver/mongo",
+func (p *GenerationPipeline) addDependencies(plan *Plan) error {
+	if len(plan.Dependencies) > 0 {
+		fmt.Fprintln(p.out, "Adding required dependencies...")
 	}
-
-	for _, dep := range deps {
+	for _, dep := range plan.Dependencies {
 		cmd := exec.Command("go", "get", dep)
 		cmd.Dir = p.config.OutputDir
 		if err := cmd.Run(); err != nil {
-			fmt.Printf("Error adding dependency %s: %v\n", dep, err)
+			fmt.Fprintf(p.out, "Error adding dependency %s: %v\n", dep, err)
+		}
+	}
+
+	for _, requirement := range plan.Requirements {
+		module, version, _ := strings.Cut(requirement, "@")
+		if err := requireDependency(p.config.OutputDir, module, version, p.out); err != nil {
+			return fmt.Errorf("error adding dependency %s: %w", module, err)
 		}
 	}
 	return nil
 }
 
 // generateTypes generates domain types from OpenAPI schemas
-func (p *GenerationPipeline) generateTypes() error {
-	// Implementation extracted from main.go - for now, return nil
-	// TODO: Extract types generation logic from main.go
+func (p *GenerationPipeline) generateTypes(plan *Plan) error {
+	typeGen := generator.NewTypeGenerator(p.parser, config.DomainPackage, p.templateFS)
+	typeGen.SetOptionalStrategy(p.optionalStrategy())
+	typeGen.SetImportPath(p.importPath)
+	typeGen.SetMongo(p.config.GenMongo)
+	typeGen.SetFormatRegistry(p.formats)
+	typeGen.SetWorkers(p.config.Workers)
+	typeGen.SetRenderCache(p.cache)
+
+	var typeFiles map[string]string
+	var err error
+	if p.config.SplitTypes {
+		typeFiles, err = typeGen.GenerateTypeFiles()
+	} else {
+		var typesCode string
+		typesCode, err = typeGen.GenerateTypes()
+		typeFiles = map[string]string{config.TypesFile: typesCode}
+	}
+	if err != nil {
+		return err
+	}
+
+	// Write helper types such as Date or Optional[T] next to the types
+	helpers, err := typeGen.GenerateHelpers()
+	if err != nil {
+		return fmt.Errorf("error generating helper types: %w", err)
+	}
+
+	// Split types files are named after their schema
+	typeSchemas := make(map[string]string)
+	if p.config.SplitTypes {
+		for name, schema := range p.parser.GetSchemas() {
+			typeSchemas[generator.TypeFileName(generator.GoTypeName(name, schema))] = "schema " + name
+		}
+	}
+
+	sources := make([]string, 0, len(typeFiles)+len(helpers))
+	for filename, code := range typeFiles {
+		plan.addFile(GeneratedFile{
+			Path:     path.Join(p.layout.Domain, filename),
+			Content:  []byte(code),
+			Template: templateName(config.DomainTypesTemplate),
+			Subject:  typeSchemas[filename],
+		})
+		sources = append(sources, code)
+	}
+	for filename, code := range helpers {
+		plan.addFile(GeneratedFile{
+			Path:     path.Join(p.layout.Domain, filename),
+			Content:  []byte(code),
+			Template: "domain/" + filename + ".tmpl",
+		})
+		sources = append(sources, code)
+	}
+
+	// Mapped formats may use third-party packages such as google/uuid
+	externalImports, err := generator.ExternalImports(sources...)
+	if err != nil {
+		return fmt.Errorf("error reading imports of generated types: %w", err)
+	}
+	plan.Dependencies = append(plan.Dependencies, externalImports...)
+
+	// Files left from an earlier run in the other mode would redeclare
+	// every type: a single types.go, or one file per schema
+	stale, err := p.staleTypeFiles(typeFiles)
+	if err != nil {
+		return fmt.Errorf("error listing stale types files: %w", err)
+	}
+	plan.Removed = append(plan.Removed, stale...)
 	return nil
 }
 
+// staleTypeFiles returns the generated types files of the domain package
+// that the current types files replace. Shared files, tests and hand-written
+// files are left alone.
+func (p *GenerationPipeline) staleTypeFiles(typeFiles map[string]string) ([]string, error) {
+	entries, err := os.ReadDir(p.outputPath(p.layout.Domain))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var stale []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if _, planned := typeFiles[name]; planned || entry.IsDir() || filepath.Ext(name) != ".go" ||
+			strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		// types.go is shared with the other mode only
+		if generator.IsSharedDomainFile(name) && !(p.config.SplitTypes && name == config.TypesFile) {
+			continue
+		}
+
+		file := path.Join(p.layout.Domain, name)
+		generated, err := isGeneratedFile(p.outputPath(file))
+		if err != nil {
+			return nil, err
+		}
+		if generated {
+			stale = append(stale, file)
+		} else if name == config.TypesFile {
+			fmt.Fprintf(p.out, "%s was not generated by goapigen. Leaving it in place\n", p.outputPath(file))
+		}
+	}
+	return stale, nil
+}
+
 // generateServices generates service layer for schemas
-func (p *GenerationPipeline) generateServices(schemaNames []string) error {
-	// Implementation extracted from main.go - for now, return nil
-	// TODO: Extract services generation logic from main.go
-	return nil
+func (p *GenerationPipeline) generateServices(plan *Plan, schemaNames []string) error {
+	serviceGen, err := generator.NewServiceGenerator(p.parser, p.config.PackageName, p.importPath, p.templateFS)
+	if err != nil {
+		return fmt.Errorf("error creating service generator: %w", err)
+	}
+	serviceGen.SetOptionalStrategy(p.optionalStrategy())
+	serviceGen.SetFormatRegistry(p.formats)
+	serviceGen.SetLayout(p.layout)
+	serviceGen.SetRenderCache(p.cache)
+	if err := serviceGen.AddTemplates(p.extras["service"]...); err != nil {
+		return fmt.Errorf("error adding service templates: %w", err)
+	}
+
+	return p.generateDomains(plan, schemaNames, func(name string) ([]GeneratedFile, error) {
+		serviceCode, err := serviceGen.GenerateService(name)
+		if err != nil {
+			return nil, fmt.Errorf("error generating service for %s: %w", name, err)
+		}
+		serviceTestCode, err := serviceGen.GenerateServiceTests(name)
+		if err != nil {
+			return nil, fmt.Errorf("error generating service tests for %s: %w", name, err)
+		}
+
+		domain := strings.ToLower(name)
+		subject := "schema " + name
+		files := []GeneratedFile{
+			{Path: path.Join(p.layout.Services, domain, domain+"_service.go"), Content: []byte(serviceCode), Template: "service/service.go.tmpl", Subject: subject},
+			{Path: path.Join(p.layout.Services, domain, domain+"_service_test.go"), Content: []byte(serviceTestCode), Template: "service/service_test.go.tmpl", Subject: subject},
+		}
+
+		extraFiles, err := serviceGen.GenerateExtraFiles(name)
+		if err != nil {
+			return nil, fmt.Errorf("error generating service files for %s: %w", name, err)
+		}
+		for _, filename := range sortedFileNames(extraFiles) {
+			files = append(files, GeneratedFile{
+				Path:     path.Join(p.layout.Services, domain, filename),
+				Content:  []byte(extraFiles[filename]),
+				Template: extraTemplateName("service", domain, filename),
+				Subject:  subject,
+			})
+		}
+		return files, nil
+	})
 }
 
 // generateMongoRepositories generates MongoDB repositories
-func (p *GenerationPipeline) generateMongoRepositories(schemaNames []string) error {
-	// Implementation extracted from main.go - for now, return nil
-	// TODO: Extract mongo repositories generation logic from main.go
+func (p *GenerationPipeline) generateMongoRepositories(plan *Plan, schemaNames []string) error {
+	mongoGen, err := generator.NewMongoGenerator(p.parser, p.config.PackageName, config.DefaultRepoPackage, p.importPath, p.templateFS)
+	if err != nil {
+		return fmt.Errorf("error creating MongoDB generator: %w", err)
+	}
+	mongoGen.SetOptionalStrategy(p.optionalStrategy())
+	mongoGen.SetFormatRegistry(p.formats)
+	mongoGen.SetLayout(p.layout)
+	mongoGen.SetRenderCache(p.cache)
+	if err := mongoGen.AddTemplates(p.extras["mongo"]...); err != nil {
+		return fmt.Errorf("error adding repository templates: %w", err)
+	}
+	for name, override := range p.config.Schemas {
+		if override.Collection != "" {
+			mongoGen.SetCollectionName(name, override.Collection)
+		}
+	}
+
+	return p.generateDomains(plan, schemaNames, func(name string) ([]GeneratedFile, error) {
+		repoCode, err := mongoGen.GenerateRepository(name)
+		if err != nil {
+			return nil, fmt.Errorf("error generating repository for %s: %w", name, err)
+		}
+		testCode, err := mongoGen.GenerateRepositoryTests(name)
+		if err != nil {
+			return nil, fmt.Errorf("error generating repository tests for %s: %w", name, err)
+		}
+
+		domain := strings.ToLower(name)
+		subject := "schema " + name
+		files := []GeneratedFile{
+			{Path: path.Join(p.layout.Repositories, domain, domain+"_repository.go"), Content: []byte(repoCode), Template: "mongo/repository.go.tmpl", Subject: subject},
+			{Path: path.Join(p.layout.Repositories, domain, domain+"_repository_test.go"), Content: []byte(testCode), Template: "mongo/repository_test.go.tmpl", Subject: subject},
+		}
+
+		extraFiles, err := mongoGen.GenerateExtraFiles(name)
+		if err != nil {
+			return nil, fmt.Errorf("error generating repository files for %s: %w", name, err)
+		}
+		for _, filename := range sortedFileNames(extraFiles) {
+			files = append(files, GeneratedFile{
+				Path:     path.Join(p.layout.Repositories, domain, filename),
+				Content:  []byte(extraFiles[filename]),
+				Template: extraTemplateName("mongo", domain, filename),
+				Subject:  subject,
+			})
+		}
+		return files, nil
+	})
+}
+
+// generateDomains generates the files of each schema concurrently and adds
+// them to the plan in the order of the schemas. The first failing schema in
+// that order reports its error.
+func (p *GenerationPipeline) generateDomains(plan *Plan, schemaNames []string, generate func(name string) ([]GeneratedFile, error)) error {
+	results := make([][]GeneratedFile, len(schemaNames))
+	err := workpool.Run(p.config.Workers, len(schemaNames), func(i int) error {
+		files, err := generate(schemaNames[i])
+		results[i] = files
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, files := range results {
+		for _, file := range files {
+			plan.addFile(file)
+		}
+	}
 	return nil
 }
 
+// sortedFileNames returns the names of generated files in order
+func sortedFileNames(files map[string]string) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // generateHTTPHandlers generates HTTP handlers
-func (p *GenerationPipeline) generateHTTPHandlers() error {
-	// Implementation extracted from main.go - for now, return nil
-	// TODO: Extract HTTP handlers generation logic from main.go
+func (p *GenerationPipeline) generateHTTPHandlers(plan *Plan) error {
+	httpGen, err := generator.NewHTTPGenerator(p.parser, p.config.PackageName, p.httpPackage(), p.importPath, p.importPath, p.templateFS)
+	if err != nil {
+		return fmt.Errorf("error creating HTTP handler generator: %w", err)
+	}
+	httpGen.SetOptionalStrategy(p.optionalStrategy())
+	httpGen.SetFormatRegistry(p.formats)
+	httpGen.SetOpenAPIValidation(p.config.OpenAPIValidation)
+	httpGen.SetLayout(p.layout)
+	httpGen.SetWorkers(p.config.Workers)
+	httpGen.SetRenderCache(p.cache)
+	if err := httpGen.AddTemplates(p.extras["http"]...); err != nil {
+		return fmt.Errorf("error adding HTTP templates: %w", err)
+	}
+
+	// Handlers are grouped by the first tag of their operations and call the
+	// service of the schema named after it
+	for _, operation := range p.parser.GetOperations() {
+		if len(operation.Tags) > 0 && p.config.Schemas[operation.Tags[0]].Skip {
+			return fmt.Errorf("schema %s is skipped, but the handlers of the operations tagged %s need its service", operation.Tags[0], operation.Tags[0])
+		}
+	}
+
+	handlersCode, err := httpGen.GenerateHandlers()
+	if err != nil {
+		return err
+	}
+
+	operationIDs := make(map[string]string)
+	for opID := range p.parser.GetOperations() {
+		operationIDs[strings.ToLower(opID)] = opID
+	}
+	for filename, code := range handlersCode {
+		file := GeneratedFile{
+			Path:    p.httpFilePath(filename),
+			Content: []byte(code),
+			// The embedded spec always tracks the spec being generated from,
+			// and handler.go registers the handlers of the current operations
+			Regenerated: filename == "httputil/"+config.OpenAPISpecFile || path.Base(filename) == "handler.go",
+		}
+		file.Template, file.Subject = httpOrigin(filename, operationIDs, p.extras["http"])
+		plan.addFile(file)
+	}
+
+	// The validation middleware is built on kin-openapi, so the generated
+	// module cannot compile without it
+	if p.config.OpenAPIValidation {
+		plan.Requirements = append(plan.Requirements, config.KinOpenAPIModule+"@"+config.KinOpenAPIVersion)
+	}
 	return nil
 }
 
-// regenerateRoutes regenerates routes.go when components change
-func (p *GenerationPipeline) regenerateRoutes() error {
-	// Implementation extracted from main.go - for now, return nil
-	// TODO: Extract routes regeneration logic from main.go
-	return nil
+// httpOrigin returns the template and subject of a file of the HTTP
+// generator from its name: the files of an operation are named after its
+// ID, the others after their template.
+func httpOrigin(filename string, operationIDs map[string]string, extras []string) (string, string) {
+	dir, base := path.Split(filename)
+	if dir == "httputil/" {
+		if base == config.OpenAPISpecFile {
+			return "", ""
+		}
+		return "http/" + base + ".tmpl", ""
+	}
+
+	switch {
+	case base == "handler.go":
+		return "http/schema_handler.go.tmpl", "resource " + path.Base(dir)
+	case base == "mock_service.go":
+		// Mocks go in the mocks/ directory of their resource
+		return "http/mocks.go.tmpl", "resource " + path.Base(path.Dir(path.Clean(dir)))
+	}
+	for _, suffix := range []string{"_handler.go", "_handler_test.go"} {
+		if opID, ok := strings.CutSuffix(base, suffix); ok && operationIDs[opID] != "" {
+			return "http/operation" + suffix + ".tmpl", "operation " + operationIDs[opID]
+		}
+	}
+	for _, extra := range extras {
+		name := path.Base(extra)
+		if opID, ok := strings.CutSuffix(base, "_"+strings.TrimSuffix(name, ".tmpl")); ok && operationIDs[opID] != "" {
+			return "http/" + name, "operation " + operationIDs[opID]
+		}
+	}
+	return "", ""
+}
+
+// httpFilePath maps a file of the HTTP generator to its place in the
+// layout: httputil/ files go to the shared httputil package and the others,
+// including domain/<domain>/ files, to the HTTP handlers
+func (p *GenerationPipeline) httpFilePath(filename string) string {
+	if strings.HasPrefix(filename, "httputil/") {
+		return path.Join(p.layout.HTTPUtil, path.Base(filename))
+	}
+	return path.Join(p.layout.HTTP, strings.TrimPrefix(filename, "domain/"))
 }
 
 // generateEnvFile generates the .env configuration file
-func (p *GenerationPipeline) generateEnvFile() error {
-	// Implementation extracted from main.go - for now, return nil
-	// TODO: Extract env file generation logic from main.go
+func (p *GenerationPipeline) generateEnvFile(plan *Plan) error {
+	dbName := p.projectName()
+	if dbName == "." {
+		dbName = "api"
+	}
+
+	code, err := p.renderTemplate(config.EnvTemplate, map[string]string{
+		"MongoURI":    defaultMongoURI,
+		"DBName":      dbName,
+		"DefaultPort": defaultPort,
+	})
+	if err != nil {
+		return err
+	}
+	plan.add(config.EnvFile, code, false)
+	return nil
+}
+
+// templateName returns the path of a template relative to the templates
+// directory, e.g. domain/errors.go.tmpl
+func templateName(name string) string {
+	return strings.TrimPrefix(name, "templates/")
+}
+
+// extraTemplateName returns the path of the added template a file of a
+// schema was rendered from, e.g. service/events.go.tmpl for pet_events.go
+func extraTemplateName(dir, domain, filename string) string {
+	return dir + "/" + strings.TrimPrefix(filename, domain+"_") + ".tmpl"
+}
+
+// renderTemplate executes an embedded template, formatting Go code
+func (p *GenerationPipeline) renderTemplate(name string, data interface{}) ([]byte, error) {
+	tmpl, err := template.ParseFS(p.templateFS, name)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing template %s: %w", name, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("error executing template %s: %w", name, err)
+	}
+	if !strings.HasSuffix(name, ".go.tmpl") {
+		return buf.Bytes(), nil
+	}
+
+	code, err := gosource.Format(buf.Bytes(), p.importPath)
+	if err != nil {
+		return nil, fmt.Errorf("template %s rendered invalid Go: %w", path.Base(name), err)
+	}
+	return code, nil
+}
+
+// optionalStrategy returns the configured strategy, value by default
+func (p *GenerationPipeline) optionalStrategy() generator.OptionalStrategy {
+	if p.config.OptionalStrategy == "" {
+		return generator.OptionalValue
+	}
+	return p.config.OptionalStrategy
+}
+
+// httpPackage returns the package name of the HTTP handlers, http by default
+func (p *GenerationPipeline) httpPackage() string {
+	if p.config.HTTPPackage == "" {
+		return config.DefaultHandlerPackage
+	}
+	return p.config.HTTPPackage
+}
+
+// getModuleNameFromPath attempts to determine the Go module name from go.mod in specified directory
+func getModuleNameFromPath(dir string) (string, error) {
+	// Try to open go.mod in the specified directory
+	goModPath := filepath.Join(dir, "go.mod")
+	data, err := os.ReadFile(goModPath)
+	if err != nil {
+		return "", err
+	}
+
+	// Find the module line
+	lines := strings.Split(string(data), "\n")
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "module") {
+			parts := strings.Fields(line)
+			if len(parts) >= 2 {
+				return parts[1], nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("module declaration not found in go.mod")
+}
+
+// isGeneratedFile reports whether a file starts with the goapigen header
+func isGeneratedFile(path string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	return strings.HasPrefix(string(data), generatedHeader), nil
+}
+
+// requireDependency records module@version in the go.mod of dir and then
+// fetches it. The requirement is written offline, so a generated module
+// always declares what it imports even when the download fails
+func requireDependency(dir, module, version string, out io.Writer) error {
+	cmd := exec.Command("go", "mod", "edit", "-require="+module+"@"+version)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to require %s in go.mod: %w\n%s", module, err, output)
+	}
+
+	cmd = exec.Command("go", "get", module+"@"+version)
+	cmd.Dir = dir
+	if err := cmd.Run(); err != nil {
+		fmt.Fprintf(out, "Could not download %s: %v. Run go mod download before building\n", module, err)
+	}
 	return nil
 }
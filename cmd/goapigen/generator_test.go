@@ -1,13 +1,20 @@
 package cli
 
 import (
+	"bytes"
 	"embed"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/zeek-r/goapigen/internal/config"
+	"github.com/zeek-r/goapigen/internal/manifest"
+	"github.com/zeek-r/goapigen/internal/testutil"
 )
 
 // Mock embed.FS for testing
@@ -22,7 +29,7 @@ func TestGenerationConfig(t *testing.T) {
 		{
 			name: "default_config",
 			config: &GenerationConfig{
-				SpecFile:    "../../examples/petstore/openapi.yaml",
+				SpecFiles:   []string{"../../examples/petstore/openapi.yaml"},
 				OutputDir:   t.TempDir(),
 				PackageName: "api",
 				GenTypes:    true,
@@ -37,7 +44,7 @@ func TestGenerationConfig(t *testing.T) {
 		{
 			name: "full_generation_config",
 			config: &GenerationConfig{
-				SpecFile:    "../../examples/petstore/openapi.yaml",
+				SpecFiles:   []string{"../../examples/petstore/openapi.yaml"},
 				OutputDir:   t.TempDir(),
 				GenTypes:    true,
 				GenServices: true,
@@ -68,7 +75,7 @@ func TestNewGenerationPipeline(t *testing.T) {
 	tempDir := t.TempDir()
 
 	config := &GenerationConfig{
-		SpecFile:  "../../examples/petstore/openapi.yaml",
+		SpecFiles: []string{"../../examples/petstore/openapi.yaml"},
 		OutputDir: tempDir,
 	}
 
@@ -80,17 +87,19 @@ func TestNewGenerationPipeline(t *testing.T) {
 	assert.NotEmpty(t, pipeline.targetModule, "Target module should be detected")
 	assert.NotEmpty(t, pipeline.importPath, "Import path should be set")
 
-	// Check that output directory was created
-	assert.DirExists(t, tempDir, "Output directory should exist")
-
-	// Check that go.mod was created
+	// go.mod is created once a plan is applied, reporting to the output
 	goModPath := filepath.Join(tempDir, "go.mod")
+	assert.NoFileExists(t, goModPath, "go.mod should not be created yet")
+	var out bytes.Buffer
+	pipeline.SetOutput(&out)
+	require.NoError(t, pipeline.Apply(&Plan{}))
 	assert.FileExists(t, goModPath, "go.mod should be created")
+	assert.Contains(t, out.String(), "Running go mod init")
 }
 
 func TestNewGenerationPipeline_InvalidSpec(t *testing.T) {
 	config := &GenerationConfig{
-		SpecFile:  "nonexistent.yaml",
+		SpecFiles: []string{"nonexistent.yaml"},
 		OutputDir: t.TempDir(),
 	}
 
@@ -104,7 +113,7 @@ func TestGetSchemaNames(t *testing.T) {
 
 	// Create pipeline
 	config := &GenerationConfig{
-		SpecFile:  "../../examples/petstore/openapi.yaml",
+		SpecFiles: []string{"../../examples/petstore/openapi.yaml"},
 		OutputDir: tempDir,
 	}
 	pipeline, err := NewGenerationPipeline(config, mockTemplateFS)
@@ -139,12 +148,11 @@ func TestGetSchemaNames(t *testing.T) {
 
 func TestInitializeGoModule(t *testing.T) {
 	t.Run("create_new_module", func(t *testing.T) {
-		tempDir := t.TempDir()
-
-		moduleName, err := initializeGoModule(tempDir)
-		require.NoError(t, err)
+		tempDir := filepath.Join(t.TempDir(), "api")
 
-		assert.NotEmpty(t, moduleName, "Module name should not be empty")
+		var out bytes.Buffer
+		pipeline := &GenerationPipeline{config: &GenerationConfig{OutputDir: tempDir}, targetModule: "api", out: &out}
+		require.NoError(t, pipeline.initializeGoModule())
 
 		// Check go.mod exists
 		goModPath := filepath.Join(tempDir, "go.mod")
@@ -153,7 +161,11 @@ func TestInitializeGoModule(t *testing.T) {
 		// Check content
 		content, err := os.ReadFile(goModPath)
 		require.NoError(t, err)
-		assert.Contains(t, string(content), "module", "go.mod should contain module declaration")
+		assert.Contains(t, string(content), "module api", "go.mod should declare the module")
+
+		// go mod init reports to the output of the pipeline
+		assert.Contains(t, out.String(), "No go.mod found in output directory")
+		assert.Contains(t, out.String(), "go: creating new go.mod")
 	})
 
 	t.Run("existing_module", func(t *testing.T) {
@@ -165,31 +177,504 @@ func TestInitializeGoModule(t *testing.T) {
 		err := os.WriteFile(goModPath, []byte(goModContent), 0644)
 		require.NoError(t, err)
 
-		moduleName, err := initializeGoModule(tempDir)
+		moduleName, err := detectGoModule(tempDir)
 		require.NoError(t, err)
-
 		assert.Equal(t, "test-existing", moduleName, "Should use existing module name")
+
+		var out bytes.Buffer
+		pipeline := &GenerationPipeline{config: &GenerationConfig{OutputDir: tempDir}, targetModule: moduleName, out: &out}
+		require.NoError(t, pipeline.initializeGoModule())
+		assert.Empty(t, out.String())
+
+		content, err := os.ReadFile(goModPath)
+		require.NoError(t, err)
+		assert.Equal(t, goModContent, string(content), "go.mod should be left alone")
 	})
 }
 
-// TestGenerationPipelineIntegration tests the pipeline with stubs
+// TestGenerationPipelineIntegration tests the pipeline with the embedded templates
 func TestGenerationPipelineIntegration(t *testing.T) {
 	tempDir := t.TempDir()
 
 	config := &GenerationConfig{
-		SpecFile:    "../../examples/petstore/openapi.yaml",
+		SpecFiles:   []string{"../../examples/petstore/openapi.yaml"},
 		OutputDir:   tempDir,
 		InitProject: true,
 		GenTypes:    true,
 	}
 
-	pipeline, err := NewGenerationPipeline(config, mockTemplateFS)
+	pipeline, err := NewGenerationPipeline(config, templateFS)
 	require.NoError(t, err)
+	pipeline.SetOutput(io.Discard)
 
-	// Execute pipeline (with stub implementations)
 	err = pipeline.Execute()
-	assert.NoError(t, err, "Pipeline execution should succeed with stubs")
+	assert.NoError(t, err, "Pipeline execution should succeed")
 
 	// Basic validation - directory structure should exist
 	assert.DirExists(t, filepath.Join(tempDir, "internal"), "Internal directory should be created")
+	assert.FileExists(t, filepath.Join(tempDir, "internal/pkg/domain/types.go"))
+	assert.FileExists(t, filepath.Join(tempDir, "internal/pkg/config/config.go"))
+	assert.FileExists(t, filepath.Join(tempDir, ".env"))
+	assert.FileExists(t, filepath.Join(tempDir, "cmd", filepath.Base(tempDir), "main.go"))
+}
+
+func TestGenerationPipeline_Plan(t *testing.T) {
+	tempDir := t.TempDir()
+
+	config := &GenerationConfig{
+		SpecFiles: []string{"../../examples/petstore/openapi.yaml"},
+		OutputDir: tempDir,
+		GenTypes:  true,
+		GenHTTP:   true,
+		DryRun:    true,
+	}
+	pipeline, err := NewGenerationPipeline(config, templateFS)
+	require.NoError(t, err)
+	pipeline.SetOutput(io.Discard)
+
+	// A dry run leaves the output directory alone
+	assert.NoFileExists(t, filepath.Join(tempDir, "go.mod"))
+
+	plan, err := pipeline.Plan()
+	require.NoError(t, err)
+
+	paths := make([]string, 0, len(plan.Files))
+	regenerated := make(map[string]bool)
+	for _, file := range plan.Files {
+		paths = append(paths, file.Path)
+		regenerated[file.Path] = file.Regenerated
+	}
+	assert.IsNonDecreasing(t, paths, "Files should be ordered by path")
+	assert.Contains(t, paths, "internal/pkg/domain/types.go")
+	assert.Contains(t, paths, "internal/services/pet/pet_service.go")
+	assert.Contains(t, paths, "internal/adapters/http/pet/handler.go")
+	assert.Contains(t, paths, "internal/pkg/httputil/http_utils.go")
+
+	cmdDir := "cmd/" + filepath.Base(tempDir)
+	assert.True(t, regenerated[cmdDir+"/routes.go"], "routes.go should follow the spec")
+	assert.False(t, regenerated[cmdDir+"/main.go"], "main.go should be kept once written")
+
+	changes, err := pipeline.Changes(plan)
+	require.NoError(t, err)
+	assert.Len(t, changes, len(plan.Files), "Every file should be new")
+
+	// Once written, only the edits to regenerated files are changes
+	config.DryRun = false
+	pipeline, err = NewGenerationPipeline(config, templateFS)
+	require.NoError(t, err)
+	pipeline.SetOutput(io.Discard)
+	require.NoError(t, pipeline.Apply(plan))
+
+	routesPath := filepath.Join(tempDir, filepath.FromSlash(cmdDir), "routes.go")
+	mainPath := filepath.Join(tempDir, filepath.FromSlash(cmdDir), "main.go")
+	require.NoError(t, os.WriteFile(routesPath, []byte("package main\n"), 0644))
+	require.NoError(t, os.WriteFile(mainPath, []byte("package main\n"), 0644))
+
+	changes, err = pipeline.Changes(plan)
+	require.NoError(t, err)
+	assert.Equal(t, []FileChange{{Kind: ChangeModify, Path: cmdDir + "/routes.go"}}, changes)
+}
+
+func TestGenerationPipeline_Layout(t *testing.T) {
+	tempDir := t.TempDir()
+
+	pipeline, err := NewGenerationPipeline(&GenerationConfig{
+		SpecFiles:   []string{"../../examples/petstore/openapi.yaml"},
+		OutputDir:   tempDir,
+		GenTypes:    true,
+		GenServices: true,
+		GenMongo:    true,
+		GenHTTP:     true,
+		InitProject: true,
+		Layout:      config.LayoutConfig{Preset: "flat", Layout: config.Layout{Domain: "pkg/model"}},
+		DryRun:      true,
+	}, templateFS)
+	require.NoError(t, err)
+
+	plan, err := pipeline.Plan()
+	require.NoError(t, err)
+
+	files := make(map[string]string)
+	for _, file := range plan.Files {
+		assert.False(t, strings.HasPrefix(file.Path, "internal/"), "%s should follow the layout", file.Path)
+		files[file.Path] = string(file.Content)
+	}
+	for _, name := range []string{
+		"pkg/model/types.go",
+		"service/pet/pet_service.go",
+		"repository/pet/pet_repository.go",
+		"handler/pet/handler.go",
+		"httputil/http_utils.go",
+		"config/config.go",
+		"logger/logger.go",
+	} {
+		assert.Contains(t, files, name)
+	}
+	assert.Contains(t, plan.Directories, "pkg/model")
+
+	// Imports follow the layout, naming the domain package after itself
+	module := filepath.Base(tempDir)
+	assert.Contains(t, files["service/pet/pet_service.go"], `domain "`+module+`/pkg/model"`)
+	assert.Contains(t, files["handler/pet/handler.go"], `"`+module+`/service/pet"`)
+	assert.Contains(t, files["logger/logger.go"], `"`+module+`/config"`)
+
+	cmdDir := "cmd/" + module
+	assert.Contains(t, files[cmdDir+"/main.go"], `petRepository "`+module+`/repository/pet"`)
+	assert.Contains(t, files[cmdDir+"/routes.go"], `petHandler "`+module+`/handler/pet"`)
+}
+
+func TestGenerationPipeline_ProtectedRegions(t *testing.T) {
+	tempDir := t.TempDir()
+
+	pipeline, err := NewGenerationPipeline(&GenerationConfig{
+		SpecFiles:   []string{"../../examples/petstore/openapi.yaml"},
+		OutputDir:   tempDir,
+		GenServices: true,
+	}, templateFS)
+	require.NoError(t, err)
+	pipeline.SetOutput(io.Discard)
+
+	plan, err := pipeline.Plan()
+	require.NoError(t, err)
+	require.NoError(t, pipeline.Apply(plan))
+
+	servicePath := filepath.Join(tempDir, "internal", "services", "pet", "pet_service.go")
+	content, err := os.ReadFile(servicePath)
+	require.NoError(t, err)
+	edited := strings.Replace(string(content), "\t// Validate request\n", "\t// Validate request, then audit\n", 1)
+	end := strings.LastIndex(edited, "// goapigen:keep end")
+	edited = edited[:end] + "func audit() {}\n" + edited[end:]
+	require.NoError(t, os.WriteFile(servicePath, []byte(edited), 0644))
+
+	// Edited regions survive regeneration
+	plan, err = pipeline.Plan()
+	require.NoError(t, err)
+	for _, file := range plan.Files {
+		if file.Path == "internal/services/pet/pet_service.go" {
+			assert.True(t, file.Merged)
+			assert.Empty(t, file.Conflicts)
+		}
+	}
+	require.NoError(t, pipeline.Apply(plan))
+
+	merged, err := os.ReadFile(servicePath)
+	require.NoError(t, err)
+	assert.Equal(t, edited, string(merged))
+}
+
+func TestGenerationPipeline_Manifest(t *testing.T) {
+	tempDir := t.TempDir()
+	spec, err := os.ReadFile("../../examples/petstore/openapi.yaml")
+	require.NoError(t, err)
+	specPath := filepath.Join(tempDir, "openapi.yaml")
+	require.NoError(t, os.WriteFile(specPath, spec, 0644))
+
+	cfg := &GenerationConfig{
+		SpecFiles: []string{specPath},
+		OutputDir: filepath.Join(tempDir, "api"),
+		GenTypes:  true,
+		GenHTTP:   true,
+	}
+	generate := func() (*GenerationPipeline, *Plan) {
+		pipeline, err := NewGenerationPipeline(cfg, templateFS)
+		require.NoError(t, err)
+		pipeline.SetOutput(io.Discard)
+		plan, err := pipeline.Plan()
+		require.NoError(t, err)
+		return pipeline, plan
+	}
+	pipeline, plan := generate()
+	require.NoError(t, pipeline.Apply(plan))
+
+	const handler = "internal/adapters/http/order/deleteorder_handler.go"
+	const handlerTest = "internal/adapters/http/order/deleteorder_handler_test.go"
+	generated, err := manifest.Load(cfg.OutputDir)
+	require.NoError(t, err)
+	assert.Len(t, generated.Files, len(plan.Files))
+	assert.Equal(t, scopeHTTP, generated.Files[handler].Scope)
+
+	// Handlers of an operation deleted from the spec are stale
+	spec = spec[:bytes.Index(spec, []byte("    delete:\n      operationId: deleteOrder"))]
+	require.NoError(t, os.WriteFile(specPath, spec, 0644))
+	pipeline, plan = generate()
+	assert.Equal(t, []string{handler, handlerTest}, plan.Removed)
+
+	// but are not deleted once edited, unless forced
+	editedPath := filepath.Join(cfg.OutputDir, filepath.FromSlash(handlerTest))
+	require.NoError(t, os.WriteFile(editedPath, []byte("package http\n"), 0644))
+	err = pipeline.Apply(plan)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), handlerTest)
+	assert.NotContains(t, err.Error(), handler+"\n")
+	assert.FileExists(t, filepath.Join(cfg.OutputDir, filepath.FromSlash(handler)))
+
+	cfg.Force = true
+	pipeline, plan = generate()
+	require.NoError(t, pipeline.Apply(plan))
+	assert.NoFileExists(t, filepath.Join(cfg.OutputDir, filepath.FromSlash(handler)))
+	assert.NoFileExists(t, editedPath)
+
+	generated, err = manifest.Load(cfg.OutputDir)
+	require.NoError(t, err)
+	assert.NotContains(t, generated.Files, handler)
+	registration, err := os.ReadFile(filepath.Join(cfg.OutputDir, "internal", "adapters", "http", "order", "handler.go"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(registration), "deleteOrder", "handler.go should follow the spec")
+
+	// Generating a single schema leaves the files of the others alone
+	cfg.SchemaName = "Pet"
+	cfg.GenServices = true
+	_, plan = generate()
+	assert.Empty(t, plan.Removed)
+}
+
+func TestGenerationPipeline_UnchangedFiles(t *testing.T) {
+	cfg := &GenerationConfig{
+		SpecFiles:   []string{"../../examples/petstore/openapi.yaml"},
+		OutputDir:   t.TempDir(),
+		GenServices: true,
+		GenHTTP:     true,
+		InitProject: true,
+		Overwrite:   true,
+	}
+	generate := func() string {
+		pipeline, err := NewGenerationPipeline(cfg, templateFS)
+		require.NoError(t, err)
+		var out bytes.Buffer
+		pipeline.SetOutput(&out)
+		plan, err := pipeline.Plan()
+		require.NoError(t, err)
+		plan.Dependencies = nil
+		require.NoError(t, pipeline.Apply(plan))
+		return out.String()
+	}
+	generate()
+
+	routes, err := filepath.Glob(filepath.Join(cfg.OutputDir, "cmd", "*", "routes.go"))
+	require.NoError(t, err)
+	require.Len(t, routes, 1)
+	routesPath := routes[0]
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	require.NoError(t, os.Chtimes(routesPath, past, past))
+
+	// Regenerated, merged and overwritten files are only written when their
+	// content changes
+	output := generate()
+	assert.NotContains(t, output, "Generated ")
+	assert.NotContains(t, output, "Merged ")
+	info, err := os.Stat(routesPath)
+	require.NoError(t, err)
+	assert.True(t, info.ModTime().Equal(past), "routes.go should not be written again")
+}
+
+func TestGenerationPipeline_Deterministic(t *testing.T) {
+	specPath := writeSyntheticSpec(t, 20)
+	outputDir := filepath.Join(t.TempDir(), "api")
+
+	plan := func(workers int, splitTypes bool) *Plan {
+		pipeline, err := NewGenerationPipeline(&GenerationConfig{
+			SpecFiles:   []string{specPath},
+			OutputDir:   outputDir,
+			GenTypes:    true,
+			GenServices: true,
+			GenMongo:    true,
+			GenHTTP:     true,
+			InitProject: true,
+			SplitTypes:  splitTypes,
+			DryRun:      true,
+			Workers:     workers,
+		}, templateFS)
+		require.NoError(t, err)
+		pipeline.SetOutput(io.Discard)
+		plan, err := pipeline.Plan()
+		require.NoError(t, err)
+		return plan
+	}
+
+	// The plan is the same whatever the number of workers and the run
+	for _, splitTypes := range []bool{false, true} {
+		sequential := plan(1, splitTypes)
+		require.NotEmpty(t, sequential.Files)
+		for range 3 {
+			assert.Equal(t, sequential, plan(8, splitTypes))
+		}
+	}
+}
+
+func TestGenerationPipeline_Verify(t *testing.T) {
+	templatesDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(templatesDir, "service"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(templatesDir, "service", "service.go.tmpl"),
+		[]byte("package {{.SchemaName | lower}}\n\nvar count int = \"{{.SchemaName}}\"\n"), 0644))
+
+	// The types use the standard library only, so they compile offline
+	specFile := testutil.CreateTempFile(t, "openapi.yaml", `
+openapi: 3.0.0
+info:
+  title: Pets
+  version: 1.0.0
+paths: {}
+components:
+  schemas:
+    Pet:
+      type: object
+      required: [name]
+      properties:
+        name:
+          type: string
+        age:
+          type: integer
+`)
+	cfg := &GenerationConfig{
+		SpecFiles: []string{specFile},
+		OutputDir: t.TempDir(),
+		GenTypes:  true,
+		Verify:    true,
+	}
+	pipeline, err := NewGenerationPipeline(cfg, templateFS)
+	require.NoError(t, err)
+	pipeline.SetOutput(io.Discard)
+	require.NoError(t, pipeline.Execute(), "The generated types should compile")
+
+	// Code that parses but does not compile fails the generation, naming
+	// the template and the schema
+	cfg.GenServices = true
+	cfg.TemplatesDir = templatesDir
+	pipeline, err = NewGenerationPipeline(cfg, templateFS)
+	require.NoError(t, err)
+	pipeline.SetOutput(io.Discard)
+	err = pipeline.Execute()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "generated code does not compile")
+	assert.Contains(t, err.Error(), `internal/services/pet/pet_service.go:3:17: cannot use "Pet" (untyped string constant) as int value in variable declaration (template service/service.go.tmpl for schema Pet)`)
+	assert.Contains(t, err.Error(), "(template service/service_test.go.tmpl for schema Pet)")
+	assert.FileExists(t, filepath.Join(cfg.OutputDir, "internal", "services", "pet", "pet_service.go"), "Files should be written before they are verified")
+}
+
+func TestGenerationPipeline_UnionsWithoutMongo(t *testing.T) {
+	specFile := testutil.CreateTempFile(t, "openapi.yaml", `
+openapi: 3.0.0
+info:
+  title: Payments
+  version: 1.0.0
+paths: {}
+components:
+  schemas:
+    Card:
+      type: object
+      properties:
+        number:
+          type: string
+    Transfer:
+      type: object
+      properties:
+        iban:
+          type: string
+    Payment:
+      type: object
+      properties:
+        due:
+          type: string
+          format: date
+        method:
+          oneOf:
+            - $ref: '#/components/schemas/Card'
+            - $ref: '#/components/schemas/Transfer'
+`)
+	cfg := &GenerationConfig{
+		SpecFiles: []string{specFile},
+		OutputDir: t.TempDir(),
+		GenTypes:  true,
+		Verify:    true,
+	}
+	pipeline, err := NewGenerationPipeline(cfg, templateFS)
+	require.NoError(t, err)
+	pipeline.SetOutput(io.Discard)
+	require.NoError(t, pipeline.Execute(), "The generated types should compile offline")
+
+	// Without repositories, unions and helper types have no BSON methods
+	// and the module does not depend on the Mongo driver
+	goMod, err := os.ReadFile(filepath.Join(cfg.OutputDir, "go.mod"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(goMod), "go.mongodb.org")
+	for _, name := range []string{"types.go", "date.go"} {
+		code, err := os.ReadFile(filepath.Join(cfg.OutputDir, "internal", "pkg", "domain", name))
+		require.NoError(t, err)
+		assert.NotContains(t, string(code), "go.mongodb.org", name)
+		assert.NotContains(t, string(code), "MarshalBSONValue", name)
+	}
+
+	// The Mongo repositories store them with their BSON methods
+	cfg.GenMongo = true
+	cfg.DryRun = true
+	pipeline, err = NewGenerationPipeline(cfg, templateFS)
+	require.NoError(t, err)
+	pipeline.SetOutput(io.Discard)
+	plan, err := pipeline.Plan()
+	require.NoError(t, err)
+	checked := 0
+	for _, file := range plan.Files {
+		if file.Path == "internal/pkg/domain/types.go" || file.Path == "internal/pkg/domain/date.go" {
+			assert.Contains(t, string(file.Content), `"go.mongodb.org/mongo-driver/bson"`, file.Path)
+			assert.Contains(t, string(file.Content), "MarshalBSONValue", file.Path)
+			checked++
+		}
+	}
+	assert.Equal(t, 2, checked, "types.go and date.go should be planned")
+	assert.Contains(t, plan.Dependencies, "go.mongodb.org/mongo-driver/bson")
+}
+
+func TestGenerationPipeline_UntaggedOperation(t *testing.T) {
+	specFile := testutil.CreateTempFile(t, "openapi.yaml", `
+openapi: 3.0.0
+info:
+  title: Health
+  version: 1.0.0
+paths:
+  /health:
+    get:
+      operationId: health
+      responses:
+        "200":
+          description: Healthy
+`)
+	pipeline, err := NewGenerationPipeline(&GenerationConfig{
+		SpecFiles: []string{specFile},
+		OutputDir: t.TempDir(),
+		GenHTTP:   true,
+	}, templateFS)
+	require.NoError(t, err)
+	pipeline.SetOutput(io.Discard)
+
+	// The handler of an untagged operation would have no service to call
+	_, err = pipeline.Plan()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "operation health has no tags: tag it with the resource it belongs to")
+}
+
+func TestHTTPOrigin(t *testing.T) {
+	operationIDs := map[string]string{"getpet": "getPet", "listpets": "listPets"}
+	extras := []string{"templates/http/doc.go.tmpl"}
+
+	tests := []struct {
+		filename string
+		template string
+		subject  string
+	}{
+		{"httputil/http_utils.go", "http/http_utils.go.tmpl", ""},
+		{"httputil/" + config.OpenAPISpecFile, "", ""},
+		{"domain/pet/handler.go", "http/schema_handler.go.tmpl", "resource pet"},
+		{"domain/pet/mocks/mock_service.go", "http/mocks.go.tmpl", "resource pet"},
+		{"domain/pet/getpet_handler.go", "http/operation_handler.go.tmpl", "operation getPet"},
+		{"domain/pet/listpets_handler_test.go", "http/operation_handler_test.go.tmpl", "operation listPets"},
+		{"domain/pet/getpet_doc.go", "http/doc.go.tmpl", "operation getPet"},
+		{"unknown_handler.go", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			template, subject := httpOrigin(tt.filename, operationIDs, extras)
+			assert.Equal(t, tt.template, template)
+			assert.Equal(t, tt.subject, subject)
+		})
+	}
 }
@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/zeek-r/goapigen/internal/lint"
+	"github.com/zeek-r/goapigen/internal/parser"
+)
+
+// Exit codes of the lint command
+const (
+	lintExitClean  = 0
+	lintExitIssues = 1
+	lintExitError  = 2
+)
+
+// runLint runs the lint command with its arguments and returns the exit
+// code: 1 when the spec has issues, 2 when it cannot be read
+func runLint(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("lint", "[flags] [spec...]", `
+Lint reports the problems of a spec that break or degrade the generated code
+and that spec validation lets through, such as operations without an
+operationId or names colliding once converted to Go.
+
+Exit codes: 0 when the spec is clean, 1 when issues are found, 2 when the
+spec cannot be read.`, stderr)
+
+	var specFiles stringList
+	flags.Var(&specFiles, "spec", "OpenAPI specification: file, directory, URL, go:<module>/<file> or - for stdin (repeatable, documents are merged)")
+	format := flags.String("format", "text", "Output format: text or json")
+
+	if err := parseFlags(flags, args, stdout); err != nil {
+		if err == flag.ErrHelp {
+			return lintExitClean
+		}
+		return lintExitError
+	}
+	// Specs may also be given as arguments, e.g. goapigen lint api.yaml
+	specFiles = append(specFiles, flags.Args()...)
+
+	if len(specFiles) == 0 {
+		fmt.Fprintln(stderr, "Error: OpenAPI specification file is required")
+		flags.Usage()
+		return lintExitError
+	}
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(stderr, "Error: unknown format %q, expected text or json\n", *format)
+		return lintExitError
+	}
+
+	apiParser, err := parser.NewOpenAPIParser(specFiles...)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error parsing OpenAPI spec: %v\n", err)
+		return lintExitError
+	}
+
+	issues := lint.Lint(apiParser)
+	if *format == "json" {
+		if issues == nil {
+			issues = []lint.Issue{}
+		}
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(issues); err != nil {
+			fmt.Fprintf(stderr, "Error writing issues: %v\n", err)
+			return lintExitError
+		}
+	} else {
+		for _, issue := range issues {
+			fmt.Fprintln(stdout, issue)
+		}
+	}
+
+	if len(issues) > 0 {
+		if *format == "text" {
+			fmt.Fprintf(stderr, "%d issue(s) found\n", len(issues))
+		}
+		return lintExitIssues
+	}
+	return lintExitClean
+}
@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zeek-r/goapigen/internal/lint"
+	"github.com/zeek-r/goapigen/internal/testutil"
+)
+
+const untaggedSpec = `openapi: 3.0.3
+info: {title: Lint, version: "1"}
+paths:
+  /health:
+    get:
+      operationId: health
+      responses:
+        "200": {description: OK}
+`
+
+func TestRunLint(t *testing.T) {
+	specFile := testutil.CreateTempFile(t, "openapi.yaml", untaggedSpec)
+
+	t.Run("text", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		code := runLint([]string{"--spec", specFile}, &stdout, &stderr)
+
+		assert.Equal(t, lintExitIssues, code)
+		assert.Equal(t, specFile+":5:5: operation GET /health has no tags, so it belongs to no domain and gets no HTTP handler (untagged-operation)\n", stdout.String())
+		assert.Contains(t, stderr.String(), "1 issue(s) found")
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		code := runLint([]string{"-format", "json", specFile}, &stdout, &stderr)
+		assert.Equal(t, lintExitIssues, code)
+
+		var issues []lint.Issue
+		require.NoError(t, json.Unmarshal(stdout.Bytes(), &issues))
+		require.Len(t, issues, 1)
+		assert.Equal(t, lint.RuleUntaggedOperation, issues[0].Rule)
+		assert.Equal(t, 5, issues[0].Location.Line)
+	})
+
+	t.Run("clean", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		code := runLint([]string{"-format", "json", "../../examples/petstore/openapi.yaml"}, &stdout, &stderr)
+		assert.Equal(t, lintExitClean, code, stdout.String())
+		assert.Equal(t, "[]\n", stdout.String())
+	})
+
+	t.Run("unreadable", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, lintExitError, runLint([]string{"missing.yaml"}, &stdout, &stderr))
+		assert.Equal(t, lintExitError, runLint(nil, &stdout, &stderr))
+		assert.Equal(t, lintExitError, runLint([]string{"-format", "xml", specFile}, &stdout, &stderr))
+	})
+}
@@ -0,0 +1,170 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/zeek-r/goapigen/internal/config"
+	"github.com/zeek-r/goapigen/internal/generator"
+	"github.com/zeek-r/goapigen/internal/parser"
+)
+
+// findProject returns the project file to read: the one named by --config,
+// else goapigen.yaml in the output directory given on the command line or
+// in the working directory. It returns an empty path when there is none.
+func findProject(configFile, outputDir string) (string, error) {
+	if configFile != "" {
+		if _, err := os.Stat(configFile); err != nil {
+			return "", fmt.Errorf("failed to read project file: %w", err)
+		}
+		return configFile, nil
+	}
+
+	candidate := filepath.Join(outputDir, config.ProjectFile)
+	if _, err := os.Stat(candidate); err != nil {
+		return "", nil
+	}
+	return candidate, nil
+}
+
+// applyProject sets the options of a project file on a configuration
+func applyProject(cfg *GenerationConfig, project *config.Project) error {
+	if len(project.Specs) > 0 {
+		cfg.SpecFiles = nil
+		for _, spec := range project.Specs {
+			source, err := parser.ParseSpecSource(spec)
+			if err != nil {
+				return err
+			}
+			// Files are named relative to the project file
+			if _, isFile := source.(*parser.FileSource); isFile {
+				spec = project.Path(spec)
+			}
+			cfg.SpecFiles = append(cfg.SpecFiles, spec)
+		}
+	}
+
+	cfg.OutputDir = project.Dir()
+	if project.Output != "" {
+		cfg.OutputDir = project.Path(project.Output)
+	}
+	if project.Package != "" {
+		cfg.PackageName = project.Package
+	}
+	if project.HTTPPackage != "" {
+		cfg.HTTPPackage = project.HTTPPackage
+	}
+
+	for _, generate := range []struct {
+		value *bool
+		field *bool
+	}{
+		{project.Generate.Types, &cfg.GenTypes},
+		{project.Generate.Services, &cfg.GenServices},
+		{project.Generate.Mongo, &cfg.GenMongo},
+		{project.Generate.HTTP, &cfg.GenHTTP},
+	} {
+		if generate.value != nil {
+			*generate.field = *generate.value
+		}
+	}
+
+	if project.Optional != "" {
+		strategy, err := generator.ParseOptionalStrategy(project.Optional)
+		if err != nil {
+			return err
+		}
+		cfg.OptionalStrategy = strategy
+	}
+	cfg.SplitTypes = project.SplitTypes
+	cfg.OpenAPIValidation = project.OpenAPIValidation
+
+	formats := make([]string, 0, len(project.Formats))
+	for format, goType := range project.Formats {
+		formats = append(formats, format+"="+goType)
+	}
+	sort.Strings(formats)
+	cfg.FormatMappings = formats
+
+	cfg.Layout = project.Layout
+	cfg.TemplatesDir = project.Path(project.Templates)
+	cfg.Schemas = project.Schemas
+	return nil
+}
+
+// projectPath returns how the project file in dir names a path given on the
+// command line: relative paths stay relative, now to the project file
+func projectPath(dir, name string) (string, error) {
+	if filepath.IsAbs(name) {
+		return name, nil
+	}
+	absPath, err := filepath.Abs(name)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(dir, absPath)
+	if err != nil {
+		return name, nil
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// projectFor records a configuration as the project file of its output
+// directory. Specs read from standard input cannot be recorded.
+func projectFor(cfg *GenerationConfig) (*config.Project, error) {
+	outputDir, err := filepath.Abs(cfg.OutputDir)
+	if err != nil {
+		return nil, err
+	}
+
+	generate := func(value bool) *bool { return &value }
+	project := &config.Project{
+		Package:     cfg.PackageName,
+		HTTPPackage: cfg.HTTPPackage,
+		Generate: config.Generators{
+			Types:    generate(cfg.GenTypes),
+			Services: generate(cfg.GenServices),
+			Mongo:    generate(cfg.GenMongo),
+			HTTP:     generate(cfg.GenHTTP),
+		},
+		Optional:          string(cfg.OptionalStrategy),
+		SplitTypes:        cfg.SplitTypes,
+		OpenAPIValidation: cfg.OpenAPIValidation,
+		Layout:            cfg.Layout,
+		Schemas:           cfg.Schemas,
+	}
+
+	for _, spec := range cfg.SpecFiles {
+		source, err := parser.ParseSpecSource(spec)
+		if err != nil {
+			return nil, err
+		}
+		switch source := source.(type) {
+		case *parser.ReaderSource:
+			continue
+		case *parser.FileSource:
+			if spec, err = projectPath(outputDir, source.Path); err != nil {
+				return nil, err
+			}
+		}
+		project.Specs = append(project.Specs, spec)
+	}
+
+	if cfg.TemplatesDir != "" {
+		if project.Templates, err = projectPath(outputDir, cfg.TemplatesDir); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(cfg.FormatMappings) > 0 {
+		project.Formats = make(map[string]string)
+		for _, mapping := range cfg.FormatMappings {
+			format, goType, _ := strings.Cut(mapping, "=")
+			project.Formats[format] = goType
+		}
+	}
+	return project, nil
+}
@@ -0,0 +1,161 @@
+package cli
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zeek-r/goapigen/internal/config"
+	"github.com/zeek-r/goapigen/internal/generator"
+)
+
+// parseGenerationFlags returns the configuration of a generate command line
+func parseGenerationFlags(t *testing.T, args ...string) (*GenerationConfig, error) {
+	t.Helper()
+	flags := flag.NewFlagSet("generate", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	genFlags := addGenerationFlags(flags)
+	require.NoError(t, flags.Parse(args))
+	return genFlags.Config(flags.Args())
+}
+
+func TestGenerationFlags_Project(t *testing.T) {
+	dir := t.TempDir()
+	specPath, err := filepath.Abs(petstoreSpec)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, config.ProjectFile), []byte(`
+specs: [`+filepath.ToSlash(specPath)+`]
+package: petstore
+generate:
+  services: true
+  http: true
+optional: pointer
+formats:
+  uuid: github.com/google/uuid.UUID
+layout:
+  domain: pkg/model
+templates: tmpl
+`), 0644))
+
+	t.Run("file_values", func(t *testing.T) {
+		cfg, err := parseGenerationFlags(t, "--output", dir)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{filepath.ToSlash(specPath)}, cfg.SpecFiles)
+		assert.Equal(t, dir, cfg.OutputDir)
+		assert.Equal(t, "petstore", cfg.PackageName)
+		assert.Equal(t, config.DefaultHandlerPackage, cfg.HTTPPackage)
+		assert.True(t, cfg.GenTypes)
+		assert.True(t, cfg.GenServices)
+		assert.True(t, cfg.GenHTTP)
+		assert.False(t, cfg.GenMongo)
+		assert.Equal(t, generator.OptionalPointer, cfg.OptionalStrategy)
+		assert.Equal(t, []string{"uuid=github.com/google/uuid.UUID"}, cfg.FormatMappings)
+		assert.Equal(t, filepath.Join(dir, config.ProjectFile), cfg.ProjectFile)
+		assert.Equal(t, config.LayoutConfig{Layout: config.Layout{Domain: "pkg/model"}}, cfg.Layout)
+		assert.Equal(t, filepath.Join(dir, "tmpl"), cfg.TemplatesDir, "Templates should be relative to the project file")
+	})
+
+	t.Run("flags_override", func(t *testing.T) {
+		cfg, err := parseGenerationFlags(t, "--output", dir, "--http=false", "--package", "api",
+			"--optional", "value", "--format", "uuid=string", "--layout", "hexagonal", "other.yaml")
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"other.yaml"}, cfg.SpecFiles)
+		assert.Equal(t, "api", cfg.PackageName)
+		assert.False(t, cfg.GenHTTP)
+		assert.True(t, cfg.GenServices, "Flags not given keep the file value")
+		assert.Equal(t, generator.OptionalValue, cfg.OptionalStrategy)
+		assert.Equal(t, []string{"uuid=github.com/google/uuid.UUID", "uuid=string"}, cfg.FormatMappings)
+		assert.Equal(t, config.LayoutConfig{Preset: "hexagonal", Layout: config.Layout{Domain: "pkg/model"}}, cfg.Layout,
+			"The preset flag should keep the directories of the file")
+
+		_, err = parseGenerationFlags(t, "--output", dir, "--layout", "onion")
+		assert.ErrorContains(t, err, "unknown layout preset")
+	})
+
+	t.Run("explicit_config", func(t *testing.T) {
+		_, err := parseGenerationFlags(t, "--config", filepath.Join(dir, "missing.yaml"))
+		assert.Error(t, err)
+
+		// The output directory defaults to the directory of the file
+		cfg, err := parseGenerationFlags(t, "--config", filepath.Join(dir, config.ProjectFile))
+		require.NoError(t, err)
+		assert.Equal(t, dir, cfg.OutputDir)
+	})
+}
+
+func TestInitRecordsProject(t *testing.T) {
+	root := t.TempDir()
+	outputDir := filepath.Join(root, "svc")
+	specDir := filepath.Join(root, "api")
+	require.NoError(t, os.MkdirAll(specDir, 0755))
+	spec, err := os.ReadFile(petstoreSpec)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(specDir, "openapi.yaml"), spec, 0644))
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(root))
+	defer os.Chdir(wd)
+
+	var stdout, stderr bytes.Buffer
+	code := runInit([]string{"--output", "svc", "--services", "--optional", "generic", "api/openapi.yaml"}, &stdout, &stderr)
+	require.Equal(t, generateExitOK, code, stderr.String())
+
+	project, err := config.LoadProject(filepath.Join(outputDir, config.ProjectFile))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"../api/openapi.yaml"}, project.Specs, "Specs should be relative to the project file")
+	assert.Equal(t, "generic", project.Optional)
+	require.NotNil(t, project.Generate.Services)
+	assert.True(t, *project.Generate.Services)
+
+	// Regenerating from the project directory needs no flags
+	require.NoError(t, os.Chdir(outputDir))
+	assert.Equal(t, diffExitClean, runDiff(nil, &stdout, &stderr), stdout.String())
+}
+
+func TestSchemaOverrides(t *testing.T) {
+	outputDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(outputDir, config.ProjectFile), []byte(`
+generate: {services: true, mongo: true}
+schemas:
+  Order: {skip: true}
+  Pet: {collection: animals}
+`), 0644))
+
+	var stdout, stderr bytes.Buffer
+	code := runInit([]string{"--output", outputDir, petstoreSpec}, &stdout, &stderr)
+	require.Equal(t, generateExitOK, code, stderr.String())
+
+	assert.NoDirExists(t, filepath.Join(outputDir, "internal/services/order"))
+	assert.NoFileExists(t, filepath.Join(outputDir, "internal/adapters/repository/order/order_repository.go"))
+
+	repo, err := os.ReadFile(filepath.Join(outputDir, "internal/adapters/repository/pet/pet_repository.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(repo), `db.Collection("animals")`)
+
+	mains, err := filepath.Glob(filepath.Join(outputDir, "cmd", "*", "main.go"))
+	require.NoError(t, err)
+	require.Len(t, mains, 1)
+	content, err := os.ReadFile(mains[0])
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "/internal/services/order", "Skipped schemas should not be wired")
+	assert.NotContains(t, string(content), "/internal/services/pet", "Services are wired with their handlers only")
+
+	// Handlers of a skipped schema's operations would call a missing service
+	stderr.Reset()
+	assert.Equal(t, generateExitFailed, runGenerate([]string{"--output", outputDir, "--http", petstoreSpec}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "schema Order is skipped")
+
+	// Overrides must name schemas of the spec
+	require.NoError(t, os.WriteFile(filepath.Join(outputDir, config.ProjectFile), []byte("schemas:\n  Owner: {skip: true}\n"), 0644))
+	stderr.Reset()
+	assert.Equal(t, generateExitFailed, runGenerate([]string{"--output", outputDir, petstoreSpec}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), `schema override "Owner" matches no schema`)
+}
@@ -0,0 +1,171 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Exit codes of the templates command
+const (
+	templatesExitOK     = 0
+	templatesExitFailed = 1
+	templatesExitUsage  = exitUsage
+)
+
+const templatesDescription = `
+Templates export writes the embedded templates to a directory, to start
+customized templates from. Point --templates, or templates: in the project
+file, at the directory: its files replace the embedded templates of the same
+path, and the other .tmpl files of service/, mongo/ and http/ are rendered
+for every schema, repository or operation with the data of the templates
+next to them. Existing files are kept unless --overwrite is given.
+
+Exit codes: 0 on success, 1 when writing fails, 2 on invalid flags.`
+
+// templatesRoot is the directory of the embedded templates. Template
+// directories mirror the paths below it, e.g. service/service.go.tmpl.
+const templatesRoot = "templates"
+
+// extraTemplateDirs hold the templates rendered for every schema or
+// operation besides the embedded ones, by generator
+var extraTemplateDirs = []string{"http", "mongo", "service"}
+
+// overlayFS serves the templates of a directory in place of the embedded
+// templates with the same path
+type overlayFS struct {
+	overlay fs.FS
+	base    fs.FS
+}
+
+func (o overlayFS) Open(name string) (fs.File, error) {
+	if rel, ok := strings.CutPrefix(name, templatesRoot+"/"); ok {
+		file, err := o.overlay.Open(rel)
+		if err == nil {
+			return file, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return o.base.Open(name)
+}
+
+// loadTemplates overlays a template directory on the embedded templates. It
+// returns the templates to read from and the paths of the added templates
+// by directory. Files matching no embedded template outside of the
+// directories of added templates are reported, as they would be ignored.
+func loadTemplates(dir string, base fs.FS) (fs.FS, map[string][]string, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read templates: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, nil, fmt.Errorf("templates %s is not a directory", dir)
+	}
+
+	overlay := os.DirFS(dir)
+	extras := make(map[string][]string)
+	err = fs.WalkDir(overlay, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || !strings.HasSuffix(name, ".tmpl") {
+			return err
+		}
+		templatePath := path.Join(templatesRoot, name)
+		if _, err := fs.Stat(base, templatePath); err == nil {
+			return nil
+		}
+
+		component := path.Dir(name)
+		for _, extraDir := range extraTemplateDirs {
+			if component == extraDir {
+				extras[component] = append(extras[component], templatePath)
+				return nil
+			}
+		}
+		return fmt.Errorf("template %s replaces no embedded template; added templates go in %s/",
+			filepath.Join(dir, filepath.FromSlash(name)), strings.Join(extraTemplateDirs, "/, "))
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return overlayFS{overlay: overlay, base: base}, extras, nil
+}
+
+// exportTemplates writes the embedded templates to a directory and returns
+// the paths written. Existing files are kept unless overwrite is set.
+func exportTemplates(dir string, overwrite bool, out io.Writer) ([]string, error) {
+	var written []string
+	err := fs.WalkDir(templateFS, templatesRoot, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		target := filepath.Join(dir, filepath.FromSlash(strings.TrimPrefix(name, templatesRoot+"/")))
+		if _, err := os.Stat(target); err == nil && !overwrite {
+			fmt.Fprintf(out, "%s already exists. Skipping (use --overwrite to force overwrite)\n", target)
+			return nil
+		}
+
+		content, err := fs.ReadFile(templateFS, name)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", target, err)
+		}
+		if err := os.WriteFile(target, content, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", target, err)
+		}
+		written = append(written, target)
+		return nil
+	})
+	sort.Strings(written)
+	return written, err
+}
+
+// runTemplates runs the templates command with its arguments and returns
+// the exit code
+func runTemplates(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("templates", "export [flags]", templatesDescription, stderr)
+	output := flags.String("output", templatesRoot, "Directory to write the templates to")
+	overwrite := flags.Bool("overwrite", false, "Overwrite existing files (default: false)")
+
+	switch {
+	case len(args) > 0 && args[0] == "export":
+		args = args[1:]
+	case len(args) > 0 && (args[0] == "-h" || args[0] == "-help" || args[0] == "--help"):
+		printHelp(flags, stdout)
+		return templatesExitOK
+	default:
+		if len(args) > 0 {
+			fmt.Fprintf(stderr, "Error: unknown templates command %q\n\n", args[0])
+		}
+		flags.Usage()
+		return templatesExitUsage
+	}
+	if err := parseFlags(flags, args, stdout); err != nil {
+		if err == flag.ErrHelp {
+			return templatesExitOK
+		}
+		return templatesExitUsage
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintf(stderr, "Error: unexpected arguments %s\n", strings.Join(flags.Args(), " "))
+		flags.Usage()
+		return templatesExitUsage
+	}
+
+	written, err := exportTemplates(*output, *overwrite, stdout)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return templatesExitFailed
+	}
+	fmt.Fprintf(stdout, "Exported %d templates to %s\n", len(written), *output)
+	return templatesExitOK
+}
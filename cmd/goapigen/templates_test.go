@@ -0,0 +1,285 @@
+package cli
+
+import (
+	"bytes"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zeek-r/goapigen/internal/config"
+	"github.com/zeek-r/goapigen/internal/generator"
+	specparser "github.com/zeek-r/goapigen/internal/parser"
+)
+
+// composedSpec covers the schema shapes with dedicated template branches:
+// allOf structs, enums, unions with and without a discriminator, and named
+// primitive, array and map types
+const composedSpec = `openapi: 3.0.3
+info: {title: Composed, version: "1"}
+paths: {}
+components:
+  schemas:
+    Code:
+      type: string
+      pattern: '^[A-Z]{3}$'
+    Tags:
+      type: array
+      minItems: 1
+      uniqueItems: true
+      items: {type: string}
+    Labels:
+      type: object
+      maxProperties: 2
+      additionalProperties: {$ref: '#/components/schemas/Line'}
+    Status:
+      type: string
+      enum: [open, closed]
+    Line:
+      type: object
+      required: [sku]
+      properties:
+        sku: {type: string, minLength: 2}
+        quantity: {type: integer, minimum: 1}
+    Card:
+      type: object
+      required: [kind, number]
+      properties:
+        kind: {type: string}
+        number: {type: string, pattern: '^[0-9]{16}$'}
+    Voucher:
+      type: object
+      required: [kind, code]
+      properties:
+        kind: {type: string}
+        code: {$ref: '#/components/schemas/Code'}
+    Method:
+      oneOf:
+        - $ref: '#/components/schemas/Card'
+        - $ref: '#/components/schemas/Voucher'
+      discriminator:
+        propertyName: kind
+    Contact:
+      anyOf:
+        - type: string
+          maxLength: 20
+        - type: object
+          properties:
+            email: {type: string}
+    Base:
+      type: object
+      properties:
+        id: {type: string}
+    Order:
+      allOf:
+        - $ref: '#/components/schemas/Base'
+        - type: object
+          required: [method, tags]
+          properties:
+            method: {$ref: '#/components/schemas/Method'}
+            tags: {$ref: '#/components/schemas/Tags'}
+            labels: {$ref: '#/components/schemas/Labels'}
+            status: {$ref: '#/components/schemas/Status'}
+            contact: {$ref: '#/components/schemas/Contact'}
+            lines:
+              type: array
+              items: {$ref: '#/components/schemas/Line'}
+`
+
+func TestDomainTypesTemplateCompiles(t *testing.T) {
+	dir := t.TempDir()
+	specFile := filepath.Join(dir, "openapi.yaml")
+	require.NoError(t, os.WriteFile(specFile, []byte(composedSpec), 0644))
+
+	apiParser, err := specparser.NewOpenAPIParser(specFile)
+	require.NoError(t, err)
+
+	for _, strategy := range []generator.OptionalStrategy{generator.OptionalValue, generator.OptionalPointer, generator.OptionalGeneric} {
+		t.Run(string(strategy), func(t *testing.T) {
+			typeGen := generator.NewTypeGenerator(apiParser, "domain", templateFS)
+			typeGen.SetOptionalStrategy(strategy)
+
+			files := map[string]string{}
+			typesCode, err := typeGen.GenerateTypes()
+			require.NoError(t, err)
+			files[config.TypesFile] = typesCode
+
+			helpers, err := typeGen.GenerateHelpers()
+			require.NoError(t, err)
+			for name, code := range helpers {
+				files[name] = code
+			}
+
+			tmpl, err := template.ParseFS(templateFS, config.DomainErrorsTemplate)
+			require.NoError(t, err)
+			var buf bytes.Buffer
+			require.NoError(t, tmpl.Execute(&buf, nil))
+			files[config.ErrorsFile] = buf.String()
+
+			typeCheck(t, files)
+		})
+	}
+}
+
+// typeCheck parses and type-checks generated sources as a single package.
+// Helpers may import modules this repository does not depend on (e.g. BSON
+// support of Optional), so only errors in the types file fail the test, and
+// not the imports of the MongoDB driver the BSON support of unions needs.
+func typeCheck(t *testing.T, files map[string]string) {
+	t.Helper()
+
+	fset := token.NewFileSet()
+	var parsed []*ast.File
+	for name, code := range files {
+		file, err := parser.ParseFile(fset, name, code, 0)
+		require.NoError(t, err, "generated %s does not parse:\n%s", name, code)
+		parsed = append(parsed, file)
+	}
+
+	var typeErrors []string
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "source", nil),
+		Error: func(err error) {
+			typeErr, ok := err.(types.Error)
+			if ok && strings.Contains(typeErr.Msg, `could not import go.mongodb.org/mongo-driver/`) {
+				return
+			}
+			if ok && typeErr.Fset.Position(typeErr.Pos).Filename == config.TypesFile {
+				typeErrors = append(typeErrors, err.Error())
+			}
+		},
+	}
+	_, _ = conf.Check("domain", fset, parsed, nil)
+	require.Empty(t, typeErrors, "generated %s does not type-check:\n%s", config.TypesFile, files[config.TypesFile])
+}
+
+func TestLoadTemplates(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate := func(name, content string) {
+		t.Helper()
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(file), 0755))
+		require.NoError(t, os.WriteFile(file, []byte(content), 0644))
+	}
+	writeTemplate("domain/errors.go.tmpl", "package domain\n\n// Customized\n")
+	writeTemplate("service/events.go.tmpl", "package {{.SchemaName | lower}}\n\ntype {{.TypeName}}Created struct{}\n")
+	writeTemplate("http/doc.go.tmpl", "package {{.HandlerPackage}}\n\n// {{.Method}} {{.Path}}\n")
+	writeTemplate("README.md", "Not a template")
+
+	templates, extras, err := loadTemplates(dir, templateFS)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"http":    {"templates/http/doc.go.tmpl"},
+		"service": {"templates/service/events.go.tmpl"},
+	}, extras)
+
+	// The directory wins over the embedded templates, which fill the gaps
+	content, err := fs.ReadFile(templates, config.DomainErrorsTemplate)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "Customized")
+	_, err = fs.ReadFile(templates, config.DomainTypesTemplate)
+	assert.NoError(t, err)
+
+	// Added templates are rendered for every schema and operation
+	outputDir := t.TempDir()
+	pipeline, err := NewGenerationPipeline(&GenerationConfig{
+		SpecFiles:    []string{petstoreSpec},
+		OutputDir:    outputDir,
+		HTTPPackage:  config.DefaultHandlerPackage,
+		GenTypes:     true,
+		GenHTTP:      true,
+		TemplatesDir: dir,
+		DryRun:       true,
+	}, templateFS)
+	require.NoError(t, err)
+	plan, err := pipeline.Plan()
+	require.NoError(t, err)
+
+	files := make(map[string]string)
+	for _, file := range plan.Files {
+		files[file.Path] = string(file.Content)
+	}
+	assert.Contains(t, files["internal/pkg/domain/errors.go"], "Customized")
+	assert.Equal(t, "package pet\n\ntype PetCreated struct{}\n", files["internal/services/pet/pet_events.go"])
+	assert.Equal(t, "package http\n\n// GET /pets/{id}\n", files["internal/adapters/http/pet/getpet_doc.go"])
+
+	// Templates replacing nothing outside the directories of added
+	// templates would be ignored
+	writeTemplate("domain/money.go.tmpl", "package domain\n")
+	_, _, err = loadTemplates(dir, templateFS)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "money.go.tmpl replaces no embedded template")
+
+	_, _, err = loadTemplates(filepath.Join(dir, "missing"), templateFS)
+	assert.Error(t, err)
+}
+
+func TestInvalidGoTemplate(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "service"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "service", "service.go.tmpl"),
+		[]byte("package {{.SchemaName | lower}}\n\nfunc New{{.SchemaName}}Service( {\n}\n"), 0644))
+
+	pipeline, err := NewGenerationPipeline(&GenerationConfig{
+		SpecFiles:    []string{petstoreSpec},
+		OutputDir:    t.TempDir(),
+		GenServices:  true,
+		TemplatesDir: dir,
+		DryRun:       true,
+	}, templateFS)
+	require.NoError(t, err)
+
+	// Code that does not parse fails generation, naming the template, the
+	// schema and the line
+	_, err = pipeline.Plan()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "template service.go.tmpl for schema Order rendered invalid Go: line 3:23: expected ')', found '{'\n     1 | package order\n     2 |\n>    3 | func NewOrderService( {\n     4 | }")
+}
+
+func TestRunTemplatesExport(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "templates")
+
+	var stdout, stderr bytes.Buffer
+	require.Equal(t, templatesExitOK, runTemplates([]string{"export", "--output", dir}, &stdout, &stderr), stderr.String())
+
+	// Every embedded template is exported at its path below templates/
+	err := fs.WalkDir(templateFS, templatesRoot, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		want, err := fs.ReadFile(templateFS, name)
+		require.NoError(t, err)
+		got, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(strings.TrimPrefix(name, templatesRoot+"/"))))
+		require.NoError(t, err)
+		assert.Equal(t, string(want), string(got), name)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// Exported templates reproduce the embedded ones
+	_, extras, err := loadTemplates(dir, templateFS)
+	require.NoError(t, err)
+	assert.Empty(t, extras)
+
+	// Edited templates are kept
+	edited := filepath.Join(dir, "service", "service.go.tmpl")
+	require.NoError(t, os.WriteFile(edited, []byte("edited"), 0644))
+	stdout.Reset()
+	require.Equal(t, templatesExitOK, runTemplates([]string{"export", "--output", dir}, &stdout, &stderr))
+	assert.Contains(t, stdout.String(), "Exported 0 templates")
+	content, err := os.ReadFile(edited)
+	require.NoError(t, err)
+	assert.Equal(t, "edited", string(content))
+
+	assert.Equal(t, templatesExitUsage, runTemplates(nil, &stdout, &stderr))
+	assert.Equal(t, templatesExitUsage, runTemplates([]string{"import"}, &stdout, &stderr))
+}
@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/debug"
+)
+
+const versionDescription = `
+Version prints the version of goapigen along with the commit and Go version
+it was built from, when the build recorded them.
+
+Exit codes: 0, or 2 on invalid flags.`
+
+// buildVersion is the version information of the running binary
+type buildVersion struct {
+	Version   string
+	Revision  string
+	Time      string
+	Modified  bool
+	GoVersion string
+}
+
+// readBuildVersion reads the version information embedded by the go command.
+// Binaries built from a checkout report (devel) with their commit, those
+// installed with go install report their module version.
+func readBuildVersion() buildVersion {
+	version := buildVersion{Version: "(devel)", GoVersion: runtime.Version()}
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return version
+	}
+	if info.Main.Version != "" {
+		version.Version = info.Main.Version
+	}
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			version.Revision = setting.Value
+		case "vcs.time":
+			version.Time = setting.Value
+		case "vcs.modified":
+			version.Modified = setting.Value == "true"
+		}
+	}
+	return version
+}
+
+// runVersion runs the version command with its arguments and returns the
+// exit code
+func runVersion(args []string, stdout, stderr io.Writer) int {
+	flags := newFlagSet("version", "", versionDescription, stderr)
+	if err := parseFlags(flags, args, stdout); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return exitUsage
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintf(stderr, "Error: unexpected arguments %v\n", flags.Args())
+		return exitUsage
+	}
+
+	version := readBuildVersion()
+	fmt.Fprintf(stdout, "goapigen %s\n", version.Version)
+	if version.Revision != "" {
+		revision := version.Revision
+		if version.Modified {
+			revision += " (modified)"
+		}
+		fmt.Fprintf(stdout, "commit:  %s\n", revision)
+	}
+	if version.Time != "" {
+		fmt.Fprintf(stdout, "built:   %s\n", version.Time)
+	}
+	fmt.Fprintf(stdout, "go:      %s\n", version.GoVersion)
+	return 0
+}
@@ -0,0 +1,338 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/zeek-r/goapigen/internal/generator"
+	"github.com/zeek-r/goapigen/internal/parser"
+)
+
+// The inputs of watch mode are checked every watchInterval, and generated
+// again once they have not changed for watchDebounce, so that an editor
+// saving several files, or a file in several writes, causes one generation
+const (
+	watchInterval = 250 * time.Millisecond
+	watchDebounce = 500 * time.Millisecond
+)
+
+// watchSummaryLimit bounds the subjects listed by the summary of a generation
+const watchSummaryLimit = 5
+
+// watcher generates a project again whenever the spec files, the files they
+// reference, the project file or the templates change
+type watcher struct {
+	config *GenerationConfig                 // Configuration of the last generation
+	load   func() (*GenerationConfig, error) // Reads the flags and the project file again
+	stdout io.Writer
+	stderr io.Writer
+
+	interval time.Duration
+	debounce time.Duration
+
+	// cache holds the code rendered by the earlier generations, so that only
+	// the schemas and operations whose data changed are rendered again
+	cache *generator.RenderCache
+	// specFiles are the files the spec was last read from, references included
+	specFiles []string
+	// dependencies were added to go.mod by an earlier generation
+	dependencies map[string]bool
+	// generated is set once a generation succeeded
+	generated bool
+}
+
+// newWatcher creates a watcher of the inputs of a configuration, loaded
+// again by load before each generation
+func newWatcher(cfg *GenerationConfig, load func() (*GenerationConfig, error), stdout, stderr io.Writer) *watcher {
+	return &watcher{
+		config:       cfg,
+		load:         load,
+		stdout:       stdout,
+		stderr:       stderr,
+		interval:     watchInterval,
+		debounce:     watchDebounce,
+		cache:        generator.NewRenderCache(),
+		dependencies: make(map[string]bool),
+	}
+}
+
+// watchable reports whether one of the specs is read from disk. URLs,
+// modules and standard input are read once and cannot be watched.
+func watchable(specs []string) bool {
+	for _, spec := range specs {
+		if source, err := parser.ParseSpecSource(spec); err == nil {
+			if _, ok := source.(*parser.FileSource); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// run generates the project, then generates it again on every change of
+// its inputs until ctx is done. Errors are printed and the watch goes on.
+func (w *watcher) run(ctx context.Context) {
+	w.generate()
+	generated := w.inputs()
+	fmt.Fprintf(w.stdout, "Watching %d file(s) for changes, press Ctrl+C to stop\n", len(generated))
+
+	seen := generated
+	var changedAt time.Time
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			current := w.inputs()
+			if !current.equal(seen) {
+				seen, changedAt = current, now
+				continue
+			}
+			if changedAt.IsZero() || now.Sub(changedAt) < w.debounce {
+				continue
+			}
+			changedAt = time.Time{}
+
+			// Files changed and restored since the last generation are left alone
+			changed := generated.changed(current)
+			if len(changed) == 0 {
+				continue
+			}
+			if w.invalidates(changed) {
+				w.cache = generator.NewRenderCache()
+			}
+			fmt.Fprintf(w.stdout, "\nChanged: %s\n", strings.Join(displayPaths(changed), ", "))
+			w.generate()
+
+			// Files written during the generation keep their earlier state, so
+			// that their change is seen by the next tick
+			generated = w.inputs()
+			for name, state := range current {
+				if _, ok := generated[name]; ok {
+					generated[name] = state
+				}
+			}
+			seen = generated
+		}
+	}
+}
+
+// generate runs the pipeline and prints a summary, or the error that
+// stopped it
+func (w *watcher) generate() {
+	start := time.Now()
+	cfg, err := w.load()
+	if err != nil {
+		w.fail(err)
+		return
+	}
+	w.config = cfg
+
+	pipeline, err := NewGenerationPipeline(cfg, templateFS)
+	if err != nil {
+		w.fail(err)
+		return
+	}
+	pipeline.SetOutput(w.stdout)
+	pipeline.SetRenderCache(w.cache)
+	pipeline.SetRefresh(true)
+	w.specFiles = pipeline.SpecFiles()
+
+	plan, err := pipeline.Plan()
+	rendered := w.cache.Flush()
+	if err != nil {
+		w.fail(err)
+		return
+	}
+	changes, err := pipeline.Changes(plan)
+	if err != nil {
+		w.fail(err)
+		return
+	}
+
+	dependencies := plan.Dependencies
+	plan.Dependencies = nil
+	for _, dep := range dependencies {
+		if !w.dependencies[dep] {
+			plan.Dependencies = append(plan.Dependencies, dep)
+		}
+	}
+
+	if err := pipeline.Apply(plan); err != nil {
+		w.fail(err)
+		return
+	}
+	for _, dep := range plan.Dependencies {
+		w.dependencies[dep] = true
+	}
+	first := !w.generated
+	w.generated = true
+
+	if cfg.Verify {
+		if err := pipeline.Verify(plan); err != nil {
+			w.fail(err)
+			return
+		}
+	}
+	fmt.Fprintf(w.stdout, "%s Generated in %s: %s\n", time.Now().Format(time.TimeOnly),
+		time.Since(start).Round(time.Millisecond), summarize(changes, rendered, first))
+}
+
+// fail prints the error of a generation
+func (w *watcher) fail(err error) {
+	fmt.Fprintf(w.stderr, "%s Error: %v\n", time.Now().Format(time.TimeOnly), err)
+}
+
+// invalidates reports whether a change makes the rendered code stale: the
+// templates the code was rendered from or the project file changed
+func (w *watcher) invalidates(changed []string) bool {
+	var projectFile, templatesDir string
+	if w.config.ProjectFile != "" {
+		projectFile, _ = filepath.Abs(w.config.ProjectFile)
+	}
+	if w.config.TemplatesDir != "" {
+		templatesDir, _ = filepath.Abs(w.config.TemplatesDir)
+	}
+
+	for _, name := range changed {
+		if name == projectFile {
+			return true
+		}
+		if templatesDir != "" {
+			if rel, err := filepath.Rel(templatesDir, name); err == nil && !strings.HasPrefix(rel, "..") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// inputs returns the state of the files the project is generated from
+func (w *watcher) inputs() inputState {
+	state := make(inputState)
+	for _, spec := range w.config.SpecFiles {
+		source, err := parser.ParseSpecSource(spec)
+		if err != nil {
+			continue
+		}
+		file, ok := source.(*parser.FileSource)
+		if !ok {
+			continue
+		}
+		// A directory stands for the documents at its top level, including
+		// those added while watching
+		if entries, err := os.ReadDir(file.Path); err == nil {
+			for _, entry := range entries {
+				state.add(filepath.Join(file.Path, entry.Name()))
+			}
+			continue
+		}
+		state.add(file.Path)
+	}
+	for _, name := range w.specFiles {
+		state.add(name)
+	}
+	if w.config.ProjectFile != "" {
+		state.add(w.config.ProjectFile)
+	}
+	if w.config.TemplatesDir != "" {
+		_ = filepath.WalkDir(w.config.TemplatesDir, func(name string, entry fs.DirEntry, err error) error {
+			if err == nil && !entry.IsDir() {
+				state.add(name)
+			}
+			return nil
+		})
+	}
+	return state
+}
+
+// fileState is what a watched file is compared by
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// inputState maps the watched files to their state. Missing files are left
+// out, so that creating one is a change.
+type inputState map[string]fileState
+
+// add records the state of a file by its absolute path, if it exists
+func (s inputState) add(name string) {
+	info, err := os.Stat(name)
+	if err != nil || info.IsDir() {
+		return
+	}
+	if absName, err := filepath.Abs(name); err == nil {
+		s[absName] = fileState{modTime: info.ModTime(), size: info.Size()}
+	}
+}
+
+// equal reports whether two states have the same files in the same state
+func (s inputState) equal(other inputState) bool {
+	return len(s.changed(other)) == 0
+}
+
+// changed returns the files created, modified or deleted from s to other, in
+// order
+func (s inputState) changed(other inputState) []string {
+	var changed []string
+	for name, state := range other {
+		if previous, ok := s[name]; !ok || !previous.modTime.Equal(state.modTime) || previous.size != state.size {
+			changed = append(changed, name)
+		}
+	}
+	for name := range s {
+		if _, ok := other[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// summarize describes a generation: how many files it changed and, unless
+// it is the first, the schemas, operations and resources rendered again
+func summarize(changes []FileChange, rendered []string, first bool) string {
+	summary := "no changes"
+	if len(changes) > 0 {
+		summary = fmt.Sprintf("%d file(s) changed", len(changes))
+	}
+	if first || len(rendered) == 0 {
+		return summary
+	}
+
+	listed := rendered
+	if len(listed) > watchSummaryLimit {
+		listed = listed[:watchSummaryLimit]
+	}
+	summary += ", rendered " + strings.Join(listed, ", ")
+	if more := len(rendered) - len(listed); more > 0 {
+		summary += fmt.Sprintf(" and %d more", more)
+	}
+	return summary
+}
+
+// displayPaths names files relative to the working directory when below it
+func displayPaths(names []string) []string {
+	wd, wdErr := os.Getwd()
+	display := make([]string, len(names))
+	for i, name := range names {
+		display[i] = name
+		if wdErr != nil {
+			continue
+		}
+		if rel, err := filepath.Rel(wd, name); err == nil && !strings.HasPrefix(rel, "..") {
+			display[i] = rel
+		}
+	}
+	return display
+}
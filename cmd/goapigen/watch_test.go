@@ -0,0 +1,190 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zeek-r/goapigen/internal/testutil"
+)
+
+// syncBuffer is a buffer written by a watcher while a test reads it
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// waitFor waits until a buffer contains s count times
+func waitFor(t *testing.T, b *syncBuffer, s string, count int) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		return strings.Count(b.String(), s) >= count
+	}, 10*time.Second, 10*time.Millisecond, "waiting for %q in:\n%s", s, b)
+}
+
+// watchSpec has a schema referenced from a file of its own, and a schema
+// and an operation that edits of Pet leave alone
+const watchSpec = `
+openapi: 3.0.0
+info:
+  title: Pets
+  version: 1.0.0
+paths:
+  /pets:
+    post:
+      operationId: createPet
+      tags: [Pet]
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: 'pet.yaml'
+      responses:
+        "201":
+          description: Created
+          content:
+            application/json:
+              schema:
+                $ref: 'pet.yaml'
+  /owners/{id}:
+    get:
+      operationId: getOwner
+      tags: [Owner]
+      parameters:
+        - name: id
+          in: path
+          required: true
+          schema:
+            type: string
+      responses:
+        "200":
+          description: OK
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/Owner'
+components:
+  schemas:
+    Owner:
+      type: object
+      properties:
+        name:
+          type: string
+`
+
+const watchPet = `
+type: object
+required: [name]
+properties:
+  name:
+    type: string
+`
+
+func TestWatcher(t *testing.T) {
+	dir := testutil.CreateTempTree(t, map[string]string{
+		"openapi.yaml": watchSpec,
+		"pet.yaml":     watchPet,
+	})
+	cfg := &GenerationConfig{
+		SpecFiles:   []string{filepath.Join(dir, "openapi.yaml")},
+		OutputDir:   filepath.Join(dir, "api"),
+		GenTypes:    true,
+		GenServices: true,
+		GenHTTP:     true,
+	}
+	load := func() (*GenerationConfig, error) {
+		loaded := *cfg
+		return &loaded, nil
+	}
+
+	var stdout, stderr syncBuffer
+	w := newWatcher(cfg, load, &stdout, &stderr)
+	w.interval, w.debounce = 10*time.Millisecond, 30*time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w.run(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// The referenced file is watched along with the spec
+	waitFor(t, &stdout, "Watching 2 file(s) for changes", 1)
+	assert.Empty(t, stderr.String())
+	typesFile := filepath.Join(cfg.OutputDir, "internal", "pkg", "domain", "types.go")
+	routesFile := filepath.Join(cfg.OutputDir, "cmd", "api", "routes.go")
+	routesInfo, err := os.Stat(routesFile)
+	require.NoError(t, err)
+
+	// Editing the referenced schema renders its code only, and the generated
+	// files left unedited are written again without --overwrite
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pet.yaml"), []byte(watchPet+"  tag:\n    type: string\n"), 0644))
+	waitFor(t, &stdout, "Generated in", 2)
+	output := stdout.String()
+	assert.Contains(t, output, "Changed: ")
+	assert.Regexp(t, `Generated in \S+: \d+ file\(s\) changed, rendered .*schema Pet`, output)
+	assert.Contains(t, output, "operation createPet")
+	assert.NotContains(t, output, "schema Owner")
+	assert.NotContains(t, output, "operation getOwner")
+	types, err := os.ReadFile(typesFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(types), "Tag string")
+
+	// Files left unchanged are not written again
+	info, err := os.Stat(routesFile)
+	require.NoError(t, err)
+	assert.Equal(t, routesInfo.ModTime(), info.ModTime())
+
+	// Generated files edited since are kept
+	edited := string(types) + "\n// Edited\n"
+	require.NoError(t, os.WriteFile(typesFile, []byte(edited), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pet.yaml"), []byte(watchPet+"  age:\n    type: integer\n"), 0644))
+	waitFor(t, &stdout, "Generated in", 3)
+	types, err = os.ReadFile(typesFile)
+	require.NoError(t, err)
+	assert.Equal(t, edited, string(types))
+	assert.Empty(t, stderr.String())
+
+	// A spec that does not parse is reported without stopping the watch
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "openapi.yaml"), []byte("openapi: 3.0.0\npaths: [\n"), 0644))
+	waitFor(t, &stderr, "Error: ", 1)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "openapi.yaml"), []byte(watchSpec), 0644))
+	waitFor(t, &stdout, "Generated in", 4)
+	assert.Contains(t, stdout.String(), "no changes")
+}
+
+func TestSummarize(t *testing.T) {
+	changes := []FileChange{{Kind: ChangeModify, Path: "a.go"}, {Kind: ChangeCreate, Path: "b.go"}}
+	rendered := []string{"operation a", "operation b", "operation c", "operation d", "schema A", "schema B", "schema C"}
+
+	assert.Equal(t, "2 file(s) changed", summarize(changes, rendered, true))
+	assert.Equal(t, "no changes", summarize(nil, nil, false))
+	assert.Equal(t, "2 file(s) changed, rendered schema A", summarize(changes, []string{"schema A"}, false))
+	assert.Equal(t, "2 file(s) changed, rendered operation a, operation b, operation c, operation d, schema A and 2 more",
+		summarize(changes, rendered, false))
+}
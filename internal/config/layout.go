@@ -0,0 +1,210 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Layout places the packages of a generated project. Directories are slash
+// separated and relative to the project root.
+//
+// Package names do not follow the directories: the domain package is
+// package domain wherever it lives, and is imported as domain when its
+// directory is named otherwise. Services, HTTP handlers and repositories get
+// one package per schema in a subdirectory named after it.
+type Layout struct {
+	Domain       string `yaml:"domain,omitempty"`
+	Services     string `yaml:"services,omitempty"`
+	HTTP         string `yaml:"http,omitempty"`
+	Repositories string `yaml:"repositories,omitempty"`
+	HTTPUtil     string `yaml:"httputil,omitempty"`
+	Config       string `yaml:"config,omitempty"`
+	Logger       string `yaml:"logger,omitempty"`
+}
+
+// DefaultLayoutPreset is the layout of projects that do not choose one
+const DefaultLayoutPreset = "standard"
+
+// layoutPresets are the layouts selected by name
+var layoutPresets = map[string]Layout{
+	// standard keeps everything under internal/, adapters apart
+	"standard": {
+		Domain:       "internal/pkg/domain",
+		Services:     "internal/services",
+		HTTP:         "internal/adapters/http",
+		Repositories: "internal/adapters/repository",
+		HTTPUtil:     "internal/pkg/httputil",
+		Config:       "internal/pkg/config",
+		Logger:       "internal/pkg/logger",
+	},
+	// flat puts one directory per package at the project root
+	"flat": {
+		Domain:       "domain",
+		Services:     "service",
+		HTTP:         "handler",
+		Repositories: "repository",
+		HTTPUtil:     "httputil",
+		Config:       "config",
+		Logger:       "logger",
+	},
+	// hexagonal separates the core from its adapters and the platform code
+	"hexagonal": {
+		Domain:       "internal/core/domain",
+		Services:     "internal/core/services",
+		HTTP:         "internal/adapters/http",
+		Repositories: "internal/adapters/repository",
+		HTTPUtil:     "internal/platform/httputil",
+		Config:       "internal/platform/config",
+		Logger:       "internal/platform/logger",
+	},
+}
+
+// DefaultLayout returns the standard layout
+func DefaultLayout() Layout {
+	return layoutPresets[DefaultLayoutPreset]
+}
+
+// LayoutPresets returns the names of the layout presets, sorted
+func LayoutPresets() []string {
+	names := make([]string, 0, len(layoutPresets))
+	for name := range layoutPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LayoutConfig is the layout section of a project file: a preset, standard
+// by default, and the directories that differ from it, e.g.
+//
+//	layout:
+//	  preset: flat
+//	  domain: pkg/model
+type LayoutConfig struct {
+	Preset string `yaml:"preset,omitempty"`
+	Layout `yaml:",inline"`
+}
+
+// Resolve returns the layout of the preset with the configured directories
+// in place of its own
+func (c LayoutConfig) Resolve() (Layout, error) {
+	preset := c.Preset
+	if preset == "" {
+		preset = DefaultLayoutPreset
+	}
+	layout, ok := layoutPresets[preset]
+	if !ok {
+		return Layout{}, fmt.Errorf("unknown layout preset %q, expected one of %s", preset, strings.Join(LayoutPresets(), ", "))
+	}
+
+	for _, dir := range []struct {
+		value string
+		field *string
+	}{
+		{c.Domain, &layout.Domain},
+		{c.Services, &layout.Services},
+		{c.HTTP, &layout.HTTP},
+		{c.Repositories, &layout.Repositories},
+		{c.HTTPUtil, &layout.HTTPUtil},
+		{c.Config, &layout.Config},
+		{c.Logger, &layout.Logger},
+	} {
+		if dir.value != "" {
+			*dir.field = dir.value
+		}
+	}
+
+	if err := layout.Validate(); err != nil {
+		return Layout{}, err
+	}
+	return layout, nil
+}
+
+// Validate checks that the directories of a layout are distinct clean
+// relative paths inside the project
+func (l Layout) Validate() error {
+	seen := make(map[string]string)
+	for _, dir := range l.dirs() {
+		switch {
+		case dir.path == "":
+			return fmt.Errorf("layout directory %s is empty", dir.name)
+		case path.IsAbs(dir.path) || strings.Contains(dir.path, `\`):
+			return fmt.Errorf("layout directory %s must be a slash-separated relative path, got %q", dir.name, dir.path)
+		case path.Clean(dir.path) != dir.path || dir.path == "." || dir.path == ".." || strings.HasPrefix(dir.path, "../"):
+			return fmt.Errorf("layout directory %s must be a clean path inside the project, got %q", dir.name, dir.path)
+		case dir.path == "cmd" || strings.HasPrefix(dir.path, "cmd/"):
+			return fmt.Errorf("layout directory %s must not be under cmd/, which holds the main package", dir.name)
+		}
+		if other, taken := seen[dir.path]; taken {
+			return fmt.Errorf("layout directories %s and %s are both %q", other, dir.name, dir.path)
+		}
+		seen[dir.path] = dir.name
+	}
+	return nil
+}
+
+// Dirs returns the directories of the layout, in a stable order
+func (l Layout) Dirs() []string {
+	dirs := make([]string, 0, 7)
+	for _, dir := range l.dirs() {
+		dirs = append(dirs, dir.path)
+	}
+	return dirs
+}
+
+type layoutDir struct {
+	name string
+	path string
+}
+
+func (l Layout) dirs() []layoutDir {
+	return []layoutDir{
+		{"domain", l.Domain},
+		{"services", l.Services},
+		{"http", l.HTTP},
+		{"repositories", l.Repositories},
+		{"httputil", l.HTTPUtil},
+		{"config", l.Config},
+		{"logger", l.Logger},
+	}
+}
+
+// LayoutImports are what templates import the packages of a layout with
+type LayoutImports struct {
+	// Import specs of the shared packages, aliased to the package name when
+	// the directory is named otherwise
+	Domain   string
+	HTTPUtil string
+	Config   string
+	Logger   string
+
+	// Import paths of the directories holding one package per schema
+	Services     string
+	HTTP         string
+	Repositories string
+}
+
+// Imports returns the imports of the layout's packages in a module
+func (l Layout) Imports(module string) LayoutImports {
+	return LayoutImports{
+		Domain:       importSpec(DomainPackage, module+"/"+l.Domain),
+		HTTPUtil:     importSpec(HttpUtilPackage, module+"/"+l.HTTPUtil),
+		Config:       importSpec(ConfigPackage, module+"/"+l.Config),
+		Logger:       importSpec(LoggerPackage, module+"/"+l.Logger),
+		Services:     module + "/" + l.Services,
+		HTTP:         module + "/" + l.HTTP,
+		Repositories: module + "/" + l.Repositories,
+	}
+}
+
+// importSpec returns the spec importing a package, with its name when the
+// last element of the path differs
+func importSpec(pkg, importPath string) string {
+	if path.Base(importPath) == pkg {
+		return strconv.Quote(importPath)
+	}
+	return pkg + " " + strconv.Quote(importPath)
+}
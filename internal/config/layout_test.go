@@ -0,0 +1,61 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLayoutConfig_Resolve(t *testing.T) {
+	layout, err := LayoutConfig{}.Resolve()
+	require.NoError(t, err)
+	assert.Equal(t, DefaultLayout(), layout)
+	assert.Equal(t, "internal/pkg/domain", layout.Domain)
+
+	// Directories replace those of the preset
+	layout, err = LayoutConfig{Preset: "hexagonal", Layout: Layout{Domain: "pkg/model"}}.Resolve()
+	require.NoError(t, err)
+	assert.Equal(t, "pkg/model", layout.Domain)
+	assert.Equal(t, "internal/core/services", layout.Services)
+
+	for _, preset := range LayoutPresets() {
+		_, err := LayoutConfig{Preset: preset}.Resolve()
+		assert.NoError(t, err, preset)
+	}
+
+	tests := []struct {
+		name   string
+		config LayoutConfig
+		errMsg string
+	}{
+		{"unknown_preset", LayoutConfig{Preset: "onion"}, `unknown layout preset "onion"`},
+		{"absolute", LayoutConfig{Layout: Layout{Domain: "/domain"}}, "relative path"},
+		{"outside", LayoutConfig{Layout: Layout{Services: "../services"}}, "inside the project"},
+		{"unclean", LayoutConfig{Layout: Layout{Services: "app//services"}}, "clean path"},
+		{"root", LayoutConfig{Layout: Layout{Domain: "."}}, "inside the project"},
+		{"cmd", LayoutConfig{Layout: Layout{Logger: "cmd/logger"}}, "cmd/"},
+		{"shared", LayoutConfig{Preset: "flat", Layout: Layout{HTTP: "service"}}, `services and http are both "service"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.config.Resolve()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+}
+
+func TestLayout_Imports(t *testing.T) {
+	imports := DefaultLayout().Imports("example.com/svc")
+	assert.Equal(t, `"example.com/svc/internal/pkg/domain"`, imports.Domain)
+	assert.Equal(t, "example.com/svc/internal/services", imports.Services)
+
+	// Directories not named after their package are imported with its name
+	layout, err := LayoutConfig{Layout: Layout{Domain: "pkg/model", Config: "platform/settings"}}.Resolve()
+	require.NoError(t, err)
+	imports = layout.Imports("example.com/svc")
+	assert.Equal(t, `domain "example.com/svc/pkg/model"`, imports.Domain)
+	assert.Equal(t, `config "example.com/svc/platform/settings"`, imports.Config)
+	assert.Equal(t, `"example.com/svc/internal/pkg/httputil"`, imports.HTTPUtil)
+}
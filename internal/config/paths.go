@@ -1,19 +1,8 @@
 package config
 
-// Directory structure constants for generated projects
+// Package, file and template names of generated projects; directories are
+// set by the Layout
 const (
-	// Default directories
-	InternalDir      = "internal"
-	PkgDir           = "internal/pkg"
-	DomainDir        = "internal/pkg/domain"
-	ServicesDir      = "internal/services"
-	AdaptersDir      = "internal/adapters"
-	HttpAdaptersDir  = "internal/adapters/http"
-	MongoAdaptersDir = "internal/adapters/repository"
-	HttpUtilDir      = "internal/pkg/httputil"
-	LoggerDir        = "internal/pkg/logger"
-	ConfigDir        = "internal/pkg/config"
-
 	// Default package names
 	DefaultAPIPackage     = "api"
 	DefaultHandlerPackage = "http"
@@ -27,6 +16,13 @@ const (
 	// File names
 	GoModFile          = "go.mod"
 	TypesFile          = "types.go"
+	OptionalFile       = "optional.go"
+	DateFile           = "date.go"
+	URIFile            = "uri.go"
+	DurationFile       = "duration.go"
+	DecodeFile         = "decode.go"
+	ValidationFile     = "validation.go"
+	OpenAPISpecFile    = "openapi.json"
 	ErrorsFile         = "errors.go"
 	RouterFile         = "router.go"
 	HttpUtilsFile      = "http_utils.go"
@@ -39,33 +35,24 @@ const (
 	ConfigTestFile     = "config_test.go"
 
 	// Template paths
-	DomainErrorsTemplate = "templates/domain/errors.go.tmpl"
-	DomainTypesTemplate  = "templates/domain/types.go.tmpl"
-	MainTemplate         = "templates/main.go.tmpl"
-	EnvTemplate          = "templates/env.tmpl"
-	LoggerTemplate       = "templates/pkg/logger.go.tmpl"
-	LoggerTestTemplate   = "templates/pkg/logger_test.go.tmpl"
-	ConfigTemplate       = "templates/pkg/config.go.tmpl"
-	ConfigTestTemplate   = "templates/pkg/config_test.go.tmpl"
+	DomainErrorsTemplate     = "templates/domain/errors.go.tmpl"
+	DomainTypesTemplate      = "templates/domain/types.go.tmpl"
+	DomainOptionalTemplate   = "templates/domain/optional.go.tmpl"
+	DomainDateTemplate       = "templates/domain/date.go.tmpl"
+	DomainURITemplate        = "templates/domain/uri.go.tmpl"
+	DomainDurationTemplate   = "templates/domain/duration.go.tmpl"
+	DomainValidationTemplate = "templates/domain/validation.go.tmpl"
+	ValidateTemplate         = "templates/validate.tmpl"
+	MainTemplate             = "templates/main.go.tmpl"
+	EnvTemplate              = "templates/env.tmpl"
+	LoggerTemplate           = "templates/pkg/logger.go.tmpl"
+	LoggerTestTemplate       = "templates/pkg/logger_test.go.tmpl"
+	ConfigTemplate           = "templates/pkg/config.go.tmpl"
+	ConfigTestTemplate       = "templates/pkg/config_test.go.tmpl"
 )
 
-// Import path helpers
-func GetDomainImportPath(baseImportPath string) string {
-	return baseImportPath + "/" + DomainDir
-}
-
-func GetServicesImportPath(baseImportPath, domain string) string {
-	return baseImportPath + "/" + ServicesDir + "/" + domain
-}
-
-func GetHttpUtilImportPath(baseImportPath string) string {
-	return baseImportPath + "/" + HttpUtilDir
-}
-
-func GetLoggerImportPath(baseImportPath string) string {
-	return baseImportPath + "/" + LoggerDir
-}
-
-func GetConfigImportPath(baseImportPath string) string {
-	return baseImportPath + "/" + ConfigDir
-}
+// Dependencies of generated code, kept in step with go.mod
+const (
+	KinOpenAPIModule  = "github.com/getkin/kin-openapi"
+	KinOpenAPIVersion = "v0.123.0"
+)
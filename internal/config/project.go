@@ -0,0 +1,119 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectFile is the project configuration file goapigen reads from the
+// project root
+const ProjectFile = "goapigen.yaml"
+
+// Project is the content of a goapigen.yaml file. It records how the code
+// of a project is generated, so that every regeneration uses the same specs
+// and options. Relative paths are relative to the directory of the file.
+type Project struct {
+	// Specs are merged in order, as repeated --spec flags
+	Specs []string `yaml:"specs,omitempty"`
+	// Output defaults to the directory of the file
+	Output      string `yaml:"output,omitempty"`
+	Package     string `yaml:"package,omitempty"`
+	HTTPPackage string `yaml:"httpPackage,omitempty"`
+
+	Generate Generators `yaml:"generate,omitempty"`
+
+	Optional          string `yaml:"optional,omitempty"`
+	SplitTypes        bool   `yaml:"splitTypes,omitempty"`
+	OpenAPIValidation bool   `yaml:"openapiValidation,omitempty"`
+
+	// Formats map string formats to Go types, as --format flags
+	Formats map[string]string `yaml:"formats,omitempty"`
+
+	// Layout places the generated packages
+	Layout LayoutConfig `yaml:"layout,omitempty"`
+
+	// Templates overlays the embedded templates, as --templates
+	Templates string `yaml:"templates,omitempty"`
+
+	// Schemas holds the overrides of single schemas by name
+	Schemas map[string]SchemaOverride `yaml:"schemas,omitempty"`
+
+	dir string
+}
+
+// Generators selects the generated components. Unset components keep the
+// default of the command line.
+type Generators struct {
+	Types    *bool `yaml:"types,omitempty"`
+	Services *bool `yaml:"services,omitempty"`
+	Mongo    *bool `yaml:"mongo,omitempty"`
+	HTTP     *bool `yaml:"http,omitempty"`
+}
+
+// SchemaOverride changes the code generated for a single schema
+type SchemaOverride struct {
+	// Skip generates the type only, without service, repository or wiring in
+	// main.go, as suits schemas such as Error that are not resources
+	Skip bool `yaml:"skip,omitempty"`
+	// Collection names the MongoDB collection of the repository
+	Collection string `yaml:"collection,omitempty"`
+}
+
+// LoadProject reads a project file. Unknown keys are rejected, so that a
+// misspelled option does not silently fall back to its default.
+func LoadProject(path string) (*Project, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	project := &Project{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(project); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	project.dir = filepath.Dir(absPath)
+	return project, nil
+}
+
+// Dir returns the directory relative paths of the project are resolved from
+func (p *Project) Dir() string {
+	return p.dir
+}
+
+// Path resolves a path of the project file against its directory
+func (p *Project) Path(path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(p.dir, path)
+}
+
+// Marshal encodes the project as YAML
+func (p *Project) Marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("# goapigen project configuration: goapigen generate reads it from this\n")
+	buf.WriteString("# directory, and command line flags override its values.\n")
+
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(p); err != nil {
+		return nil, fmt.Errorf("failed to encode project: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode project: %w", err)
+	}
+	return buf.Bytes(), nil
+}
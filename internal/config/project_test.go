@@ -0,0 +1,73 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadProject(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ProjectFile)
+	require.NoError(t, os.WriteFile(path, []byte(`
+specs: [api/openapi.yaml, https://example.com/orders.yaml]
+output: svc
+generate:
+  services: true
+  http: false
+optional: pointer
+formats:
+  decimal: github.com/shopspring/decimal.Decimal
+layout:
+  preset: flat
+  domain: pkg/model
+templates: templates
+schemas:
+  Error: {skip: true}
+  Person: {collection: people}
+`), 0644))
+
+	project, err := LoadProject(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"api/openapi.yaml", "https://example.com/orders.yaml"}, project.Specs)
+	assert.Equal(t, filepath.Join(dir, "svc"), project.Path(project.Output))
+	assert.Equal(t, dir, project.Dir())
+	assert.Nil(t, project.Generate.Types, "Unset generators should keep their default")
+	require.NotNil(t, project.Generate.HTTP)
+	assert.False(t, *project.Generate.HTTP)
+	assert.Equal(t, LayoutConfig{Preset: "flat", Layout: Layout{Domain: "pkg/model"}}, project.Layout)
+	assert.Equal(t, SchemaOverride{Skip: true}, project.Schemas["Error"])
+	assert.Equal(t, "people", project.Schemas["Person"].Collection)
+
+	// Marshal round-trips the options
+	data, err := project.Marshal()
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0644))
+	reloaded, err := LoadProject(path)
+	require.NoError(t, err)
+	assert.Equal(t, project, reloaded)
+}
+
+func TestLoadProject_Errors(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := LoadProject(filepath.Join(dir, "missing.yaml"))
+	assert.Error(t, err)
+
+	// Misspelled options are reported rather than ignored
+	path := filepath.Join(dir, ProjectFile)
+	require.NoError(t, os.WriteFile(path, []byte("generate:\n  servics: true\n"), 0644))
+	_, err = LoadProject(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "servics")
+
+	// An empty file is an empty project
+	require.NoError(t, os.WriteFile(path, nil, 0644))
+	project, err := LoadProject(path)
+	require.NoError(t, err)
+	assert.Empty(t, project.Specs)
+}
@@ -0,0 +1,119 @@
+package generator
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// Vendor extensions controlling the generated Go code. x-go-type and
+// x-go-type-import are handled with the format mappings in formats.go.
+const (
+	extGoName       = "x-go-name"
+	extGoJSONIgnore = "x-go-json-ignore"
+	extOmitEmpty    = "x-omitempty"
+	extBsonName     = "x-bson-name"
+)
+
+// jsonTagPattern matches the json key of a struct tag and its options
+var jsonTagPattern = regexp.MustCompile(`json:"([^",]*)((?:,[^"]*)?)"`)
+
+// stringExtension returns a non-empty string extension of a schema
+func stringExtension(schema *openapi3.Schema, name string) (string, bool) {
+	if schema == nil {
+		return "", false
+	}
+	value, ok := schema.Extensions[name].(string)
+	return value, ok && value != ""
+}
+
+// propertyExtension returns an extension set on an inline property schema.
+// The extensions of a referenced schema describe the referenced type rather
+// than the property, so they are ignored.
+func propertyExtension(prop *openapi3.SchemaRef, name string) (interface{}, bool) {
+	if prop == nil || prop.Ref != "" || prop.Value == nil {
+		return nil, false
+	}
+	value, ok := prop.Value.Extensions[name]
+	return value, ok
+}
+
+// GoTypeName returns the Go type name of a component schema, which is the
+// schema name unless overridden with x-go-name
+func GoTypeName(schemaName string, schema *openapi3.Schema) string {
+	if name, ok := stringExtension(schema, extGoName); ok {
+		return name
+	}
+	return schemaName
+}
+
+// refTypeName returns the Go type name of the schema referenced by ref
+func refTypeName(ref *openapi3.SchemaRef) string {
+	return GoTypeName(SchemaRefName(ref.Ref), ref.Value)
+}
+
+// propertyGoName returns the field name set on a property with x-go-name
+func propertyGoName(prop *openapi3.SchemaRef) (string, bool) {
+	name, ok := propertyExtension(prop, extGoName)
+	if !ok {
+		return "", false
+	}
+	goName, ok := name.(string)
+	return goName, ok && goName != ""
+}
+
+// GoFieldName returns the Go field name of a property, honoring x-go-name
+func GoFieldName(propName string, prop *openapi3.SchemaRef) string {
+	if name, ok := propertyGoName(prop); ok {
+		return name
+	}
+	return ToGoFieldName(propName)
+}
+
+// BsonFieldName returns the BSON field name of a property, which is the
+// property name unless overridden with x-bson-name
+func BsonFieldName(propName string, prop *openapi3.SchemaRef) string {
+	if name, ok := propertyExtension(prop, extBsonName); ok {
+		if bsonName, ok := name.(string); ok && bsonName != "" {
+			return bsonName
+		}
+	}
+	return propName
+}
+
+// IsJSONIgnored reports whether a property is left out of JSON payloads
+// with x-go-json-ignore
+func IsJSONIgnored(prop *openapi3.SchemaRef) bool {
+	ignored, _ := propertyExtension(prop, extGoJSONIgnore)
+	return ignored == true
+}
+
+// applyTagExtensions adjusts the struct tags of a property field for the
+// x-bson-name, x-go-json-ignore and x-omitempty extensions
+func applyTagExtensions(propName string, prop *openapi3.SchemaRef, tags string) string {
+	if bsonName := BsonFieldName(propName, prop); bsonName != propName {
+		tags = strings.Replace(tags, `bson:"`+propName, `bson:"`+bsonName, 1)
+	}
+
+	if IsJSONIgnored(prop) {
+		return jsonTagPattern.ReplaceAllString(tags, `json:"-"`)
+	}
+
+	omitEmpty, ok := propertyExtension(prop, extOmitEmpty)
+	if !ok {
+		return tags
+	}
+	return jsonTagPattern.ReplaceAllStringFunc(tags, func(tag string) string {
+		match := jsonTagPattern.FindStringSubmatch(tag)
+		key, options := match[1], match[2]
+		omitted := strings.Contains(options, ",omitempty") || strings.Contains(options, ",omitzero")
+		switch {
+		case omitEmpty == true && !omitted:
+			options = ",omitempty" + options
+		case omitEmpty == false:
+			options = strings.NewReplacer(",omitempty", "", ",omitzero", "").Replace(options)
+		}
+		return `json:"` + key + options + `"`
+	})
+}
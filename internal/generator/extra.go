@@ -0,0 +1,61 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"text/template"
+)
+
+// parseExtraTemplates parses templates added to those of a generator into a
+// copy of its template set, so that they can use its functions and shared
+// templates, and returns the set along with their names. The set of the
+// generator may be shared with other generators, so it is left alone.
+func parseExtraTemplates(tmpl *template.Template, templateFS fs.FS, paths []string) (*template.Template, []string, error) {
+	if len(paths) == 0 {
+		return tmpl, nil, nil
+	}
+	extended, err := tmpl.Clone()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to copy templates: %w", err)
+	}
+	if _, err := extended.ParseFS(templateFS, paths...); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse templates: %w", err)
+	}
+
+	names := make([]string, 0, len(paths))
+	for _, p := range paths {
+		names = append(names, path.Base(p))
+	}
+	return extended, names, nil
+}
+
+// renderExtraTemplates renders added templates with the data of a schema or
+// an operation, the subject, reusing the code of the cache rendered from
+// the same data. Files are named after the prefix and the template, e.g.
+// pet_events.go for events.go.tmpl and the prefix pet, and Go files are
+// formatted with the imports of the generated module, importPath, last.
+func renderExtraTemplates(cache *RenderCache, importPath string, tmpl *template.Template, names []string, prefix, subject string, data interface{}) (map[string]string, error) {
+	files := make(map[string]string, len(names))
+	for _, name := range names {
+		filename := prefix + "_" + strings.TrimSuffix(name, ".tmpl")
+		// Sets of different generators may add templates of the same name
+		code, err := cache.render(tmpl.Name()+"/"+name, subject, data, func() (string, error) {
+			var buf bytes.Buffer
+			if err := tmpl.ExecuteTemplate(&buf, name, data); err != nil {
+				return "", fmt.Errorf("failed to render template %s: %w", name, err)
+			}
+			if !strings.HasSuffix(filename, ".go") {
+				return buf.String(), nil
+			}
+			return formatGo(buf.Bytes(), importPath, name, subject)
+		})
+		if err != nil {
+			return nil, err
+		}
+		files[filename] = code
+	}
+	return files, nil
+}
@@ -0,0 +1,339 @@
+package generator
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/zeek-r/goapigen/internal/config"
+	"github.com/zeek-r/goapigen/internal/parser"
+)
+
+// FormatMapping maps an OpenAPI string format to a Go type
+type FormatMapping struct {
+	Type   string // Go type expression, e.g. "uuid.UUID"
+	Import string // Import path providing Type, empty for builtin and generated types
+	Helper string // Template generating Type into the domain package, if any
+}
+
+// FormatRegistry maps string formats to Go types and records the import
+// paths of the package qualifiers used by mapped types. Each generator owns
+// one, so mappings registered for one run do not leak into another.
+type FormatRegistry struct {
+	formats map[string]FormatMapping
+	imports map[string]string // package qualifier -> import path
+}
+
+// NewFormatRegistry creates a registry holding the default format mappings
+func NewFormatRegistry() *FormatRegistry {
+	return &FormatRegistry{
+		formats: map[string]FormatMapping{
+			"date-time": {Type: "time.Time", Import: "time"},
+			"date":      {Type: "Date", Helper: config.DomainDateTemplate},
+			"uuid":      {Type: "uuid.UUID", Import: "github.com/google/uuid"},
+			"byte":      {Type: "[]byte"}, // encoding/json encodes []byte as base64
+			"binary":    {Type: "[]byte"},
+			"decimal":   {Type: "decimal.Decimal", Import: "github.com/shopspring/decimal"},
+			"ipv4":      {Type: "net.IP", Import: "net"},
+			"ipv6":      {Type: "net.IP", Import: "net"},
+			// *url.URL has no JSON encoding and ISO 8601 durations ("P3DT4H")
+			// do not fit time.Duration, so both get a generated type
+			"uri":      {Type: "URI", Helper: config.DomainURITemplate},
+			"duration": {Type: "Duration", Helper: config.DomainDurationTemplate},
+		},
+		imports: map[string]string{
+			"time":    "time",
+			"uuid":    "github.com/google/uuid",
+			"decimal": "github.com/shopspring/decimal",
+			"net":     "net",
+		},
+	}
+}
+
+// specFormatRegistry creates a registry with the default format mappings and
+// the packages imported by the x-go-type extensions of a spec
+func specFormatRegistry(apiParser *parser.OpenAPIParser) *FormatRegistry {
+	formats := NewFormatRegistry()
+	if apiParser != nil {
+		formats.RegisterSpecImports(apiParser.Doc)
+	}
+	return formats
+}
+
+// qualifiedIdentPattern matches package-qualified identifiers such as uuid.UUID
+var qualifiedIdentPattern = regexp.MustCompile(`\b([A-Za-z_][A-Za-z0-9_]*)\.[A-Za-z_][A-Za-z0-9_]*`)
+
+// identPattern matches Go identifiers, including qualified ones
+var identPattern = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?`)
+
+// majorVersionPattern matches major version suffixes of import paths
+var majorVersionPattern = regexp.MustCompile(`^v[0-9]+$|\.v[0-9]+$`)
+
+// Register maps a string format to a Go type. goType is either a builtin
+// type or a type qualified with its full import path, e.g.
+// "github.com/shopspring/decimal.Decimal" or "[]encoding/json.RawMessage".
+func (r *FormatRegistry) Register(format, goType string) error {
+	if format == "" || goType == "" {
+		return fmt.Errorf("invalid format mapping %q=%q", format, goType)
+	}
+
+	typeExpr, importPath := ParseGoTypeReference(goType)
+	r.formats[format] = FormatMapping{Type: typeExpr, Import: importPath}
+	if importPath != "" {
+		r.imports[packageQualifier(importPath)] = importPath
+	}
+	return nil
+}
+
+// RegisterMappings registers format mappings written as "format=goType",
+// as accepted on the command line
+func (r *FormatRegistry) RegisterMappings(mappings []string) error {
+	for _, mapping := range mappings {
+		format, goType, ok := strings.Cut(mapping, "=")
+		if !ok {
+			return fmt.Errorf("invalid format mapping %q, expected format=type", mapping)
+		}
+		if err := r.Register(strings.TrimSpace(format), strings.TrimSpace(goType)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RegisterSpecImports records the packages of the types set with x-go-type
+// anywhere in a spec, so that the generated code imports them
+func (r *FormatRegistry) RegisterSpecImports(doc *openapi3.T) {
+	visitSchemas(doc, func(schema *openapi3.Schema) {
+		if qualifier, importPath, ok := goTypeExtensionImport(schema); ok {
+			r.imports[qualifier] = importPath
+		}
+	})
+}
+
+// ParseGoTypeReference splits a type qualified with its full import path
+// into a Go type expression and the import path, e.g.
+// "[]github.com/google/uuid.UUID" -> "[]uuid.UUID", "github.com/google/uuid".
+// Builtin types are returned unchanged with an empty import path.
+func ParseGoTypeReference(ref string) (string, string) {
+	// Keep slice, map and pointer prefixes out of the import path
+	typeStart := strings.LastIndexAny(ref, "]*") + 1
+	prefix, qualified := ref[:typeStart], ref[typeStart:]
+
+	dot := strings.LastIndex(qualified, ".")
+	if dot <= strings.LastIndex(qualified, "/") {
+		return ref, ""
+	}
+
+	importPath, name := qualified[:dot], qualified[dot+1:]
+	return prefix + packageQualifier(importPath) + "." + name, importPath
+}
+
+// packageQualifier returns the name a package is referred to by, assuming
+// it matches the last import path element without a major version suffix
+func packageQualifier(importPath string) string {
+	elements := strings.Split(importPath, "/")
+	name := elements[len(elements)-1]
+	if len(elements) > 1 && strings.HasPrefix(name, "v") && majorVersionPattern.MatchString(name) {
+		name = elements[len(elements)-2]
+	}
+	name = majorVersionPattern.ReplaceAllString(name, "")
+	return strings.NewReplacer("-", "_", ".", "_").Replace(name)
+}
+
+// visitSchemas calls visit once for every schema reachable from the
+// components and operations of doc
+func visitSchemas(doc *openapi3.T, visit func(*openapi3.Schema)) {
+	if doc == nil {
+		return
+	}
+
+	seen := make(map[*openapi3.Schema]bool)
+	var walk func(ref *openapi3.SchemaRef)
+	walk = func(ref *openapi3.SchemaRef) {
+		if ref == nil || ref.Value == nil || seen[ref.Value] {
+			return
+		}
+		schema := ref.Value
+		seen[schema] = true
+		visit(schema)
+
+		for _, prop := range schema.Properties {
+			walk(prop)
+		}
+		for _, members := range []openapi3.SchemaRefs{schema.AllOf, schema.OneOf, schema.AnyOf} {
+			for _, member := range members {
+				walk(member)
+			}
+		}
+		walk(schema.Items)
+		walk(schema.AdditionalProperties.Schema)
+		walk(schema.Not)
+	}
+	walkContent := func(content openapi3.Content) {
+		for _, mediaType := range content {
+			if mediaType != nil {
+				walk(mediaType.Schema)
+			}
+		}
+	}
+	walkParameters := func(parameters openapi3.Parameters) {
+		for _, param := range parameters {
+			if param != nil && param.Value != nil {
+				walk(param.Value.Schema)
+				walkContent(param.Value.Content)
+			}
+		}
+	}
+
+	if doc.Components != nil {
+		for _, ref := range doc.Components.Schemas {
+			walk(ref)
+		}
+	}
+	if doc.Paths == nil {
+		return
+	}
+	for _, pathItem := range doc.Paths.Map() {
+		walkParameters(pathItem.Parameters)
+		for _, operation := range pathItem.Operations() {
+			walkParameters(operation.Parameters)
+			if operation.RequestBody != nil && operation.RequestBody.Value != nil {
+				walkContent(operation.RequestBody.Value.Content)
+			}
+			if operation.Responses == nil {
+				continue
+			}
+			for _, response := range operation.Responses.Map() {
+				if response != nil && response.Value != nil {
+					walkContent(response.Value.Content)
+				}
+			}
+		}
+	}
+}
+
+// lookupFormat returns the mapping registered for a string format
+func (r *FormatRegistry) lookupFormat(format string) (FormatMapping, bool) {
+	mapping, ok := r.formats[format]
+	return mapping, ok
+}
+
+// goTypeExtension returns the Go type set on a schema with the x-go-type
+// extension
+func goTypeExtension(schema *openapi3.Schema) (string, bool) {
+	goType, ok := schema.Extensions["x-go-type"].(string)
+	if !ok || goType == "" {
+		return "", false
+	}
+
+	// Types without an import path rely on x-go-type-import or known packages
+	if strings.Contains(goType, "/") {
+		typeExpr, _ := ParseGoTypeReference(goType)
+		return typeExpr, true
+	}
+	return goType, true
+}
+
+// goTypeExtensionImport returns the package qualifier and import path of
+// the type set with x-go-type, as named by x-go-type-import or by the
+// import path the type is qualified with
+func goTypeExtensionImport(schema *openapi3.Schema) (string, string, bool) {
+	goType, ok := schema.Extensions["x-go-type"].(string)
+	if !ok || goType == "" {
+		return "", "", false
+	}
+
+	importPath := ""
+	if strings.Contains(goType, "/") {
+		_, importPath = ParseGoTypeReference(goType)
+	}
+
+	qualifier := ""
+	switch imp := schema.Extensions["x-go-type-import"].(type) {
+	case string:
+		importPath = imp
+	case map[string]interface{}:
+		importPath, _ = imp["path"].(string)
+		qualifier, _ = imp["name"].(string)
+	}
+
+	if importPath == "" {
+		return "", "", false
+	}
+	if qualifier == "" {
+		qualifier = packageQualifier(importPath)
+	}
+	return qualifier, importPath, true
+}
+
+// localFormatType returns the generated domain type a string schema maps
+// to, such as Date, if any
+func (r *FormatRegistry) localFormatType(schema *openapi3.Schema) (string, bool) {
+	if schema.Type != "string" {
+		return "", false
+	}
+	if _, ok := goTypeExtension(schema); ok {
+		return "", false
+	}
+
+	mapping, ok := r.lookupFormat(schema.Format)
+	if !ok || mapping.Helper == "" {
+		return "", false
+	}
+	return mapping.Type, true
+}
+
+// TypeImports returns the import specs of the packages referenced by a Go
+// type expression, e.g. "map[string]uuid.UUID" -> `"github.com/google/uuid"`.
+// Qualifiers of unknown packages (such as the generated domain package) are
+// ignored.
+func (r *FormatRegistry) TypeImports(goType string) []string {
+	var imports []string
+	for _, match := range qualifiedIdentPattern.FindAllStringSubmatch(goType, -1) {
+		if importPath, ok := r.imports[match[1]]; ok {
+			imports = appendUnique(imports, importSpec(match[1], importPath))
+		}
+	}
+	sort.Strings(imports)
+	return imports
+}
+
+// importSpec formats an import spec, naming the package when the qualifier
+// differs from the import path's last element
+func importSpec(qualifier, importPath string) string {
+	if qualifier != path.Base(importPath) {
+		return fmt.Sprintf("%s %q", qualifier, importPath)
+	}
+	return fmt.Sprintf("%q", importPath)
+}
+
+// usesIdentifier reports whether a Go type expression references name
+func usesIdentifier(goType, name string) bool {
+	for _, ident := range identPattern.FindAllString(goType, -1) {
+		if ident == name {
+			return true
+		}
+	}
+	return false
+}
+
+// helperTemplates returns the templates of the generated domain types
+// referenced by goTypes, keyed by type name
+func (r *FormatRegistry) helperTemplates(goTypes []string) map[string]string {
+	helpers := make(map[string]string)
+	for _, mapping := range r.formats {
+		if mapping.Helper == "" {
+			continue
+		}
+		for _, goType := range goTypes {
+			if usesIdentifier(goType, mapping.Type) {
+				helpers[mapping.Type] = mapping.Helper
+				break
+			}
+		}
+	}
+	return helpers
+}
@@ -0,0 +1,108 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+func TestParseGoTypeReference(t *testing.T) {
+	tests := []struct {
+		ref        string
+		typeExpr   string
+		importPath string
+	}{
+		{"string", "string", ""},
+		{"github.com/shopspring/decimal.Decimal", "decimal.Decimal", "github.com/shopspring/decimal"},
+		{"[]github.com/google/uuid.UUID", "[]uuid.UUID", "github.com/google/uuid"},
+		{"github.com/gofrs/uuid/v5.UUID", "uuid.UUID", "github.com/gofrs/uuid/v5"},
+		{"encoding/json.RawMessage", "json.RawMessage", "encoding/json"},
+	}
+
+	for _, tt := range tests {
+		typeExpr, importPath := ParseGoTypeReference(tt.ref)
+		if typeExpr != tt.typeExpr || importPath != tt.importPath {
+			t.Errorf("ParseGoTypeReference(%q) = %q, %q, want %q, %q", tt.ref, typeExpr, importPath, tt.typeExpr, tt.importPath)
+		}
+	}
+}
+
+func TestTypeImports(t *testing.T) {
+	formats := NewFormatRegistry()
+	imports := formats.TypeImports("map[string][]uuid.UUID")
+	if len(imports) != 1 || imports[0] != `"github.com/google/uuid"` {
+		t.Errorf("Unexpected imports: %v", imports)
+	}
+	if imports := formats.TypeImports("domain.Pet"); len(imports) != 0 {
+		t.Errorf("Expected no imports for unknown qualifier, got %v", imports)
+	}
+	if err := formats.RegisterMappings([]string{"invalid"}); err == nil {
+		t.Error("Expected error for mapping without '='")
+	}
+}
+
+func TestFormatRegistryIsolation(t *testing.T) {
+	custom := NewFormatRegistry()
+	if err := custom.RegisterMappings([]string{"decimal=github.com/cockroachdb/apd/v3.Decimal"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ref := &openapi3.SchemaRef{Value: &openapi3.Schema{Type: "string", Format: "decimal"}}
+	if goType, _ := custom.ResolveGoType(ref, "", ""); goType != "apd.Decimal" {
+		t.Errorf("Expected custom mapping, got %q", goType)
+	}
+
+	// Mappings registered on one registry do not leak into others
+	if goType, _ := NewFormatRegistry().ResolveGoType(ref, "", ""); goType != "decimal.Decimal" {
+		t.Errorf("Expected default mapping, got %q", goType)
+	}
+}
+
+func TestRegisterSpecImports(t *testing.T) {
+	money := &openapi3.Schema{
+		Type: "string",
+		Extensions: map[string]interface{}{
+			"x-go-type":        "money.Amount",
+			"x-go-type-import": map[string]interface{}{"path": "example.com/money/v2", "name": "money"},
+		},
+	}
+	doc := &openapi3.T{Components: &openapi3.Components{Schemas: openapi3.Schemas{
+		"Order": {Value: &openapi3.Schema{
+			Type:       "object",
+			Properties: openapi3.Schemas{"total": {Value: money}},
+		}},
+	}}}
+
+	formats := NewFormatRegistry()
+	ref := &openapi3.SchemaRef{Value: money}
+
+	// Resolving the type has no side effect on the registry
+	if goType, _ := formats.ResolveGoType(ref, "", ""); goType != "money.Amount" {
+		t.Errorf("Expected x-go-type, got %q", goType)
+	}
+	if imports := formats.TypeImports("money.Amount"); len(imports) != 0 {
+		t.Errorf("Expected no imports before registering the spec, got %v", imports)
+	}
+
+	formats.RegisterSpecImports(doc)
+	imports := formats.TypeImports("money.Amount")
+	if len(imports) != 1 || imports[0] != `money "example.com/money/v2"` {
+		t.Errorf("Unexpected imports: %v", imports)
+	}
+}
+
+func TestDefaultFormatHelpers(t *testing.T) {
+	formats := NewFormatRegistry()
+
+	for format, want := range map[string]string{"date": "Date", "uri": "URI", "duration": "Duration"} {
+		schema := &openapi3.Schema{Type: "string", Format: format}
+		if goType, ok := formats.localFormatType(schema); !ok || goType != want {
+			t.Errorf("Format %s: expected generated type %s, got %q", format, want, goType)
+		}
+	}
+
+	helpers := formats.helperTemplates([]string{"[]URI", "map[string]Duration"})
+	if len(helpers) != 2 || helpers["URI"] == "" || helpers["Duration"] == "" {
+		t.Errorf("Expected URI and Duration helpers, got %v", helpers)
+	}
+}
@@ -2,14 +2,17 @@ package generator
 
 import (
 	"bytes"
-	"embed"
+	"encoding/json"
 	"fmt"
+	"io/fs"
 	"sort"
 	"strings"
 	"text/template"
 
 	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/zeek-r/goapigen/internal/config"
 	"github.com/zeek-r/goapigen/internal/parser"
+	"github.com/zeek-r/goapigen/internal/workpool"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
 )
@@ -30,18 +33,23 @@ type QueryParam struct {
 
 // RequestField represents a field in a request body
 type RequestField struct {
-	Name    string
-	Type    string
-	JsonTag string
+	Name       string
+	Type       string
+	JsonTag    string
+	EnumValues []string // Allowed values when Type is a generated enum type
+	Provided   string   // Condition reporting whether a wrapped optional field was sent
 }
 
 // OperationData contains data for an operation handler
 type OperationData struct {
 	SchemaName       string
+	TypeName         string // Go name of the schema's domain type
 	OperationID      string
 	ServiceInterface string
 	Method           string
 	Path             string
+	CrudType         string // CRUD type of the operation, see parser.CrudType
+	IDParam          string // Last parameter of the path, identifying the resource
 	HasPathParams    bool
 	PathParams       []PathParam
 	HasQueryParams   bool
@@ -50,6 +58,7 @@ type OperationData struct {
 	RequestType      string
 	RequestTypeName  string
 	RequestFields    []RequestField
+	Validations      []FieldValidation // Checks of the request type's Validate method
 	HasResponseBody  bool
 	ResponseType     string
 	SuccessStatus    int
@@ -57,9 +66,13 @@ type OperationData struct {
 	PackageName      string
 	ModelImportPath  string
 	ImportPath       string
+	Packages         config.LayoutImports
 	VarName          string
 	ImportTime       bool
-	Domain           string // Domain/resource this operation belongs to
+	Imports          []string          // Import specs needed by request fields, besides time
+	TestImports      []string          // Import specs needed by test values
+	Domain           string            // Domain/resource this operation belongs to
+	CrudOps          map[string]string // CRUD operations of the resource, the methods of its service
 }
 
 // ResourceData represents a resource group in the API
@@ -69,6 +82,7 @@ type ResourceData struct {
 	Operations     []OperationData
 	Domain         string
 	ImportPath     string
+	Packages       config.LayoutImports
 	HandlerPackage string
 }
 
@@ -86,7 +100,15 @@ type HTTPGenerator struct {
 	handlerPackage  string
 	importPath      string
 	modelImportPath string
+	layout          config.Layout
+	templateFS      fs.FS
 	templates       *template.Template
+	extras          []string
+	optional        OptionalStrategy
+	formats         *FormatRegistry
+	validateOpenAPI bool
+	workers         int
+	cache           *RenderCache
 }
 
 // NewHTTPGenerator creates a new generator for HTTP handlers
@@ -96,18 +118,17 @@ func NewHTTPGenerator(
 	handlerPackage string,
 	importPath string,
 	modelImportPath string,
-	templateFS embed.FS,
+	templateFS fs.FS,
 ) (*HTTPGenerator, error) {
 	// Create templates with function map
-	tmpl := template.New("")
-	tmpl.Funcs(template.FuncMap{
+	funcs := template.FuncMap{
 		"contains": func(s, substr string) bool { return strings.Contains(s, substr) },
 		"title":    func(s string) string { return cases.Title(language.English).String(s) },
 		"lower":    func(s string) string { return strings.ToLower(s) },
-	})
+	}
 
 	// Parse templates
-	tmpl, err := tmpl.ParseFS(templateFS,
+	tmpl, err := parseTemplates(templateFS, "http", funcs,
 		"templates/http/operation_handler.go.tmpl",
 		"templates/http/operation_handler_test.go.tmpl",
 		"templates/http/handler_wrapper.go.tmpl",
@@ -115,6 +136,8 @@ func NewHTTPGenerator(
 		"templates/http/router.go.tmpl",
 		"templates/http/mocks.go.tmpl",
 		"templates/http/schema_handler.go.tmpl",
+		"templates/http/openapi_validator.go.tmpl",
+		config.ValidateTemplate,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse handler templates: %w", err)
@@ -126,19 +149,67 @@ func NewHTTPGenerator(
 		handlerPackage:  handlerPackage,
 		importPath:      importPath,
 		modelImportPath: modelImportPath,
+		layout:          config.DefaultLayout(),
+		templateFS:      templateFS,
 		templates:       tmpl,
+		optional:        OptionalValue,
+		formats:         specFormatRegistry(parser),
 	}, nil
 }
 
+// SetLayout sets the layout the generated imports follow
+func (g *HTTPGenerator) SetLayout(layout config.Layout) {
+	g.layout = layout
+}
+
+// SetOptionalStrategy sets how optional and nullable request fields are
+// generated; it must match the strategy used for the domain types
+func (g *HTTPGenerator) SetOptionalStrategy(strategy OptionalStrategy) {
+	g.optional = strategy
+}
+
+// SetFormatRegistry sets the format mappings used to resolve Go types;
+// it must match the mappings used for the domain types
+func (g *HTTPGenerator) SetFormatRegistry(formats *FormatRegistry) {
+	g.formats = formats
+}
+
+// SetOpenAPIValidation sets whether the middleware validating HTTP traffic
+// against the embedded spec is generated
+func (g *HTTPGenerator) SetOpenAPIValidation(enabled bool) {
+	g.validateOpenAPI = enabled
+}
+
+// SetWorkers sets how many operations are generated concurrently, one per
+// CPU by default
+func (g *HTTPGenerator) SetWorkers(workers int) {
+	g.workers = workers
+}
+
+// SetRenderCache sets the cache of the code rendered by an earlier
+// generation, so that only the operations and resources whose data changed
+// are rendered
+func (g *HTTPGenerator) SetRenderCache(cache *RenderCache) {
+	g.cache = cache
+}
+
+// AddTemplates parses additional templates, rendered for every operation by
+// GenerateHandlers with the data of the operation handler template
+func (g *HTTPGenerator) AddTemplates(paths ...string) error {
+	tmpl, names, err := parseExtraTemplates(g.templates, g.templateFS, paths)
+	if err != nil {
+		return err
+	}
+	g.templates = tmpl
+	g.extras = append(g.extras, names...)
+	return nil
+}
+
 // GenerateHandlers generates all HTTP handlers for the API
 func (g *HTTPGenerator) GenerateHandlers() (map[string]string, error) {
 	operations := g.parser.GetOperations()
 	result := make(map[string]string)
 
-	// Group operations by tag (resource)
-	resourceMap := make(map[string][]OperationData)
-	var allOperations []OperationData
-
 	// Create directory for httputil package
 	// Generate common HTTP utilities in internal/pkg/httputil package
 	httpUtils, err := g.generateHTTPUtils()
@@ -154,125 +225,173 @@ func (g *HTTPGenerator) GenerateHandlers() (map[string]string, error) {
 	}
 	result["httputil/handler_wrapper.go"] = handlerWrapper
 
-	// Track domains we've seen to generate mocks only once per domain
-	generatedMocks := make(map[string]bool)
-
-	// Generate handler file for each operation
-	for opID, operation := range operations {
-		if opID == "" {
-			continue // Skip operations without ID
+	// Generate the OpenAPI validation middleware along with the spec it embeds
+	if g.validateOpenAPI {
+		validator, err := g.generateOpenAPIValidator()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate OpenAPI validator: %w", err)
 		}
+		result["httputil/openapi_validator.go"] = validator
 
-		data, err := g.prepareOperationData(opID, operation)
+		spec, err := json.MarshalIndent(g.parser.Doc, "", "  ")
 		if err != nil {
-			return nil, fmt.Errorf("failed to prepare data for operation %s: %w", opID, err)
+			return nil, fmt.Errorf("failed to encode OpenAPI spec: %w", err)
 		}
+		result["httputil/"+config.OpenAPISpecFile] = string(spec) + "\n"
+	}
 
-		// Group by first tag (primary resource)
-		if len(operation.Tags) > 0 {
-			tag := operation.Tags[0]
-			if _, exists := resourceMap[tag]; !exists {
-				resourceMap[tag] = make([]OperationData, 0)
-			}
-			resourceMap[tag] = append(resourceMap[tag], data)
-
-			// Store domain with data for later use
-			data.Domain = strings.ToLower(tag)
-
-			// Generate mocks for each domain if not already generated
-			if !generatedMocks[data.Domain] {
-				// Generate mocks
-				mockCode, err := g.generateMocks(data)
-				if err != nil {
-					return nil, fmt.Errorf("failed to generate mocks for domain %s: %w", data.Domain, err)
-				}
-
-				// Create mocks directory path
-				mockFilename := "domain/" + data.Domain + "/mocks/mock_service.go"
-				result[mockFilename] = mockCode
-
-				// Mark domain as having mocks generated
-				generatedMocks[data.Domain] = true
-			}
+	// Operations are generated concurrently and collected in operation ID
+	// order, so that the output follows neither scheduling nor map order
+	opIDs := make([]string, 0, len(operations))
+	for opID := range operations {
+		if opID != "" { // Skip operations without ID
+			opIDs = append(opIDs, opID)
 		}
-		allOperations = append(allOperations, data)
+	}
+	sort.Strings(opIDs)
 
-		// Generate handler file
-		code, err := g.generateOperationHandler(data)
-		if err != nil {
-			return nil, fmt.Errorf("failed to generate handler for operation %s: %w", opID, err)
-		}
+	handlers := make([]operationFiles, len(opIDs))
+	err = workpool.Run(g.workers, len(opIDs), func(i int) error {
+		var err error
+		handlers[i], err = g.generateOperation(opIDs[i], operations[opIDs[i]])
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
 
-		// Generate handler tests
-		testCode, err := g.generateOperationHandlerTests(data)
-		if err != nil {
-			return nil, fmt.Errorf("failed to generate handler tests for operation %s: %w", opID, err)
+	// Group operations by first tag (primary resource), generating the mocks
+	// of each domain once
+	resourceMap := make(map[string][]OperationData)
+	generatedMocks := make(map[string]bool)
+	for _, handler := range handlers {
+		for filename, code := range handler.files {
+			result[filename] = code
 		}
+		resourceMap[handler.tag] = append(resourceMap[handler.tag], handler.data)
 
-		// Put handlers in their domain subdirectory
-		filename := strings.ToLower(opID) + "_handler.go"
-		testFilename := strings.ToLower(opID) + "_handler_test.go"
-		if data.Domain != "" {
-			filename = "domain/" + data.Domain + "/" + filename
-			testFilename = "domain/" + data.Domain + "/" + testFilename
+		domain := handler.data.Domain
+		if !generatedMocks[domain] {
+			mockCode, err := g.generateMocks(handler.data)
+			if err != nil {
+				return nil, fmt.Errorf("failed to generate mocks for domain %s: %w", domain, err)
+			}
+			result["domain/"+domain+"/mocks/mock_service.go"] = mockCode
+			generatedMocks[domain] = true
 		}
-		result[filename] = code
-		result[testFilename] = testCode
 	}
 
-	// Generate router
-	resources := make([]ResourceData, 0, len(resourceMap))
-	for name, ops := range resourceMap {
-		// Determine base path for the resource
-		basePath := "/" + strings.ToLower(name)
-
+	// Generate the handler registering the operations of each resource
+	tags := make([]string, 0, len(resourceMap))
+	for tag := range resourceMap {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	for _, name := range tags {
 		resourceData := ResourceData{
 			Name:           ToPascalCase(name),
-			BasePath:       basePath,
-			Operations:     ops,
+			BasePath:       "/" + strings.ToLower(name),
+			Operations:     resourceMap[name],
 			Domain:         strings.ToLower(name),
 			ImportPath:     g.importPath,
+			Packages:       g.layout.Imports(g.importPath),
 			HandlerPackage: g.handlerPackage,
 		}
-		resources = append(resources, resourceData)
 
-		// Generate schema handler
 		schemaHandlerCode, err := g.generateSchemaHandler(resourceData)
 		if err != nil {
 			return nil, fmt.Errorf("failed to generate schema handler for resource %s: %w", name, err)
 		}
+		result["domain/"+resourceData.Domain+"/handler.go"] = schemaHandlerCode
+	}
+
+	return result, nil
+}
+
+// lastPathParam returns the name of the last parameter of a path, e.g. petId
+// for /owners/{ownerId}/pets/{petId}, or "" for a path without parameters
+func lastPathParam(path string) string {
+	start := strings.LastIndex(path, "{")
+	end := strings.LastIndex(path, "}")
+	if start < 0 || end < start {
+		return ""
+	}
+	return path[start+1 : end]
+}
+
+// contentSchema returns the schema of the application/json content, or of
+// the first content type with a schema
+func contentSchema(content openapi3.Content) *openapi3.SchemaRef {
+	if mediaType := content["application/json"]; mediaType != nil && mediaType.Schema != nil && mediaType.Schema.Value != nil {
+		return mediaType.Schema
+	}
+	for _, ct := range sortedKeys(content) {
+		if mediaType := content[ct]; mediaType.Schema != nil && mediaType.Schema.Value != nil {
+			return mediaType.Schema
+		}
+	}
+	return nil
+}
 
-		// Normalize domain name to lowercase
-		domain := strings.ToLower(name)
+// operationFiles holds the files generated for an operation
+type operationFiles struct {
+	data  OperationData
+	tag   string // First tag, naming the resource of the operation
+	files map[string]string
+}
 
-		// Set schema handler file path
-		schemaHandlerFilename := "domain/" + domain + "/handler.go"
-		result[schemaHandlerFilename] = schemaHandlerCode
+// generateOperation generates the handler of an operation, its tests and
+// the added templates in the subdirectory of its domain. The first tag of
+// the operation names its domain: the handlers of untagged operations would
+// have no service to call, so they are an error.
+func (g *HTTPGenerator) generateOperation(opID string, operation *openapi3.Operation) (operationFiles, error) {
+	if len(operation.Tags) == 0 {
+		return operationFiles{}, fmt.Errorf("operation %s has no tags: tag it with the resource it belongs to, which names the service its handler calls", opID)
+	}
+	data, err := g.prepareOperationData(opID, operation)
+	if err != nil {
+		return operationFiles{}, fmt.Errorf("failed to prepare data for operation %s: %w", opID, err)
 	}
 
-	// Sort resources for consistent output
-	sort.Slice(resources, func(i, j int) bool {
-		return resources[i].Name < resources[j].Name
-	})
+	op := operationFiles{files: make(map[string]string), tag: operation.Tags[0]}
+	data.Domain = strings.ToLower(op.tag)
+	data.CrudOps = g.parser.GetCrudOperationsForSchema(op.tag)
+	dir := "domain/" + data.Domain + "/"
+	op.data = data
 
-	return result, nil
+	// Generate handler file
+	code, err := g.generateOperationHandler(data)
+	if err != nil {
+		return operationFiles{}, fmt.Errorf("failed to generate handler for operation %s: %w", opID, err)
+	}
+
+	// Generate handler tests
+	testCode, err := g.generateOperationHandlerTests(data)
+	if err != nil {
+		return operationFiles{}, fmt.Errorf("failed to generate handler tests for operation %s: %w", opID, err)
+	}
+	op.files[dir+strings.ToLower(opID)+"_handler.go"] = code
+	op.files[dir+strings.ToLower(opID)+"_handler_test.go"] = testCode
+
+	// Added templates are rendered next to the handler
+	extraFiles, err := renderExtraTemplates(g.cache, g.importPath, g.templates, g.extras, strings.ToLower(opID), "operation "+opID, data)
+	if err != nil {
+		return operationFiles{}, fmt.Errorf("failed to render templates for operation %s: %w", opID, err)
+	}
+	for extraFilename, extraCode := range extraFiles {
+		op.files[dir+extraFilename] = extraCode
+	}
+	return op, nil
 }
 
 // prepareOperationData prepares template data for an operation
 func (g *HTTPGenerator) prepareOperationData(opID string, operation *openapi3.Operation) (OperationData, error) {
 	// Find the path and method for this operation
 	var httpMethod, path string
-	for p, pathItem := range g.parser.GetPaths() {
-		for method, op := range map[string]*openapi3.Operation{
-			"GET":     pathItem.Get,
-			"POST":    pathItem.Post,
-			"PUT":     pathItem.Put,
-			"DELETE":  pathItem.Delete,
-			"PATCH":   pathItem.Patch,
-			"OPTIONS": pathItem.Options,
-			"HEAD":    pathItem.Head,
-		} {
-			if op != nil && op.OperationID == opID {
+	paths := g.parser.GetPaths()
+	for _, p := range sortedKeys(paths) {
+		for _, method := range []string{"GET", "POST", "PUT", "DELETE", "PATCH", "OPTIONS", "HEAD"} {
+			if op := paths[p].GetOperation(method); op != nil && op.OperationID == opID {
 				httpMethod = method
 				path = p
 				break
@@ -320,45 +439,70 @@ func (g *HTTPGenerator) prepareOperationData(opID string, operation *openapi3.Op
 	var requestType string
 	var requestTypeName string
 	var requestFields []RequestField
+	var validations []FieldValidation
 
 	if operation.RequestBody != nil && operation.RequestBody.Value != nil {
 		hasRequestBody = true
 
-		// Find the content type (prefer application/json)
-		var contentType string
-		var schema *openapi3.Schema
-		for ct, mediaType := range operation.RequestBody.Value.Content {
-			if mediaType.Schema != nil && mediaType.Schema.Value != nil {
-				if ct == "application/json" || contentType == "" {
-					contentType = ct
-					schema = mediaType.Schema.Value
-				}
-			}
-		}
+		schemaRef := contentSchema(operation.RequestBody.Value.Content)
+		if schemaRef != nil {
+			schema := schemaRef.Value
 
-		if schema != nil {
 			// For nested request types, generate an operation-specific type name
 			requestTypeName = ToPascalCase(opID) + "Request"
 			requestType = requestTypeName
 
-			// Extract fields from schema
-			for propName, propRef := range schema.Properties {
+			// Nested objects of a referenced body are hoisted domain types
+			var parentType string
+			if schemaRef.Ref != "" {
+				parentType = refTypeName(schemaRef)
+			}
+
+			// Extract fields from schema, including those of allOf members
+			properties, required := SchemaProperties(parentType, schema)
+			for _, propName := range sortedPropertyNames(properties) {
+				prop := properties[propName]
+				propRef := prop.Ref
 				if propRef != nil && propRef.Value != nil {
 					// Skip system fields for create operations
 					if httpMethod == "POST" && (propName == "id" || propName == "created_at" || propName == "updated_at") {
 						continue
 					}
 
-					goType, err := MapSchemaToGoType(propRef.Value)
+					// Fields ignored by encoding/json cannot be sent
+					if IsJSONIgnored(propRef) {
+						continue
+					}
+
+					var nameHint string
+					if prop.Owner != "" {
+						nameHint = HoistedTypeName(prop.Owner, propName)
+					}
+
+					goType, err := g.formats.ResolvePropertyType(propName, propRef, nameHint, config.DomainPackage)
 					if err != nil {
 						return OperationData{}, fmt.Errorf("failed to map request field %s: %w", propName, err)
 					}
 
-					requestFields = append(requestFields, RequestField{
-						Name:    ToGoFieldName(propName),
-						Type:    goType,
-						JsonTag: propName,
-					})
+					field := RequestField{
+						Name:       GoFieldName(propName, propRef),
+						Type:       goType,
+						JsonTag:    propName,
+						EnumValues: EnumStringValues(propRef.Value),
+					}
+					wrapped := g.optional.Wraps(propName, propRef.Value, required)
+					if wrapped {
+						field.Type = g.optional.WrapType(goType, propRef.Value.Nullable, config.DomainPackage)
+						field.Provided = g.optional.ProvidedCheck("req." + field.Name)
+					}
+
+					requestFields = append(requestFields, field)
+
+					validation := fieldValidation(propName, propRef, Contains(required, propName), goType,
+						"r."+field.Name, g.optional, wrapped, config.DomainPackage)
+					if validation.HasChecks() {
+						validations = append(validations, validation)
+					}
 				}
 			}
 
@@ -366,6 +510,9 @@ func (g *HTTPGenerator) prepareOperationData(opID string, operation *openapi3.Op
 			sort.Slice(requestFields, func(i, j int) bool {
 				return requestFields[i].Name < requestFields[j].Name
 			})
+			sort.Slice(validations, func(i, j int) bool {
+				return validations[i].Field < validations[j].Field
+			})
 		}
 	}
 
@@ -375,8 +522,10 @@ func (g *HTTPGenerator) prepareOperationData(opID string, operation *openapi3.Op
 	var successStatus int
 
 	if operation.Responses != nil {
-		// Find success response (2xx)
-		for statusCode, response := range operation.Responses.Map() {
+		// Find success response (2xx), the lowest status code first
+		responses := operation.Responses.Map()
+		for _, statusCode := range sortedKeys(responses) {
+			response := responses[statusCode]
 			if statusCode[0] == '2' {
 				successStatusInt := 0
 				fmt.Sscanf(statusCode, "%d", &successStatusInt)
@@ -390,47 +539,14 @@ func (g *HTTPGenerator) prepareOperationData(opID string, operation *openapi3.Op
 				if response.Value != nil && len(response.Value.Content) > 0 {
 					hasResponseBody = true
 
-					// Find the content type (prefer application/json)
-					var contentType string
-					var schema *openapi3.Schema
-					for ct, mediaType := range response.Value.Content {
-						if mediaType.Schema != nil && mediaType.Schema.Value != nil {
-							if ct == "application/json" || contentType == "" {
-								contentType = ct
-								schema = mediaType.Schema.Value
-							}
-						}
-					}
-
-					if schema != nil {
+					schemaRef := contentSchema(response.Value.Content)
+					if schemaRef != nil {
+						// Referenced schemas (and arrays of them) keep their domain type names
 						var err error
-						responseType, err = MapSchemaToGoType(schema)
+						responseType, err = g.formats.ResolveGoType(schemaRef, "", config.DomainPackage)
 						if err != nil {
 							return OperationData{}, fmt.Errorf("failed to map response schema: %w", err)
 						}
-
-						// If schema is a reference to a model, extract the type name
-						if mediaType := response.Value.Content["application/json"]; mediaType != nil &&
-							mediaType.Schema != nil && mediaType.Schema.Ref != "" {
-							parts := strings.Split(mediaType.Schema.Ref, "/")
-							if len(parts) > 0 {
-								typeName := parts[len(parts)-1]
-								responseType = fmt.Sprintf("%s.%s", g.packageName, typeName)
-							}
-						} else if schema.Items != nil && schema.Items.Ref != "" {
-							// Handle array of references
-							parts := strings.Split(schema.Items.Ref, "/")
-							if len(parts) > 0 {
-								typeName := parts[len(parts)-1]
-								responseType = fmt.Sprintf("[]%s.%s", g.packageName, typeName)
-							}
-						} else if !strings.Contains(responseType, ".") &&
-							responseType != "string" && responseType != "int" && responseType != "bool" &&
-							responseType != "float64" && responseType != "interface{}" &&
-							responseType != "map[string]interface{}" && !strings.HasPrefix(responseType, "[]") {
-							// It's a complex type that's not a primitive or built-in
-							responseType = fmt.Sprintf("%s.%s", g.packageName, responseType)
-						}
 					}
 				}
 				break
@@ -466,20 +582,18 @@ func (g *HTTPGenerator) prepareOperationData(opID string, operation *openapi3.Op
 		}
 	}
 
+	// The domain type may be renamed with x-go-name
+	typeName := schemaName
+	if schema, ok := g.parser.GetSchemaByName(schemaName); ok {
+		typeName = GoTypeName(schemaName, schema)
+	}
+
 	// Determine service interface name
 	serviceInterface := schemaName + "Service"
 	varName := opID // Use original opID instead of ToCamelCase to match handler names
 
-	// Check if we need to import the time package
-	importTime := false
-
-	// Check request fields for time.Time usage
-	for _, field := range requestFields {
-		if field.Type == "time.Time" || strings.HasPrefix(field.Type, "[]time.Time") {
-			importTime = true
-			break
-		}
-	}
+	// Collect the packages referenced by the request field types
+	imports, testImports, importTime := g.formats.requestFieldImports(requestFields)
 
 	// Check response type for time.Time usage
 	if !importTime && hasResponseBody {
@@ -491,10 +605,13 @@ func (g *HTTPGenerator) prepareOperationData(opID string, operation *openapi3.Op
 
 	return OperationData{
 		SchemaName:       schemaName,
+		TypeName:         typeName,
 		OperationID:      opID,
 		ServiceInterface: serviceInterface,
 		Method:           httpMethod,
 		Path:             path,
+		CrudType:         g.parser.CrudType(operation),
+		IDParam:          lastPathParam(path),
 		HasPathParams:    len(pathParams) > 0,
 		PathParams:       pathParams,
 		HasQueryParams:   len(queryParams) > 0,
@@ -503,6 +620,7 @@ func (g *HTTPGenerator) prepareOperationData(opID string, operation *openapi3.Op
 		RequestType:      requestType,
 		RequestTypeName:  requestTypeName,
 		RequestFields:    requestFields,
+		Validations:      validations,
 		HasResponseBody:  hasResponseBody,
 		ResponseType:     responseType,
 		SuccessStatus:    successStatus,
@@ -510,42 +628,67 @@ func (g *HTTPGenerator) prepareOperationData(opID string, operation *openapi3.Op
 		PackageName:      g.packageName,
 		ModelImportPath:  g.modelImportPath,
 		ImportPath:       g.importPath,
+		Packages:         g.layout.Imports(g.importPath),
 		VarName:          varName,
 		ImportTime:       importTime,
+		Imports:          imports,
+		TestImports:      testImports,
 	}, nil
 }
 
 // generateOperationHandler generates code for a single operation handler
 func (g *HTTPGenerator) generateOperationHandler(data OperationData) (string, error) {
-	var buf bytes.Buffer
-	if err := g.templates.ExecuteTemplate(&buf, "operation_handler.go.tmpl", data); err != nil {
-		return "", fmt.Errorf("failed to render operation handler template: %w", err)
-	}
-	return buf.String(), nil
+	return g.cache.render("operation_handler.go.tmpl", "operation "+data.OperationID, data, func() (string, error) {
+		var buf bytes.Buffer
+		if err := g.templates.ExecuteTemplate(&buf, "operation_handler.go.tmpl", data); err != nil {
+			return "", fmt.Errorf("failed to render operation handler template: %w", err)
+		}
+		return formatGo(buf.Bytes(), g.importPath, "operation_handler.go.tmpl", "operation "+data.OperationID)
+	})
 }
 
 // generateOperationHandlerTests generates test code for a single operation handler
 func (g *HTTPGenerator) generateOperationHandlerTests(data OperationData) (string, error) {
-	var buf bytes.Buffer
-	if err := g.templates.ExecuteTemplate(&buf, "operation_handler_test.go.tmpl", data); err != nil {
-		return "", fmt.Errorf("failed to render operation handler test template: %w", err)
-	}
-	return buf.String(), nil
+	return g.cache.render("operation_handler_test.go.tmpl", "operation "+data.OperationID, data, func() (string, error) {
+		var buf bytes.Buffer
+		if err := g.templates.ExecuteTemplate(&buf, "operation_handler_test.go.tmpl", data); err != nil {
+			return "", fmt.Errorf("failed to render operation handler test template: %w", err)
+		}
+		return formatGo(buf.Bytes(), g.importPath, "operation_handler_test.go.tmpl", "operation "+data.OperationID)
+	})
 }
 
 // generateHTTPUtils generates the HTTP utilities file
 func (g *HTTPGenerator) generateHTTPUtils() (string, error) {
 	var buf bytes.Buffer
 	data := struct {
-		ImportPath string
+		Packages config.LayoutImports
 	}{
-		ImportPath: g.importPath,
+		Packages: g.layout.Imports(g.importPath),
 	}
 	if err := g.templates.ExecuteTemplate(&buf, "http_utils.go.tmpl", data); err != nil {
 		return "", fmt.Errorf("failed to render HTTP utilities template: %w", err)
 	}
 
-	return buf.String(), nil
+	return formatGo(buf.Bytes(), g.importPath, "http_utils.go.tmpl", "")
+}
+
+// generateOpenAPIValidator generates the middleware validating requests and
+// responses against the embedded spec
+func (g *HTTPGenerator) generateOpenAPIValidator() (string, error) {
+	var buf bytes.Buffer
+	data := struct {
+		Packages config.LayoutImports
+		SpecFile string
+	}{
+		Packages: g.layout.Imports(g.importPath),
+		SpecFile: config.OpenAPISpecFile,
+	}
+	if err := g.templates.ExecuteTemplate(&buf, "openapi_validator.go.tmpl", data); err != nil {
+		return "", fmt.Errorf("failed to render OpenAPI validator template: %w", err)
+	}
+
+	return formatGo(buf.Bytes(), g.importPath, "openapi_validator.go.tmpl", "")
 }
 
 // generateHandlerWrapper generates the generic handler wrapper file
@@ -555,24 +698,27 @@ func (g *HTTPGenerator) generateHandlerWrapper() (string, error) {
 		return "", fmt.Errorf("failed to render handler wrapper template: %w", err)
 	}
 
-	return buf.String(), nil
+	return formatGo(buf.Bytes(), g.importPath, "handler_wrapper.go.tmpl", "")
 }
 
 // generateMocks generates mock implementations for the service interfaces
 func (g *HTTPGenerator) generateMocks(data OperationData) (string, error) {
-	var buf bytes.Buffer
-	if err := g.templates.ExecuteTemplate(&buf, "mocks.go.tmpl", data); err != nil {
-		return "", fmt.Errorf("failed to render mocks template: %w", err)
-	}
-	return buf.String(), nil
+	return g.cache.render("mocks.go.tmpl", "resource "+data.Domain, data, func() (string, error) {
+		var buf bytes.Buffer
+		if err := g.templates.ExecuteTemplate(&buf, "mocks.go.tmpl", data); err != nil {
+			return "", fmt.Errorf("failed to render mocks template: %w", err)
+		}
+		return formatGo(buf.Bytes(), g.importPath, "mocks.go.tmpl", "resource "+data.Domain)
+	})
 }
 
 // generateSchemaHandler creates a handler file that provides a function to register all operation handlers for a schema
 func (g *HTTPGenerator) generateSchemaHandler(resource ResourceData) (string, error) {
-	var buf bytes.Buffer
-	if err := g.templates.ExecuteTemplate(&buf, "schema_handler.go.tmpl", resource); err != nil {
-		return "", fmt.Errorf("failed to render schema handler template: %w", err)
-	}
-
-	return buf.String(), nil
+	return g.cache.render("schema_handler.go.tmpl", "resource "+resource.Domain, resource, func() (string, error) {
+		var buf bytes.Buffer
+		if err := g.templates.ExecuteTemplate(&buf, "schema_handler.go.tmpl", resource); err != nil {
+			return "", fmt.Errorf("failed to render schema handler template: %w", err)
+		}
+		return formatGo(buf.Bytes(), g.importPath, "schema_handler.go.tmpl", "resource "+resource.Domain)
+	})
 }
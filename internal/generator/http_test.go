@@ -15,42 +15,42 @@ func TestTimeImportDetection(t *testing.T) {
 	}{
 		{
 			name:       "No time fields",
-			fields:     []RequestField{{"Name", "string", "name"}},
+			fields:     []RequestField{{Name: "Name", Type: "string", JsonTag: "name"}},
 			respType:   "string",
 			hasResp:    true,
 			wantImport: false,
 		},
 		{
 			name:       "Has time field in request",
-			fields:     []RequestField{{"CreatedAt", "time.Time", "created_at"}},
+			fields:     []RequestField{{Name: "CreatedAt", Type: "time.Time", JsonTag: "created_at"}},
 			respType:   "string",
 			hasResp:    true,
 			wantImport: true,
 		},
 		{
 			name:       "Has time array in request",
-			fields:     []RequestField{{"Timestamps", "[]time.Time", "timestamps"}},
+			fields:     []RequestField{{Name: "Timestamps", Type: "[]time.Time", JsonTag: "timestamps"}},
 			respType:   "string",
 			hasResp:    true,
 			wantImport: true,
 		},
 		{
 			name:       "Has time response",
-			fields:     []RequestField{{"Name", "string", "name"}},
+			fields:     []RequestField{{Name: "Name", Type: "string", JsonTag: "name"}},
 			respType:   "time.Time",
 			hasResp:    true,
 			wantImport: true,
 		},
 		{
 			name:       "Has time array response",
-			fields:     []RequestField{{"Name", "string", "name"}},
+			fields:     []RequestField{{Name: "Name", Type: "string", JsonTag: "name"}},
 			respType:   "[]time.Time",
 			hasResp:    true,
 			wantImport: true,
 		},
 		{
 			name:       "Has time in complex response",
-			fields:     []RequestField{{"Name", "string", "name"}},
+			fields:     []RequestField{{Name: "Name", Type: "string", JsonTag: "name"}},
 			respType:   "models.UserWithTime",
 			hasResp:    true,
 			wantImport: false, // Changed expectation - "time" in type name doesn't mean time.Time import needed
@@ -97,3 +97,18 @@ func TestTimeImportDetection(t *testing.T) {
 		})
 	}
 }
+
+func TestLastPathParam(t *testing.T) {
+	tests := map[string]string{
+		"/pets":                          "",
+		"/pets/{id}":                     "id",
+		"/products/{productId}":          "productId",
+		"/owners/{ownerId}/pets/{petId}": "petId",
+		"/owners/{ownerId}/pets":         "ownerId",
+	}
+	for path, expected := range tests {
+		if got := lastPathParam(path); got != expected {
+			t.Errorf("lastPathParam(%q) = %q, want %q", path, got, expected)
+		}
+	}
+}
@@ -2,23 +2,26 @@ package generator
 
 import (
 	"bytes"
-	"embed"
 	"fmt"
+	"io/fs"
 	"strings"
-	"text/template"
 
+	"github.com/zeek-r/goapigen/internal/config"
 	"github.com/zeek-r/goapigen/internal/parser"
 )
 
 // MainGenerator generates the main.go file for the application
 type MainGenerator struct {
-	parser       *parser.OpenAPIParser
-	templateFS   embed.FS
-	importPath   string
-	mongoURI     string
-	dbName       string
-	defaultPort  string
-	shutdownTime int
+	parser          *parser.OpenAPIParser
+	templateFS      fs.FS
+	importPath      string
+	layout          config.Layout
+	mongoURI        string
+	dbName          string
+	defaultPort     string
+	shutdownTime    int
+	validateOpenAPI bool
+	resourceNames   []string
 }
 
 // MainResourceData holds data for each API resource in main.go
@@ -34,26 +37,29 @@ type MainResourceData struct {
 
 // MainTemplateData holds data for the main.go template
 type MainTemplateData struct {
-	ImportPath      string             // Import path for packages
-	UseMongo        bool               // Whether MongoDB is used
-	HasResources    bool               // Whether any resources are defined
-	Resources       []MainResourceData // Resources to be included in the router
-	DefaultPort     string             // Default port for the server
-	ShutdownTimeout int                // Shutdown timeout in seconds
-	MongoURI        string             // MongoDB URI
-	DBName          string             // MongoDB database name
+	ImportPath      string               // Import path for packages
+	Packages        config.LayoutImports // Imports of the packages of the layout
+	UseMongo        bool                 // Whether MongoDB is used
+	HasResources    bool                 // Whether any resources are defined
+	Resources       []MainResourceData   // Resources to be included in the router
+	DefaultPort     string               // Default port for the server
+	ShutdownTimeout int                  // Shutdown timeout in seconds
+	MongoURI        string               // MongoDB URI
+	DBName          string               // MongoDB database name
+	ValidateOpenAPI bool                 // Whether the OpenAPI validation middleware is used
 }
 
 // NewMainGenerator creates a new MainGenerator
 func NewMainGenerator(
 	parser *parser.OpenAPIParser,
 	importPath string,
-	templateFS embed.FS,
+	templateFS fs.FS,
 ) (*MainGenerator, error) {
 	return &MainGenerator{
 		parser:       parser,
 		templateFS:   templateFS,
 		importPath:   importPath,
+		layout:       config.DefaultLayout(),
 		mongoURI:     "mongodb://localhost:27017",
 		dbName:       "api",
 		defaultPort:  "8080",
@@ -81,6 +87,61 @@ func (g *MainGenerator) SetShutdownTimeout(seconds int) {
 	g.shutdownTime = seconds
 }
 
+// SetOpenAPIValidation sets whether the router uses the OpenAPI validation
+// middleware generated with the HTTP handlers
+func (g *MainGenerator) SetOpenAPIValidation(enabled bool) {
+	g.validateOpenAPI = enabled
+}
+
+// SetLayout sets the layout the generated imports follow
+func (g *MainGenerator) SetLayout(layout config.Layout) {
+	g.layout = layout
+}
+
+// SetResourceNames restricts the resources wired in main.go and routes.go
+// to the named schemas; all schemas are resources by default
+func (g *MainGenerator) SetResourceNames(names []string) {
+	g.resourceNames = names
+}
+
+// resources returns the names of the schemas wired as resources
+func (g *MainGenerator) resources() []string {
+	if g.resourceNames != nil {
+		return g.resourceNames
+	}
+	return sortedSchemaNames(g.parser.GetSchemas())
+}
+
+// resourceData returns the resources wired in main.go, routes.go and
+// database.go. Handlers are only generated for the resources tagging
+// operations, whose first tag names their domain.
+func (g *MainGenerator) resourceData(hasRepo, hasServices, hasHandler bool) []MainResourceData {
+	tagged := make(map[string]bool)
+	for _, operation := range g.parser.GetOperations() {
+		if len(operation.Tags) > 0 {
+			tagged[strings.ToLower(operation.Tags[0])] = true
+		}
+	}
+
+	resources := make([]MainResourceData, 0)
+	for _, name := range g.resources() {
+		varName := strings.ToLower(name)
+		collectionName := varName + "s" // Simple pluralization
+		apiPath := varName + "s"        // Simple pluralization for API path
+
+		resources = append(resources, MainResourceData{
+			Name:           name,
+			VarName:        varName,
+			CollectionName: collectionName,
+			APIPath:        apiPath,
+			HasRepository:  hasRepo,
+			HasService:     hasServices,
+			HasHandler:     hasHandler && tagged[varName],
+		})
+	}
+	return resources
+}
+
 // GenerateMain generates both main.go and routes.go files
 func (g *MainGenerator) GenerateMain() (map[string]string, error) {
 	result := make(map[string]string)
@@ -112,34 +173,17 @@ func (g *MainGenerator) GenerateMain() (map[string]string, error) {
 // GenerateMainFile generates the stable main.go file
 func (g *MainGenerator) GenerateMainFile(useMongo, hasRepo, hasServices, hasHandler bool) (string, error) {
 	// Load template
-	tmpl, err := template.ParseFS(g.templateFS, "templates/cmd/main.go.tmpl")
+	tmpl, err := parseTemplates(g.templateFS, "main", nil, "templates/cmd/main.go.tmpl")
 	if err != nil {
 		return "", fmt.Errorf("failed to parse main template: %w", err)
 	}
 
-	// Build resource data from schemas for conditional logic
-	resources := make([]MainResourceData, 0)
-	schemas := g.parser.GetSchemas()
-
-	for name := range schemas {
-		varName := strings.ToLower(name)
-		collectionName := varName + "s" // Simple pluralization
-		apiPath := varName + "s"        // Simple pluralization for API path
-
-		resources = append(resources, MainResourceData{
-			Name:           name,
-			VarName:        varName,
-			CollectionName: collectionName,
-			APIPath:        apiPath,
-			HasRepository:  hasRepo,
-			HasService:     hasServices,
-			HasHandler:     hasHandler,
-		})
-	}
+	resources := g.resourceData(hasRepo, hasServices, hasHandler)
 
 	// Create template data
 	data := MainTemplateData{
 		ImportPath:      g.importPath,
+		Packages:        g.layout.Imports(g.importPath),
 		UseMongo:        useMongo,
 		HasResources:    len(resources) > 0,
 		Resources:       resources,
@@ -147,6 +191,7 @@ func (g *MainGenerator) GenerateMainFile(useMongo, hasRepo, hasServices, hasHand
 		ShutdownTimeout: g.shutdownTime,
 		MongoURI:        g.mongoURI,
 		DBName:          g.dbName,
+		ValidateOpenAPI: g.validateOpenAPI && hasHandler,
 	}
 
 	// Execute template
@@ -155,40 +200,23 @@ func (g *MainGenerator) GenerateMainFile(useMongo, hasRepo, hasServices, hasHand
 		return "", fmt.Errorf("failed to execute main template: %w", err)
 	}
 
-	return buf.String(), nil
+	return formatGo(buf.Bytes(), g.importPath, "main.go.tmpl", "")
 }
 
 // GenerateRoutesFile generates the routes.go file with conditional imports
 func (g *MainGenerator) GenerateRoutesFile(useMongo, hasRepo, hasServices, hasHandler bool) (string, error) {
 	// Load template
-	tmpl, err := template.ParseFS(g.templateFS, "templates/cmd/routes.go.tmpl")
+	tmpl, err := parseTemplates(g.templateFS, "main", nil, "templates/cmd/routes.go.tmpl")
 	if err != nil {
 		return "", fmt.Errorf("failed to parse routes template: %w", err)
 	}
 
-	// Build resource data from schemas
-	resources := make([]MainResourceData, 0)
-	schemas := g.parser.GetSchemas()
-
-	for name := range schemas {
-		varName := strings.ToLower(name)
-		collectionName := varName + "s" // Simple pluralization
-		apiPath := varName + "s"        // Simple pluralization for API path
-
-		resources = append(resources, MainResourceData{
-			Name:           name,
-			VarName:        varName,
-			CollectionName: collectionName,
-			APIPath:        apiPath,
-			HasRepository:  hasRepo,
-			HasService:     hasServices,
-			HasHandler:     hasHandler,
-		})
-	}
+	resources := g.resourceData(hasRepo, hasServices, hasHandler)
 
 	// Create template data
 	data := MainTemplateData{
 		ImportPath:      g.importPath,
+		Packages:        g.layout.Imports(g.importPath),
 		UseMongo:        useMongo,
 		HasResources:    len(resources) > 0,
 		Resources:       resources,
@@ -196,6 +224,7 @@ func (g *MainGenerator) GenerateRoutesFile(useMongo, hasRepo, hasServices, hasHa
 		ShutdownTimeout: g.shutdownTime,
 		MongoURI:        g.mongoURI,
 		DBName:          g.dbName,
+		ValidateOpenAPI: g.validateOpenAPI && hasHandler,
 	}
 
 	// Execute template
@@ -204,40 +233,23 @@ func (g *MainGenerator) GenerateRoutesFile(useMongo, hasRepo, hasServices, hasHa
 		return "", fmt.Errorf("failed to execute routes template: %w", err)
 	}
 
-	return buf.String(), nil
+	return formatGo(buf.Bytes(), g.importPath, "routes.go.tmpl", "")
 }
 
 // GenerateDatabaseFile generates the database.go file with dependency injection
 func (g *MainGenerator) GenerateDatabaseFile(useMongo, hasRepo, hasServices, hasHandler bool) (string, error) {
 	// Load template
-	tmpl, err := template.ParseFS(g.templateFS, "templates/cmd/database.go.tmpl")
+	tmpl, err := parseTemplates(g.templateFS, "main", nil, "templates/cmd/database.go.tmpl")
 	if err != nil {
 		return "", fmt.Errorf("failed to parse database template: %w", err)
 	}
 
-	// Build resource data from schemas
-	resources := make([]MainResourceData, 0)
-	schemas := g.parser.GetSchemas()
-
-	for name := range schemas {
-		varName := strings.ToLower(name)
-		collectionName := varName + "s" // Simple pluralization
-		apiPath := varName + "s"        // Simple pluralization for API path
-
-		resources = append(resources, MainResourceData{
-			Name:           name,
-			VarName:        varName,
-			CollectionName: collectionName,
-			APIPath:        apiPath,
-			HasRepository:  hasRepo,
-			HasService:     hasServices,
-			HasHandler:     hasHandler,
-		})
-	}
+	resources := g.resourceData(hasRepo, hasServices, hasHandler)
 
 	// Create template data
 	data := MainTemplateData{
 		ImportPath:      g.importPath,
+		Packages:        g.layout.Imports(g.importPath),
 		UseMongo:        useMongo,
 		HasResources:    len(resources) > 0,
 		Resources:       resources,
@@ -245,6 +257,7 @@ func (g *MainGenerator) GenerateDatabaseFile(useMongo, hasRepo, hasServices, has
 		ShutdownTimeout: g.shutdownTime,
 		MongoURI:        g.mongoURI,
 		DBName:          g.dbName,
+		ValidateOpenAPI: g.validateOpenAPI && hasHandler,
 	}
 
 	// Execute template
@@ -253,7 +266,7 @@ func (g *MainGenerator) GenerateDatabaseFile(useMongo, hasRepo, hasServices, has
 		return "", fmt.Errorf("failed to execute database template: %w", err)
 	}
 
-	return buf.String(), nil
+	return formatGo(buf.Bytes(), g.importPath, "database.go.tmpl", "")
 }
 
 // GenerateWithFeatures generates both files with specific feature flags
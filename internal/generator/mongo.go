@@ -2,10 +2,12 @@ package generator
 
 import (
 	"bytes"
-	"embed"
 	"fmt"
+	"io/fs"
+	"strings"
 	"text/template"
 
+	"github.com/zeek-r/goapigen/internal/config"
 	"github.com/zeek-r/goapigen/internal/parser"
 )
 
@@ -18,12 +20,16 @@ type TestField struct {
 // RepositoryTemplateData contains data for the repository template
 type RepositoryTemplateData struct {
 	SchemaName     string
+	TypeName       string // Go name of the schema's domain type
 	VarName        string
 	PluralVarName  string
 	PackageName    string
 	RepoPackage    string
 	ImportPath     string
+	Packages       config.LayoutImports
 	CollectionName string
+	IDField        string // BSON name of the id field
+	IDName         string // Go name of the id field, empty when the schema has none
 	HasCreateOp    bool
 	HasGetOp       bool
 	HasListOp      bool
@@ -40,15 +46,21 @@ type MongoGenerator struct {
 	packageName string
 	repoPackage string
 	importPath  string
-	templateFS  embed.FS
+	layout      config.Layout
+	templateFS  fs.FS
 	typeGen     *TypeGenerator
 	templates   *template.Template
+	extras      []string
+	optional    OptionalStrategy
+	formats     *FormatRegistry
+	collections map[string]string
+	cache       *RenderCache
 }
 
 // NewMongoGenerator creates a new MongoDB repository generator
-func NewMongoGenerator(parser *parser.OpenAPIParser, packageName string, repoPackage string, importPath string, templateFS embed.FS) (*MongoGenerator, error) {
+func NewMongoGenerator(parser *parser.OpenAPIParser, packageName string, repoPackage string, importPath string, templateFS fs.FS) (*MongoGenerator, error) {
 	// Parse templates
-	tmpl, err := template.ParseFS(templateFS, "templates/mongo/repository.go.tmpl", "templates/mongo/repository_test.go.tmpl")
+	tmpl, err := parseTemplates(templateFS, "mongo", nil, "templates/mongo/repository.go.tmpl", "templates/mongo/repository_test.go.tmpl")
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse templates: %w", err)
 	}
@@ -58,12 +70,55 @@ func NewMongoGenerator(parser *parser.OpenAPIParser, packageName string, repoPac
 		packageName: packageName,
 		repoPackage: repoPackage,
 		importPath:  importPath,
+		layout:      config.DefaultLayout(),
 		templateFS:  templateFS,
 		typeGen:     NewTypeGenerator(parser, packageName, templateFS),
 		templates:   tmpl,
+		optional:    OptionalValue,
+		formats:     specFormatRegistry(parser),
+		collections: make(map[string]string),
 	}, nil
 }
 
+// SetLayout sets the layout the generated imports follow
+func (g *MongoGenerator) SetLayout(layout config.Layout) {
+	g.layout = layout
+}
+
+// SetCollectionName overrides the collection of a schema's repository,
+// named after the schema by default, e.g. pets for Pet
+func (g *MongoGenerator) SetCollectionName(schemaName, collection string) {
+	g.collections[schemaName] = collection
+}
+
+// collectionName returns the collection of a schema's repository
+func (g *MongoGenerator) collectionName(schemaName string) string {
+	if collection, ok := g.collections[schemaName]; ok {
+		return collection
+	}
+	return ToSnakeCase(schemaName) + "s"
+}
+
+// SetOptionalStrategy sets how optional and nullable fields are generated;
+// it must match the strategy used for the domain types
+func (g *MongoGenerator) SetOptionalStrategy(strategy OptionalStrategy) {
+	g.optional = strategy
+	g.typeGen.SetOptionalStrategy(strategy)
+}
+
+// SetFormatRegistry sets the format mappings used to resolve Go types;
+// it must match the mappings used for the domain types
+func (g *MongoGenerator) SetFormatRegistry(formats *FormatRegistry) {
+	g.formats = formats
+	g.typeGen.SetFormatRegistry(formats)
+}
+
+// SetRenderCache sets the cache of the code rendered by an earlier
+// generation, so that only the repositories whose data changed are rendered
+func (g *MongoGenerator) SetRenderCache(cache *RenderCache) {
+	g.cache = cache
+}
+
 // GenerateRepository generates a MongoDB repository for a schema
 func (g *MongoGenerator) GenerateRepository(schemaName string) (string, error) {
 	// Generate the template data
@@ -73,12 +128,13 @@ func (g *MongoGenerator) GenerateRepository(schemaName string) (string, error) {
 	}
 
 	// Render the template
-	var buf bytes.Buffer
-	if err := g.templates.ExecuteTemplate(&buf, "repository.go.tmpl", data); err != nil {
-		return "", fmt.Errorf("failed to render repository template: %w", err)
-	}
-
-	return buf.String(), nil
+	return g.cache.render("repository.go.tmpl", "schema "+schemaName, data, func() (string, error) {
+		var buf bytes.Buffer
+		if err := g.templates.ExecuteTemplate(&buf, "repository.go.tmpl", data); err != nil {
+			return "", fmt.Errorf("failed to render repository template: %w", err)
+		}
+		return formatGo(buf.Bytes(), g.importPath, "repository.go.tmpl", "schema "+schemaName)
+	})
 }
 
 // GenerateRepositoryTests generates test files for a repository
@@ -90,12 +146,37 @@ func (g *MongoGenerator) GenerateRepositoryTests(schemaName string) (string, err
 	}
 
 	// Render the template
-	var buf bytes.Buffer
-	if err := g.templates.ExecuteTemplate(&buf, "repository_test.go.tmpl", data); err != nil {
-		return "", fmt.Errorf("failed to render repository test template: %w", err)
+	return g.cache.render("repository_test.go.tmpl", "schema "+schemaName, data, func() (string, error) {
+		var buf bytes.Buffer
+		if err := g.templates.ExecuteTemplate(&buf, "repository_test.go.tmpl", data); err != nil {
+			return "", fmt.Errorf("failed to render repository test template: %w", err)
+		}
+		return formatGo(buf.Bytes(), g.importPath, "repository_test.go.tmpl", "schema "+schemaName)
+	})
+}
+
+// AddTemplates parses additional templates, rendered for every schema by
+// GenerateExtraFiles with the data of the repository template
+func (g *MongoGenerator) AddTemplates(paths ...string) error {
+	tmpl, names, err := parseExtraTemplates(g.templates, g.templateFS, paths)
+	if err != nil {
+		return err
 	}
+	g.templates = tmpl
+	g.extras = append(g.extras, names...)
+	return nil
+}
 
-	return buf.String(), nil
+// GenerateExtraFiles renders the added templates for a schema, by file name
+func (g *MongoGenerator) GenerateExtraFiles(schemaName string) (map[string]string, error) {
+	if len(g.extras) == 0 {
+		return nil, nil
+	}
+	data, err := g.prepareTemplateData(schemaName)
+	if err != nil {
+		return nil, err
+	}
+	return renderExtraTemplates(g.cache, g.importPath, g.templates, g.extras, strings.ToLower(schemaName), "schema "+schemaName, data)
 }
 
 // prepareTemplateData prepares data for the templates
@@ -108,17 +189,24 @@ func (g *MongoGenerator) prepareTemplateData(schemaName string) (RepositoryTempl
 
 	// Get CRUD operations for this schema
 	crudOps := g.parser.GetCrudOperationsForSchema(schemaName)
+	typeName := GoTypeName(schemaName, schema)
+	idField, idName := "id", ""
 
 	// Prepare test fields with default test values
 	testFields := []TestField{}
 
-	for propName, propRef := range schema.Properties {
+	// Fields promoted from allOf members are stored inline
+	properties, required := SchemaProperties(typeName, schema)
+	for _, propName := range sortedPropertyNames(properties) {
+		prop := properties[propName]
+		propRef := prop.Ref
 		if propRef == nil || propRef.Value == nil {
 			continue
 		}
 
 		// Skip ID field as it's handled separately in tests
 		if propName == "id" || propName == "ID" {
+			idField, idName = BsonFieldName(propName, propRef), GoFieldName(propName, propRef)
 			continue
 		}
 
@@ -128,8 +216,24 @@ func (g *MongoGenerator) prepareTemplateData(schemaName string) (RepositoryTempl
 			continue
 		}
 
-		fieldName := ToGoFieldName(propName)
-		testValue := GetTestValueForProperty(propRef.Value)
+		// Wrapped optional fields keep their zero value (absent)
+		if g.optional.Wraps(propName, propRef.Value, required) {
+			continue
+		}
+
+		// Fields of imported or generated helper types keep their zero value
+		goType, err := g.formats.ResolvePropertyType(propName, propRef, HoistedTypeName(prop.Owner, propName), "")
+		if err != nil {
+			return RepositoryTemplateData{}, fmt.Errorf("failed to map field %s: %w", propName, err)
+		}
+		_, isLocal := g.formats.localFormatType(propRef.Value)
+		_, isCustom := goTypeExtension(propRef.Value)
+		if isLocal || isCustom || len(g.formats.TypeImports(goType)) > 0 {
+			continue
+		}
+
+		fieldName := GoFieldName(propName, propRef)
+		testValue := g.formats.GetTestValueForSchemaRef(propRef, HoistedTypeName(prop.Owner, propName), config.DomainPackage)
 
 		testFields = append(testFields, TestField{
 			Name:      fieldName,
@@ -140,12 +244,16 @@ func (g *MongoGenerator) prepareTemplateData(schemaName string) (RepositoryTempl
 	// Prepare template data
 	data := RepositoryTemplateData{
 		SchemaName:     schemaName,
+		TypeName:       typeName,
 		VarName:        ToCamelCase(schemaName),
 		PluralVarName:  ToCamelCase(schemaName) + "s",
 		PackageName:    g.packageName,
 		RepoPackage:    g.repoPackage,
 		ImportPath:     g.importPath,
-		CollectionName: ToSnakeCase(schemaName) + "s",
+		Packages:       g.layout.Imports(g.importPath),
+		CollectionName: g.collectionName(schemaName),
+		IDField:        idField,
+		IDName:         idName,
 		HasCreateOp:    false,
 		HasGetOp:       false,
 		HasListOp:      false,
@@ -174,8 +282,8 @@ func (g *MongoGenerator) prepareTemplateData(schemaName string) (RepositoryTempl
 	}
 
 	// Check for timestamp fields
-	for propName, propRef := range schema.Properties {
-		if propRef != nil && propRef.Value != nil {
+	for propName, prop := range properties {
+		if prop.Ref != nil && prop.Ref.Value != nil {
 			if propName == "created_at" || propName == "createdAt" {
 				data.HasCreatedAt = true
 			}
@@ -2,51 +2,37 @@ package generator
 
 import (
 	"bytes"
-	"embed"
 	"fmt"
+	"io/fs"
 	"strings"
 	"text/template"
 
+	"github.com/zeek-r/goapigen/internal/config"
 	"github.com/zeek-r/goapigen/internal/parser"
 )
 
-// EnumField represents a field with enum constraints
-type EnumField struct {
-	Name    string
-	Type    string
-	JsonTag string
-	Values  []string
-}
-
-// MinMaxField represents a field with min/max constraints
-type MinMaxField struct {
-	Name    string
-	Type    string
-	JsonTag string
-	Min     float64
-	HasMax  bool
-	Max     float64
-}
-
 // ServiceTemplateData contains data for the service template
 type ServiceTemplateData struct {
-	SchemaName     string
-	VarName        string
-	PackageName    string
-	ImportPath     string
-	HasCreateOp    bool
-	HasGetOp       bool
-	HasListOp      bool
-	HasUpdateOp    bool
-	HasDeleteOp    bool
-	HasCreatedAt   bool
-	HasUpdatedAt   bool
-	CreateFields   []RequestField
-	UpdateFields   []RequestField
-	RequiredFields []RequestField
-	EnumFields     []EnumField
-	MinMaxFields   []MinMaxField
-	ImportTime     bool
+	SchemaName        string
+	TypeName          string // Go name of the schema's domain type
+	VarName           string
+	PackageName       string
+	ImportPath        string
+	Packages          config.LayoutImports
+	HasCreateOp       bool
+	HasGetOp          bool
+	HasListOp         bool
+	HasUpdateOp       bool
+	HasDeleteOp       bool
+	HasCreatedAt      bool
+	HasUpdatedAt      bool
+	CreateFields      []RequestField
+	UpdateFields      []RequestField
+	CreateValidations []FieldValidation // Checks of the create request's Validate method
+	UpdateValidations []FieldValidation // Checks of the update request's Validate method
+	ImportTime        bool
+	Imports           []string // Import specs needed by field types, besides time
+	TestImports       []string // Import specs needed by test values
 }
 
 // ServiceGenerator generates service implementations for API schemas
@@ -54,23 +40,29 @@ type ServiceGenerator struct {
 	parser      *parser.OpenAPIParser
 	packageName string
 	importPath  string
+	layout      config.Layout
+	templateFS  fs.FS
 	typeGen     *TypeGenerator
 	templates   *template.Template
+	extras      []string
+	optional    OptionalStrategy
+	formats     *FormatRegistry
+	cache       *RenderCache
 }
 
 // NewServiceGenerator creates a new service generator
-func NewServiceGenerator(parser *parser.OpenAPIParser, packageName string, importPath string, templateFS embed.FS) (*ServiceGenerator, error) {
+func NewServiceGenerator(parser *parser.OpenAPIParser, packageName string, importPath string, templateFS fs.FS) (*ServiceGenerator, error) {
 	// Create templates with function map
-	tmpl := template.New("")
-	tmpl.Funcs(template.FuncMap{
+	funcs := template.FuncMap{
 		"contains": func(s, substr string) bool { return strings.Contains(s, substr) },
 		"lower":    func(s string) string { return strings.ToLower(s) },
-	})
+	}
 
 	// Parse templates
-	tmpl, err := tmpl.ParseFS(templateFS,
+	tmpl, err := parseTemplates(templateFS, "service", funcs,
 		"templates/service/service.go.tmpl",
 		"templates/service/service_test.go.tmpl",
+		config.ValidateTemplate,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse templates: %w", err)
@@ -80,11 +72,40 @@ func NewServiceGenerator(parser *parser.OpenAPIParser, packageName string, impor
 		parser:      parser,
 		packageName: packageName,
 		importPath:  importPath,
+		layout:      config.DefaultLayout(),
+		templateFS:  templateFS,
 		typeGen:     NewTypeGenerator(parser, packageName, templateFS),
 		templates:   tmpl,
+		optional:    OptionalValue,
+		formats:     specFormatRegistry(parser),
 	}, nil
 }
 
+// SetLayout sets the layout the generated imports follow
+func (g *ServiceGenerator) SetLayout(layout config.Layout) {
+	g.layout = layout
+}
+
+// SetOptionalStrategy sets how optional and nullable request fields are
+// generated; it must match the strategy used for the domain types
+func (g *ServiceGenerator) SetOptionalStrategy(strategy OptionalStrategy) {
+	g.optional = strategy
+	g.typeGen.SetOptionalStrategy(strategy)
+}
+
+// SetFormatRegistry sets the format mappings used to resolve Go types;
+// it must match the mappings used for the domain types
+func (g *ServiceGenerator) SetFormatRegistry(formats *FormatRegistry) {
+	g.formats = formats
+	g.typeGen.SetFormatRegistry(formats)
+}
+
+// SetRenderCache sets the cache of the code rendered by an earlier
+// generation, so that only the services whose data changed are rendered
+func (g *ServiceGenerator) SetRenderCache(cache *RenderCache) {
+	g.cache = cache
+}
+
 // GenerateService generates a service for a schema
 func (g *ServiceGenerator) GenerateService(schemaName string) (string, error) {
 	// Generate the template data
@@ -94,12 +115,13 @@ func (g *ServiceGenerator) GenerateService(schemaName string) (string, error) {
 	}
 
 	// Render the template
-	var buf bytes.Buffer
-	if err := g.templates.ExecuteTemplate(&buf, "service.go.tmpl", data); err != nil {
-		return "", fmt.Errorf("failed to render service template: %w", err)
-	}
-
-	return buf.String(), nil
+	return g.cache.render("service.go.tmpl", "schema "+schemaName, data, func() (string, error) {
+		var buf bytes.Buffer
+		if err := g.templates.ExecuteTemplate(&buf, "service.go.tmpl", data); err != nil {
+			return "", fmt.Errorf("failed to render service template: %w", err)
+		}
+		return formatGo(buf.Bytes(), g.importPath, "service.go.tmpl", "schema "+schemaName)
+	})
 }
 
 // GenerateServiceTests generates test code for a service
@@ -111,12 +133,37 @@ func (g *ServiceGenerator) GenerateServiceTests(schemaName string) (string, erro
 	}
 
 	// Render the template
-	var buf bytes.Buffer
-	if err := g.templates.ExecuteTemplate(&buf, "service_test.go.tmpl", data); err != nil {
-		return "", fmt.Errorf("failed to render service test template: %w", err)
+	return g.cache.render("service_test.go.tmpl", "schema "+schemaName, data, func() (string, error) {
+		var buf bytes.Buffer
+		if err := g.templates.ExecuteTemplate(&buf, "service_test.go.tmpl", data); err != nil {
+			return "", fmt.Errorf("failed to render service test template: %w", err)
+		}
+		return formatGo(buf.Bytes(), g.importPath, "service_test.go.tmpl", "schema "+schemaName)
+	})
+}
+
+// AddTemplates parses additional templates, rendered for every schema by
+// GenerateExtraFiles with the data of the service template
+func (g *ServiceGenerator) AddTemplates(paths ...string) error {
+	tmpl, names, err := parseExtraTemplates(g.templates, g.templateFS, paths)
+	if err != nil {
+		return err
 	}
+	g.templates = tmpl
+	g.extras = append(g.extras, names...)
+	return nil
+}
 
-	return buf.String(), nil
+// GenerateExtraFiles renders the added templates for a schema, by file name
+func (g *ServiceGenerator) GenerateExtraFiles(schemaName string) (map[string]string, error) {
+	if len(g.extras) == 0 {
+		return nil, nil
+	}
+	data, err := g.prepareTemplateData(schemaName)
+	if err != nil {
+		return nil, err
+	}
+	return renderExtraTemplates(g.cache, g.importPath, g.templates, g.extras, strings.ToLower(schemaName), "schema "+schemaName, data)
 }
 
 // prepareTemplateData prepares data for the service templates
@@ -129,83 +176,57 @@ func (g *ServiceGenerator) prepareTemplateData(schemaName string) (ServiceTempla
 
 	// Get CRUD operations for this schema
 	crudOps := g.parser.GetCrudOperationsForSchema(schemaName)
+	typeName := GoTypeName(schemaName, schema)
 
 	// Prepare field data
-	var createFields, updateFields, requiredFields []RequestField
-	var enumFields []EnumField
-	var minMaxFields []MinMaxField
-	importTime := false
-
-	for propName, propRef := range schema.Properties {
+	var createFields, updateFields []RequestField
+	var createValidations, updateValidations []FieldValidation
+
+	// Fields promoted from allOf members are set like the schema's own
+	properties, required := SchemaProperties(typeName, schema)
+	for _, propName := range sortedPropertyNames(properties) {
+		prop := properties[propName]
+		propRef := prop.Ref
 		if propRef == nil || propRef.Value == nil {
 			continue
 		}
 
-		fieldName := ToGoFieldName(propName)
-		fieldType, err := MapSchemaToGoType(propRef.Value)
+		// Fields ignored by encoding/json cannot be sent in requests
+		if IsJSONIgnored(propRef) {
+			continue
+		}
+
+		fieldName := GoFieldName(propName, propRef)
+		fieldType, err := g.formats.ResolvePropertyType(propName, propRef, HoistedTypeName(prop.Owner, propName), config.DomainPackage)
 		if err != nil {
 			return ServiceTemplateData{}, fmt.Errorf("failed to map field %s: %w", propName, err)
 		}
 
 		field := RequestField{
-			Name:    fieldName,
-			Type:    fieldType,
-			JsonTag: propName,
+			Name:       fieldName,
+			Type:       fieldType,
+			JsonTag:    propName,
+			EnumValues: EnumStringValues(propRef.Value),
 		}
 
-		// Check if required
-		for _, required := range schema.Required {
-			if required == propName {
-				requiredFields = append(requiredFields, field)
-				break
-			}
-		}
-
-		// Check for enum values
-		if len(propRef.Value.Enum) > 0 {
-			values := make([]string, 0, len(propRef.Value.Enum))
-			for _, enumVal := range propRef.Value.Enum {
-				if strVal, ok := enumVal.(string); ok {
-					values = append(values, strVal)
-				}
-			}
-
-			if len(values) > 0 {
-				enumFields = append(enumFields, EnumField{
-					Name:    fieldName,
-					Type:    fieldType,
-					JsonTag: propName,
-					Values:  values,
-				})
-			}
+		// Wrapped fields are only validated when they hold a value
+		wrapped := g.optional.Wraps(propName, propRef.Value, required)
+		if wrapped {
+			field.Type = g.optional.WrapType(fieldType, propRef.Value.Nullable, config.DomainPackage)
+			field.Provided = g.optional.ProvidedCheck("request." + fieldName)
 		}
 
-		// Check for min/max constraints
-		hasMin := propRef.Value.Min != nil
-		hasMax := propRef.Value.Max != nil
-
-		if hasMin || hasMax {
-			mmField := MinMaxField{
-				Name:    fieldName,
-				Type:    fieldType,
-				JsonTag: propName,
-			}
-
-			if hasMin {
-				mmField.Min = *propRef.Value.Min
-			}
-
-			if hasMax {
-				mmField.HasMax = true
-				mmField.Max = *propRef.Value.Max
-			}
-
-			minMaxFields = append(minMaxFields, mmField)
-		}
+		// Updates overwrite plain fields, so they are validated like creates
+		validation := fieldValidation(propName, propRef, Contains(required, propName), fieldType,
+			"r."+fieldName, g.optional, wrapped, config.DomainPackage)
+		hasChecks := validation.HasChecks()
 
 		// Skip ID field for create operation
 		if propName == "id" || propName == "ID" {
 			updateFields = append(updateFields, field)
+			if hasChecks {
+				updateValidations = append(updateValidations, validation)
+			}
 			continue
 		}
 
@@ -218,32 +239,38 @@ func (g *ServiceGenerator) prepareTemplateData(schemaName string) (ServiceTempla
 		// Add to appropriate operation fields
 		createFields = append(createFields, field)
 		updateFields = append(updateFields, field)
-
-		// Check if we need to import time package
-		if fieldType == "time.Time" || fieldType == "*time.Time" {
-			importTime = true
+		if hasChecks {
+			createValidations = append(createValidations, validation)
+			updateValidations = append(updateValidations, validation)
 		}
+
 	}
 
+	// Collect the packages referenced by the request field types
+	imports, testImports, importTime := g.formats.requestFieldImports(append(createFields, updateFields...))
+
 	// Prepare template data
 	data := ServiceTemplateData{
-		SchemaName:     schemaName,
-		VarName:        ToCamelCase(schemaName),
-		PackageName:    g.packageName,
-		ImportPath:     g.importPath,
-		HasCreateOp:    false,
-		HasGetOp:       false,
-		HasListOp:      false,
-		HasUpdateOp:    false,
-		HasDeleteOp:    false,
-		HasCreatedAt:   false,
-		HasUpdatedAt:   false,
-		CreateFields:   createFields,
-		UpdateFields:   updateFields,
-		RequiredFields: requiredFields,
-		EnumFields:     enumFields,
-		MinMaxFields:   minMaxFields,
-		ImportTime:     importTime,
+		SchemaName:        schemaName,
+		TypeName:          typeName,
+		VarName:           ToCamelCase(schemaName),
+		PackageName:       g.packageName,
+		ImportPath:        g.importPath,
+		Packages:          g.layout.Imports(g.importPath),
+		HasCreateOp:       false,
+		HasGetOp:          false,
+		HasListOp:         false,
+		HasUpdateOp:       false,
+		HasDeleteOp:       false,
+		HasCreatedAt:      false,
+		HasUpdatedAt:      false,
+		CreateFields:      createFields,
+		UpdateFields:      updateFields,
+		CreateValidations: createValidations,
+		UpdateValidations: updateValidations,
+		ImportTime:        importTime,
+		Imports:           imports,
+		TestImports:       testImports,
 	}
 
 	// Set operation flags based on OpenAPI spec
@@ -264,8 +291,8 @@ func (g *ServiceGenerator) prepareTemplateData(schemaName string) (ServiceTempla
 	}
 
 	// Check for timestamp fields
-	for propName, propRef := range schema.Properties {
-		if propRef != nil && propRef.Value != nil {
+	for propName, prop := range properties {
+		if prop.Ref != nil && prop.Ref.Value != nil {
 			if propName == "created_at" || propName == "createdAt" {
 				data.HasCreatedAt = true
 				data.ImportTime = true
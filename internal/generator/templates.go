@@ -0,0 +1,177 @@
+package generator
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/zeek-r/goapigen/internal/gosource"
+)
+
+// TemplateCache is a template file system that parses each template set
+// once and shares it between the generators reading from it, including
+// generators running concurrently. Pass it in place of the file system to
+// the generator constructors.
+type TemplateCache struct {
+	fs   fs.FS
+	mu   sync.Mutex
+	sets map[string]*templateSet
+}
+
+// templateSet is a template set parsed once
+type templateSet struct {
+	once sync.Once
+	tmpl *template.Template
+	err  error
+}
+
+// NewTemplateCache creates a cache of the templates of a file system
+func NewTemplateCache(templateFS fs.FS) *TemplateCache {
+	return &TemplateCache{fs: templateFS, sets: make(map[string]*templateSet)}
+}
+
+// Open opens a file of the underlying file system
+func (c *TemplateCache) Open(name string) (fs.File, error) {
+	return c.fs.Open(name)
+}
+
+// parse returns the template set of a key, parsing it on first use
+func (c *TemplateCache) parse(key string, funcs template.FuncMap, paths []string) (*template.Template, error) {
+	c.mu.Lock()
+	set, ok := c.sets[key]
+	if !ok {
+		set = &templateSet{}
+		c.sets[key] = set
+	}
+	c.mu.Unlock()
+
+	set.once.Do(func() {
+		set.tmpl, set.err = newTemplateSet(c.fs, funcs, paths)
+	})
+	return set.tmpl, set.err
+}
+
+// parseTemplates parses templates into a set named after the first of them,
+// as template.ParseFS does. Sets read through a TemplateCache are parsed
+// once per name and paths, so the set returned is shared: execute it, or
+// Clone it before adding templates. The name tells apart sets parsed from
+// the same paths with different functions.
+func parseTemplates(templateFS fs.FS, name string, funcs template.FuncMap, paths ...string) (*template.Template, error) {
+	if cache, ok := templateFS.(*TemplateCache); ok {
+		return cache.parse(name+":"+strings.Join(paths, ","), funcs, paths)
+	}
+	return newTemplateSet(templateFS, funcs, paths)
+}
+
+func newTemplateSet(templateFS fs.FS, funcs template.FuncMap, paths []string) (*template.Template, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no templates to parse")
+	}
+	tmpl := template.New(path.Base(paths[0]))
+	if funcs != nil {
+		tmpl.Funcs(funcs)
+	}
+	return tmpl.ParseFS(templateFS, paths...)
+}
+
+// RenderCache keeps the code rendered from each template for each schema,
+// operation or resource along with the data it was rendered from, so that
+// generating an edited spec again only renders and formats the code whose
+// data changed. Generators sharing a cache must use the same templates:
+// start a new cache when they change. It is safe for concurrent use.
+type RenderCache struct {
+	mu       sync.Mutex
+	entries  map[string]*renderedCode
+	rendered map[string]bool
+}
+
+// renderedCode is the code of a template for a subject
+type renderedCode struct {
+	fingerprint [sha256.Size]byte // Hash of the template data
+	code        string
+	used        bool // Rendered or reused since the last Flush
+}
+
+// NewRenderCache creates an empty render cache
+func NewRenderCache() *RenderCache {
+	return &RenderCache{entries: make(map[string]*renderedCode), rendered: make(map[string]bool)}
+}
+
+// Flush returns the subjects rendered since the last flush, rather than
+// reused, in order. Code that was neither rendered nor reused since then,
+// such as that of a deleted schema, is forgotten.
+func (c *RenderCache) Flush() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, entry := range c.entries {
+		if !entry.used {
+			delete(c.entries, key)
+		}
+		entry.used = false
+	}
+	subjects := make([]string, 0, len(c.rendered))
+	for subject := range c.rendered {
+		subjects = append(subjects, subject)
+	}
+	sort.Strings(subjects)
+	c.rendered = make(map[string]bool)
+	return subjects
+}
+
+// render returns the code of a template for a subject, calling render only
+// when data differs from the data of the cached code. A nil cache, or data
+// that cannot be encoded, always renders.
+func (c *RenderCache) render(templateName, subject string, data interface{}, render func() (string, error)) (string, error) {
+	if c == nil {
+		return render()
+	}
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return render()
+	}
+	key := templateName + " " + subject
+	fingerprint := sha256.Sum256(append([]byte(fmt.Sprintf("%T:", data)), encoded...))
+
+	c.mu.Lock()
+	if entry, ok := c.entries[key]; ok && entry.fingerprint == fingerprint {
+		entry.used = true
+		c.mu.Unlock()
+		return entry.code, nil
+	}
+	c.mu.Unlock()
+
+	code, err := render()
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = &renderedCode{fingerprint: fingerprint, code: code, used: true}
+	if subject != "" {
+		c.rendered[subject] = true
+	}
+	return code, nil
+}
+
+// formatGo formats Go code rendered by a template and fixes its imports,
+// grouping those of the generated module, importPath, last. The error of
+// code that does not parse names the template, the schema or operation it
+// was rendered for, if any, and the line.
+func formatGo(code []byte, importPath, templateName, subject string) (string, error) {
+	formatted, err := gosource.Format(code, importPath)
+	if err != nil {
+		if subject != "" {
+			templateName += " for " + subject
+		}
+		return "", fmt.Errorf("template %s rendered invalid Go: %w", templateName, err)
+	}
+	return string(formatted), nil
+}
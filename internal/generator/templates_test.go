@@ -0,0 +1,74 @@
+package generator
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestRenderCache(t *testing.T) {
+	cache := NewRenderCache()
+	renders := 0
+	render := func(templateName, subject string, data interface{}) string {
+		t.Helper()
+		code, err := cache.render(templateName, subject, data, func() (string, error) {
+			renders++
+			return subject + " rendered", nil
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return code
+	}
+
+	render("service.go.tmpl", "schema Pet", OperationData{SchemaName: "Pet"})
+	render("service.go.tmpl", "schema User", OperationData{SchemaName: "User"})
+	if got := cache.Flush(); !reflect.DeepEqual(got, []string{"schema Pet", "schema User"}) {
+		t.Errorf("Expected both schemas to be rendered, got %v", got)
+	}
+
+	// Unchanged data reuses the code, changed data renders it again
+	if code := render("service.go.tmpl", "schema Pet", OperationData{SchemaName: "Pet"}); code != "schema Pet rendered" {
+		t.Errorf("Expected the cached code, got %q", code)
+	}
+	render("service.go.tmpl", "schema User", OperationData{SchemaName: "User", HasRequestBody: true})
+	if renders != 3 {
+		t.Errorf("Expected 3 renders, got %d", renders)
+	}
+	if got := cache.Flush(); !reflect.DeepEqual(got, []string{"schema User"}) {
+		t.Errorf("Expected only User to be rendered again, got %v", got)
+	}
+
+	// Code left unused by a generation is forgotten
+	render("service.go.tmpl", "schema User", OperationData{SchemaName: "User", HasRequestBody: true})
+	cache.Flush()
+	render("service.go.tmpl", "schema Pet", OperationData{SchemaName: "Pet"})
+	if renders != 4 {
+		t.Errorf("Expected the code of Pet to be rendered again once forgotten, got %d renders", renders)
+	}
+
+	// Errors are not cached
+	_, err := cache.render("service.go.tmpl", "schema Tag", nil, func() (string, error) {
+		return "", errors.New("invalid template")
+	})
+	if err == nil {
+		t.Error("Expected the render error")
+	}
+	if got := cache.Flush(); !reflect.DeepEqual(got, []string{"schema Pet"}) {
+		t.Errorf("Expected only Pet to be rendered, got %v", got)
+	}
+}
+
+func TestRenderCache_Nil(t *testing.T) {
+	var cache *RenderCache
+	renders := 0
+	for i := 0; i < 2; i++ {
+		_, _ = cache.render("service.go.tmpl", "schema Pet", nil, func() (string, error) {
+			renders++
+			return "", nil
+		})
+	}
+	if renders != 2 {
+		t.Errorf("Expected a nil cache to render every time, got %d renders", renders)
+	}
+}
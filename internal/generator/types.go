@@ -2,193 +2,904 @@ package generator
 
 import (
 	"bytes"
-	"embed"
 	"fmt"
+	"io/fs"
+	"path"
 	"sort"
 	"strings"
-	"text/template"
 
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/zeek-r/goapigen/internal/config"
 	"github.com/zeek-r/goapigen/internal/parser"
+	"github.com/zeek-r/goapigen/internal/workpool"
 )
 
+// OptionalStrategy controls how optional and nullable properties are
+// represented in generated Go types
+type OptionalStrategy string
+
+const (
+	// OptionalValue generates plain values, so absent and zero values look alike
+	OptionalValue OptionalStrategy = "value"
+	// OptionalPointer generates pointers for optional and nullable properties
+	OptionalPointer OptionalStrategy = "pointer"
+	// OptionalGeneric generates the Optional[T] and Nullable[T] wrapper types
+	OptionalGeneric OptionalStrategy = "generic"
+)
+
+// ParseOptionalStrategy converts a strategy name into an OptionalStrategy
+func ParseOptionalStrategy(name string) (OptionalStrategy, error) {
+	switch strategy := OptionalStrategy(name); strategy {
+	case OptionalValue, OptionalPointer, OptionalGeneric:
+		return strategy, nil
+	}
+	return "", fmt.Errorf("unknown optional strategy %q (expected value, pointer or generic)", name)
+}
+
+// Wraps reports whether a property is wrapped so that absent or null values
+// can be told apart from zero values. Identifiers and timestamps are managed
+// by the generated code and always keep their plain type.
+func (s OptionalStrategy) Wraps(propName string, schema *openapi3.Schema, required []string) bool {
+	if s != OptionalPointer && s != OptionalGeneric {
+		return false
+	}
+	if IsManagedProperty(propName) {
+		return false
+	}
+	return !Contains(required, propName) || schema.Nullable
+}
+
+// WrapType returns the wrapped form of goType, qualifying the generic
+// wrappers with qualifier when it is not empty. Slices and maps can already
+// be nil, so the pointer strategy leaves them unwrapped.
+func (s OptionalStrategy) WrapType(goType string, nullable bool, qualifier string) string {
+	switch {
+	case s == OptionalPointer && isNilableType(goType):
+		return goType
+	case s == OptionalPointer:
+		return "*" + goType
+	case nullable:
+		return qualifyTypeName("Nullable", qualifier) + "[" + goType + "]"
+	default:
+		return qualifyTypeName("Optional", qualifier) + "[" + goType + "]"
+	}
+}
+
+// ProvidedCheck returns a condition reporting whether the wrapped field expr
+// was present in the decoded payload
+func (s OptionalStrategy) ProvidedCheck(expr string) string {
+	if s == OptionalPointer {
+		return expr + " != nil"
+	}
+	return expr + ".Set"
+}
+
+// ValueCheck returns a condition reporting whether the wrapped field expr
+// holds a non-null value
+func (s OptionalStrategy) ValueCheck(expr string) string {
+	if s == OptionalPointer {
+		return expr + " != nil"
+	}
+	return expr + ".HasValue()"
+}
+
+// ValueOf returns an expression for the value held by the wrapped field
+// expr, whose type before wrapping is goType
+func (s OptionalStrategy) ValueOf(expr, goType string) string {
+	switch {
+	case s == OptionalPointer && isNilableType(goType):
+		return expr
+	case s == OptionalPointer:
+		return "(*" + expr + ")"
+	}
+	return expr + ".Value"
+}
+
+// isNilableType reports whether values of goType can be nil without a pointer
+func isNilableType(goType string) bool {
+	return strings.HasPrefix(goType, "[]") || strings.HasPrefix(goType, "map[")
+}
+
+// omitTags adds the options that leave absent values out of encoded
+// JSON and BSON documents to the tags of a wrapped field
+func (s OptionalStrategy) omitTags(propName, tags string) string {
+	// Wrapper structs rely on IsZero, which json only consults for omitzero
+	jsonOption := "omitempty"
+	if s == OptionalGeneric {
+		jsonOption = "omitzero"
+	}
+
+	tags = strings.Replace(tags, fmt.Sprintf(`json:"%s"`, propName), fmt.Sprintf(`json:"%s,%s"`, propName, jsonOption), 1)
+	return strings.Replace(tags, fmt.Sprintf(`bson:"%s"`, propName), fmt.Sprintf(`bson:"%s,omitempty"`, propName), 1)
+}
+
 // TypeGenerator generates Go type definitions from OpenAPI schemas
 type TypeGenerator struct {
 	parser      *parser.OpenAPIParser
 	packageName string
-	templateFS  embed.FS
+	importPath  string
+	templateFS  fs.FS
+	optional    OptionalStrategy
+	formats     *FormatRegistry
+	workers     int
+	cache       *RenderCache
+	mongo       bool
 }
 
 // TypeField represents a field in a struct type
 type TypeField struct {
-	Name    string
-	Type    string
-	Tags    string
-	Comment string
+	Name       string
+	Type       string
+	Tags       string
+	Comment    string
+	Validation FieldValidation // Checks rendered into the Validate method
 }
 
 // TypeDefinition represents a Go type definition
 type TypeDefinition struct {
-	Name   string
-	Fields []TypeField
+	Name     string
+	Fields   []TypeField
+	Embedded []TypeField      // Embedded structs from allOf $ref members
+	BaseType string           // Underlying type for non-struct definitions (e.g. hoisted primitive variants)
+	Alias    bool             // BaseType is aliased rather than defined, e.g. for x-go-type
+	Union    *UnionDefinition // Set for oneOf/anyOf compositions
+	Enum     *EnumDefinition  // Set for string enums
+
+	Validations []FieldValidation // Checks of struct fields, in field order, or of the value of a named type
+	Validated   bool              // Named non-struct type with a Validate method
+}
+
+// EnumValue represents a single constant of a generated enum type
+type EnumValue struct {
+	Name  string
+	Value string
+}
+
+// EnumDefinition describes a string enum rendered as a named type with constants
+type EnumDefinition struct {
+	Values []EnumValue
+}
+
+// UnionVariant represents a single alternative of a oneOf/anyOf composition
+type UnionVariant struct {
+	TypeName            string
+	DiscriminatorValues []string
+}
+
+// UnionDefinition describes a oneOf/anyOf composition rendered as a tagged union
+type UnionDefinition struct {
+	Kind          string // "oneOf" or "anyOf"
+	InterfaceName string
+	Discriminator string // JSON property used to select the variant, empty if none
+	Variants      []UnionVariant
 }
 
 // TypeTemplateData represents the data needed for the type template
 type TypeTemplateData struct {
-	HasTimeType       bool
-	AdditionalImports []string
-	Types             []TypeDefinition
+	HasStrictDecode bool
+	UseMongo        bool     // Whether unions get the BSON methods the Mongo repositories store them with
+	Imports         []string // Import specs, e.g. "time" or `uuid "github.com/gofrs/uuid/v5"`
+	Types           []TypeDefinition
+}
+
+// HelperTemplateData represents the data needed for the helper templates
+type HelperTemplateData struct {
+	UseMongo bool // Whether the helper types get BSON methods
 }
 
 // NewTypeGenerator creates a new type generator with the given parser
-func NewTypeGenerator(parser *parser.OpenAPIParser, packageName string, templateFS embed.FS) *TypeGenerator {
+func NewTypeGenerator(parser *parser.OpenAPIParser, packageName string, templateFS fs.FS) *TypeGenerator {
 	return &TypeGenerator{
 		parser:      parser,
 		packageName: packageName,
 		templateFS:  templateFS,
+		optional:    OptionalValue,
+		formats:     specFormatRegistry(parser),
 	}
 }
 
+// SetOptionalStrategy sets how optional and nullable properties are generated
+func (g *TypeGenerator) SetOptionalStrategy(strategy OptionalStrategy) {
+	g.optional = strategy
+}
+
+// SetImportPath sets the import path of the generated module, whose
+// imports are grouped apart in the types files
+func (g *TypeGenerator) SetImportPath(importPath string) {
+	g.importPath = importPath
+}
+
+// SetMongo sets whether the types are stored by Mongo repositories, which
+// need BSON methods on unions and helper types. Without them the domain
+// package does not depend on the Mongo driver.
+func (g *TypeGenerator) SetMongo(enabled bool) {
+	g.mongo = enabled
+}
+
+// SetFormatRegistry sets the format mappings used to resolve Go types
+func (g *TypeGenerator) SetFormatRegistry(formats *FormatRegistry) {
+	g.formats = formats
+}
+
+// SetWorkers sets how many schemas are generated concurrently, one per CPU
+// by default
+func (g *TypeGenerator) SetWorkers(workers int) {
+	g.workers = workers
+}
+
+// SetRenderCache sets the cache of the code rendered by an earlier
+// generation, so that only the types files whose schemas changed are
+// rendered
+func (g *TypeGenerator) SetRenderCache(cache *RenderCache) {
+	g.cache = cache
+}
+
+// GenerateHelpers generates the helper types the domain types depend on,
+// such as Optional[T] for the generic optional strategy or Date for "date"
+// formats, along with the helpers of generated Validate methods, keyed by
+// file name
+func (g *TypeGenerator) GenerateHelpers() (map[string]string, error) {
+	typeDefinitions, err := g.buildTypeDefinitions()
+	if err != nil {
+		return nil, err
+	}
+
+	templates := g.formats.helperTemplates(definitionTypes(typeDefinitions))
+	templates["Validation"] = config.DomainValidationTemplate
+	if g.optional == OptionalGeneric {
+		templates["Optional"] = config.DomainOptionalTemplate
+	}
+
+	helpers := make(map[string]string, len(templates))
+	for name, templatePath := range templates {
+		tmpl, err := parseTemplates(g.templateFS, "helper", nil, templatePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s helper template: %w", name, err)
+		}
+
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, HelperTemplateData{UseMongo: g.mongo}); err != nil {
+			return nil, fmt.Errorf("failed to execute %s helper template: %w", name, err)
+		}
+		code, err := formatGo(buf.Bytes(), g.importPath, path.Base(templatePath), "")
+		if err != nil {
+			return nil, err
+		}
+		helpers[strings.TrimSuffix(path.Base(templatePath), ".tmpl")] = code
+	}
+
+	return helpers, nil
+}
+
 // GenerateTypes generates Go type definitions for all schemas in the OpenAPI spec
 func (g *TypeGenerator) GenerateTypes() (string, error) {
+	typeDefinitions, err := g.buildTypeDefinitions()
+	if err != nil {
+		return "", err
+	}
+
+	return g.renderTypes(typeDefinitions, needsStrictDecode(typeDefinitions), "")
+}
+
+// GenerateTypeFiles generates the Go type definitions with one file per
+// schema, keyed by file name. Types hoisted out of a schema are generated
+// into the schema's file, and the decodeStrict helper shared by unions
+// without a discriminator into its own file.
+func (g *TypeGenerator) GenerateTypeFiles() (map[string]string, error) {
 	schemas := g.parser.GetSchemas()
+	schemaNames := sortedSchemaNames(schemas)
 
-	// Sort schema names for consistent output
-	schemaNames := make([]string, 0, len(schemas))
-	for name := range schemas {
-		schemaNames = append(schemaNames, name)
-	}
-	sort.Strings(schemaNames)
+	// Schemas are rendered concurrently, then checked in order
+	typeDefs, buildErrs := g.buildSchemaTypes(schemas, schemaNames)
+	codes := make([]string, len(schemaNames))
+	renderErrs := make([]error, len(schemaNames))
+	_ = workpool.Run(g.workers, len(schemaNames), func(i int) error {
+		if buildErrs[i] == nil {
+			codes[i], renderErrs[i] = g.renderTypes(typeDefs[i], false, "schema "+schemaNames[i])
+		}
+		return nil
+	})
 
-	// Collect imports
-	imports := g.collectImports(schemas)
-	hasTimeType := false
-	additionalImports := []string{}
+	files := make(map[string]string)
+	strictDecode := false
+	declared := make(declaredTypes)
+	for i, name := range schemaNames {
+		if buildErrs[i] != nil {
+			return nil, buildErrs[i]
+		}
+		if err := declared.declare(name, typeDefs[i]); err != nil {
+			return nil, err
+		}
+		if renderErrs[i] != nil {
+			return nil, fmt.Errorf("failed to render type for %s: %w", name, renderErrs[i])
+		}
 
-	for _, imp := range imports {
-		if imp == "time" {
-			hasTimeType = true
-		} else {
-			additionalImports = append(additionalImports, fmt.Sprintf("%q", imp))
+		filename := TypeFileName(GoTypeName(name, schemas[name]))
+		if _, exists := files[filename]; exists {
+			return nil, fmt.Errorf("schema %s would overwrite %s", name, filename)
 		}
+		files[filename] = codes[i]
+		strictDecode = strictDecode || needsStrictDecode(typeDefs[i])
 	}
 
-	// Build type definitions
-	typeDefinitions := make([]TypeDefinition, 0, len(schemaNames))
-	for _, name := range schemaNames {
-		schema := schemas[name]
-		typeDef, err := g.buildTypeDefinition(name, schema)
+	if strictDecode {
+		code, err := g.renderTypes(nil, true, "")
 		if err != nil {
-			return "", fmt.Errorf("failed to generate type for %s: %w", name, err)
+			return nil, err
 		}
-		typeDefinitions = append(typeDefinitions, typeDef)
+		files[config.DecodeFile] = code
 	}
 
+	return files, nil
+}
+
+// renderTypes renders type definitions with the domain types template,
+// along with the decodeStrict helper when strictDecode is set. The subject
+// names the schema rendered in errors, if any.
+func (g *TypeGenerator) renderTypes(typeDefinitions []TypeDefinition, strictDecode bool, subject string) (string, error) {
+	// Collect imports
+	imports := g.collectImports(typeDefinitions)
+
+	// Unions and enums need JSON (un)marshalling support, and unions BSON
+	// support to be stored by the Mongo repositories
+	for _, typeDef := range typeDefinitions {
+		if typeDef.Enum != nil || typeDef.Union != nil {
+			imports = appendUnique(imports, `"encoding/json"`, `"fmt"`)
+		}
+		if typeDef.Union != nil && g.mongo {
+			imports = appendUnique(imports, `"go.mongodb.org/mongo-driver/bson"`, `"go.mongodb.org/mongo-driver/bson/bsontype"`)
+		}
+	}
+	if strictDecode {
+		imports = appendUnique(imports, `"bytes"`, `"encoding/json"`)
+	}
+	sort.Strings(imports)
+
 	// Template data
 	data := TypeTemplateData{
-		HasTimeType:       hasTimeType,
-		AdditionalImports: additionalImports,
-		Types:             typeDefinitions,
+		HasStrictDecode: strictDecode,
+		UseMongo:        g.mongo,
+		Imports:         imports,
+		Types:           typeDefinitions,
 	}
 
 	// Load and execute template
-	tmpl, err := template.ParseFS(g.templateFS, config.DomainTypesTemplate)
-	if err != nil {
-		return "", fmt.Errorf("failed to parse domain types template: %w", err)
+	return g.cache.render(path.Base(config.DomainTypesTemplate), subject, data, func() (string, error) {
+		tmpl, err := parseTemplates(g.templateFS, "types", nil, config.DomainTypesTemplate, config.ValidateTemplate)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse domain types template: %w", err)
+		}
+
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return "", fmt.Errorf("failed to execute domain types template: %w", err)
+		}
+		return formatGo(buf.Bytes(), g.importPath, path.Base(config.DomainTypesTemplate), subject)
+	})
+}
+
+// needsStrictDecode reports whether any union lacks a discriminator and is
+// decoded by trying each variant with decodeStrict
+func needsStrictDecode(typeDefinitions []TypeDefinition) bool {
+	for _, typeDef := range typeDefinitions {
+		if typeDef.Union != nil && typeDef.Union.Discriminator == "" {
+			return true
+		}
 	}
+	return false
+}
 
-	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, data); err != nil {
-		return "", fmt.Errorf("failed to execute domain types template: %w", err)
+// buildTypeDefinitions builds the type definitions for all schemas in the
+// OpenAPI spec, sorted by schema name
+func (g *TypeGenerator) buildTypeDefinitions() ([]TypeDefinition, error) {
+	schemas := g.parser.GetSchemas()
+	schemaNames := sortedSchemaNames(schemas)
+	typeDefs, errs := g.buildSchemaTypes(schemas, schemaNames)
+
+	typeDefinitions := make([]TypeDefinition, 0, len(schemaNames))
+	declared := make(declaredTypes)
+	for i, name := range schemaNames {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		if err := declared.declare(name, typeDefs[i]); err != nil {
+			return nil, err
+		}
+		typeDefinitions = append(typeDefinitions, typeDefs[i]...)
 	}
 
-	return buf.String(), nil
+	return typeDefinitions, nil
 }
 
-// collectImports determines the necessary import statements
-func (g *TypeGenerator) collectImports(schemas map[string]*openapi3.Schema) []string {
-	importMap := make(map[string]bool)
+// buildSchemaTypes builds the type definitions of schemas concurrently,
+// returning the definitions and the error of each schema by index
+func (g *TypeGenerator) buildSchemaTypes(schemas map[string]*openapi3.Schema, schemaNames []string) ([][]TypeDefinition, []error) {
+	typeDefs := make([][]TypeDefinition, len(schemaNames))
+	errs := make([]error, len(schemaNames))
+	_ = workpool.Run(g.workers, len(schemaNames), func(i int) error {
+		name := schemaNames[i]
+		typeDefs[i], errs[i] = g.buildTypeDefinition(GoTypeName(name, schemas[name]), schemas[name])
+		if errs[i] != nil {
+			errs[i] = fmt.Errorf("failed to generate type for %s: %w", name, errs[i])
+		}
+		return nil
+	})
+	return typeDefs, errs
+}
 
-	for _, schema := range schemas {
-		for _, prop := range schema.Properties {
-			if prop.Value == nil {
-				continue
-			}
+// declaredTypes maps the generated type names to the schema declaring them.
+// Every type lives in the domain package, so a name hoisted out of one
+// schema (Pet.owner becomes PetOwner) must not be taken by another.
+type declaredTypes map[string]string
 
-			// Check for time.Time
-			if prop.Value.Type == "string" && prop.Value.Format == "date-time" {
-				importMap["time"] = true
+// declare records the types generated for a schema, failing on a name
+// already declared by a previous one
+func (d declaredTypes) declare(schemaName string, typeDefs []TypeDefinition) error {
+	for _, typeDef := range typeDefs {
+		if other, exists := d[typeDef.Name]; exists {
+			if other == schemaName {
+				return fmt.Errorf("schema %s declares type %s twice", schemaName, typeDef.Name)
 			}
-
-			// Add other imports as needed
+			return fmt.Errorf("schema %s declares type %s, already declared by schema %s (set x-go-name on a component schema to rename it)",
+				schemaName, typeDef.Name, other)
 		}
+		d[typeDef.Name] = schemaName
 	}
+	return nil
+}
 
-	imports := make([]string, 0, len(importMap))
-	for imp := range importMap {
-		imports = append(imports, imp)
+// sortedSchemaNames returns the schema names in a consistent order
+func sortedSchemaNames(schemas map[string]*openapi3.Schema) []string {
+	schemaNames := make([]string, 0, len(schemas))
+	for name := range schemas {
+		schemaNames = append(schemaNames, name)
+	}
+	sort.Strings(schemaNames)
+	return schemaNames
+}
+
+// reservedDomainFiles are the shared files of the domain package
+var reservedDomainFiles = map[string]bool{
+	config.TypesFile:      true,
+	config.ErrorsFile:     true,
+	config.OptionalFile:   true,
+	config.DateFile:       true,
+	config.URIFile:        true,
+	config.DurationFile:   true,
+	config.DecodeFile:     true,
+	config.ValidationFile: true,
+}
+
+// IsSharedDomainFile reports whether a domain package file holds shared
+// code such as types.go or the helper types, rather than the types of a
+// single schema generated with GenerateTypeFiles
+func IsSharedDomainFile(filename string) bool {
+	return reservedDomainFiles[filename]
+}
+
+// buildSuffixes are file name suffixes the go tool treats as build
+// constraints (_test, GOOS and GOARCH values)
+var buildSuffixes = map[string]bool{
+	"test": true, "aix": true, "android": true, "darwin": true, "dragonfly": true,
+	"freebsd": true, "hurd": true, "illumos": true, "ios": true, "js": true,
+	"linux": true, "netbsd": true, "openbsd": true, "plan9": true, "solaris": true,
+	"wasip1": true, "windows": true, "zos": true, "386": true, "amd64": true,
+	"arm": true, "arm64": true, "loong64": true, "mips": true, "mipsle": true,
+	"mips64": true, "mips64le": true, "ppc64": true, "ppc64le": true,
+	"riscv64": true, "s390x": true, "wasm": true,
+}
+
+// TypeFileName returns the file a type is generated into when types are
+// split per schema, e.g. PetOwner -> pet_owner.go. Names clashing with the
+// shared domain files or ending in a build constraint suffix get a _type
+// suffix.
+func TypeFileName(typeName string) string {
+	base := ToSnakeCase(typeName)
+	suffix := base[strings.LastIndex(base, "_")+1:]
+	if reservedDomainFiles[base+".go"] || (strings.Contains(base, "_") && buildSuffixes[suffix]) {
+		base += "_type"
+	}
+	return base + ".go"
+}
+
+// collectImports determines the import specs of the packages referenced by
+// the generated types
+func (g *TypeGenerator) collectImports(typeDefinitions []TypeDefinition) []string {
+	var imports []string
+	for _, goType := range definitionTypes(typeDefinitions) {
+		imports = appendUnique(imports, g.formats.TypeImports(goType)...)
 	}
 	sort.Strings(imports)
 	return imports
 }
 
-// buildTypeDefinition builds a TypeDefinition from an OpenAPI schema
-func (g *TypeGenerator) buildTypeDefinition(name string, schema *openapi3.Schema) (TypeDefinition, error) {
-	var typeDef TypeDefinition
-	typeDef.Name = name
+// definitionTypes returns the Go type expressions used by type definitions
+func definitionTypes(typeDefinitions []TypeDefinition) []string {
+	var goTypes []string
+	for _, typeDef := range typeDefinitions {
+		if typeDef.BaseType != "" {
+			goTypes = append(goTypes, typeDef.BaseType)
+		}
+		for _, field := range typeDef.Embedded {
+			goTypes = append(goTypes, field.Type)
+		}
+		for _, field := range typeDef.Fields {
+			goTypes = append(goTypes, field.Type)
+		}
+	}
+	return goTypes
+}
+
+// buildTypeDefinition builds the TypeDefinition for an OpenAPI schema.
+// The first returned definition is the schema's own type; any further
+// definitions are types hoisted out of inline schemas (nested objects,
+// composition members) so that every generated type has a name.
+func (g *TypeGenerator) buildTypeDefinition(name string, schema *openapi3.Schema) ([]TypeDefinition, error) {
+	// Schemas mapped to an existing Go type become aliases of it
+	if goType, ok := goTypeExtension(schema); ok {
+		return []TypeDefinition{{Name: name, BaseType: goType, Alias: true}}, nil
+	}
+
+	if len(schema.OneOf) > 0 || len(schema.AnyOf) > 0 {
+		return g.buildUnionDefinition(name, schema)
+	}
 
-	// Build struct fields
-	if schema.Type == "object" && schema.Properties != nil {
-		// Get required fields
-		requiredFields := make(map[string]bool)
-		for _, req := range schema.Required {
-			requiredFields[req] = true
+	if IsStringEnum(schema) {
+		return []TypeDefinition{buildEnumDefinition(name, schema)}, nil
+	}
+
+	if !isStructSchema(schema) {
+		return g.buildNamedTypeDefinition(name, schema)
+	}
+
+	typeDef := TypeDefinition{Name: name}
+
+	// Merge own properties with those of allOf members
+	properties, required, members := flattenAllOf(schema)
+	embedded := make([]TypeField, 0, len(members))
+	for _, member := range members {
+		field := TypeField{
+			Type: refTypeName(member),
+			Tags: `bson:",inline"`,
 		}
+		if hasValidateMethod(member) {
+			field.Validation.Nested = "v." + field.Type
+		}
+		embedded = append(embedded, field)
+	}
+	typeDef.Embedded = embedded
+
+	fields, hoisted, err := g.buildFields(name, properties, required)
+	if err != nil {
+		return nil, err
+	}
+	typeDef.Fields = fields
 
-		// Sort property names for consistent output
-		propNames := make([]string, 0, len(schema.Properties))
-		for propName := range schema.Properties {
-			propNames = append(propNames, propName)
+	for _, field := range append(embedded, fields...) {
+		if field.Validation.HasChecks() {
+			typeDef.Validations = append(typeDef.Validations, field.Validation)
 		}
-		sort.Strings(propNames)
+	}
 
-		// Process each property
-		for _, propName := range propNames {
-			prop := schema.Properties[propName]
-			if prop.Value == nil {
-				continue
-			}
+	return append([]TypeDefinition{typeDef}, hoisted...), nil
+}
 
-			// Get Go type for property
-			goType, err := g.mapToGoType(prop.Value, true)
-			if err != nil {
-				return typeDef, fmt.Errorf("failed to map property %s to Go type: %w", propName, err)
+// buildNamedTypeDefinition builds a named non-struct type such as
+// `type Tags []Tag` for primitive, array and map schemas
+func (g *TypeGenerator) buildNamedTypeDefinition(name string, schema *openapi3.Schema) ([]TypeDefinition, error) {
+	ref := &openapi3.SchemaRef{Value: schema}
+
+	baseType, err := g.formats.ResolveGoType(ref, name, "")
+	if err != nil {
+		return nil, err
+	}
+
+	hoisted, err := g.collectHoisted(ref, name)
+	if err != nil {
+		return nil, err
+	}
+
+	typeDef := TypeDefinition{Name: name, BaseType: baseType, Validated: hasNamedValidate(schema)}
+	if typeDef.Validated {
+		// Check the value as a field without a name, so nested failures are
+		// reported under the name of the field holding it
+		expr := "v"
+		if baseType == "string" {
+			expr = "string(v)"
+		}
+		validation := fieldValidation("", ref, false, baseType, expr, OptionalValue, false, "")
+		if validation.HasChecks() {
+			typeDef.Validations = []FieldValidation{validation}
+		}
+	}
+
+	return append([]TypeDefinition{typeDef}, hoisted...), nil
+}
+
+// buildEnumDefinition builds a named string type with one constant per enum value
+func buildEnumDefinition(name string, schema *openapi3.Schema) TypeDefinition {
+	enum := &EnumDefinition{}
+	seen := make(map[string]bool)
+
+	for i, value := range EnumStringValues(schema) {
+		constName := EnumConstName(name, value)
+		// Values that only differ in punctuation or case would collide, and so
+		// may the numbered name of one with the name of another value
+		base := constName
+		for n := i + 1; seen[constName]; n++ {
+			constName = fmt.Sprintf("%s%d", base, n)
+		}
+		seen[constName] = true
+
+		enum.Values = append(enum.Values, EnumValue{Name: constName, Value: value})
+	}
+
+	return TypeDefinition{Name: name, BaseType: "string", Enum: enum}
+}
+
+// collectHoisted builds the named types for inline object schemas reachable
+// from ref, mirroring the names chosen by ResolveGoType
+func (g *TypeGenerator) collectHoisted(ref *openapi3.SchemaRef, nameHint string) ([]TypeDefinition, error) {
+	if ref == nil || ref.Value == nil || ref.Ref != "" {
+		return nil, nil
+	}
+
+	schema := ref.Value
+	switch {
+	case IsHoistedSchema(schema):
+		return g.buildTypeDefinition(nameHint, schema)
+	case schema.Type == "array":
+		return g.collectHoisted(schema.Items, nameHint+"Item")
+	case schema.Type == "object":
+		return g.collectHoisted(schema.AdditionalProperties.Schema, nameHint+"Value")
+	}
+
+	return nil, nil
+}
+
+// isStructSchema reports whether a schema is generated as a Go struct
+func isStructSchema(schema *openapi3.Schema) bool {
+	if len(schema.Properties) > 0 || len(schema.AllOf) > 0 {
+		return true
+	}
+	if schema.Type != "object" {
+		return false
+	}
+
+	// Free-form maps are generated as map types
+	additional := schema.AdditionalProperties
+	return additional.Schema == nil && (additional.Has == nil || !*additional.Has)
+}
+
+// flattenAllOf merges the properties of a schema and its inline allOf members.
+// Referenced allOf members are returned separately, as they are embedded
+// into the generated struct rather than merged into it.
+func flattenAllOf(schema *openapi3.Schema) (openapi3.Schemas, []string, []*openapi3.SchemaRef) {
+	properties := make(openapi3.Schemas)
+	required := []string{}
+	embedded := []*openapi3.SchemaRef{}
+
+	for _, member := range schema.AllOf {
+		if member == nil {
+			continue
+		}
+		if member.Ref != "" {
+			embedded = append(embedded, member)
+			continue
+		}
+		if member.Value == nil {
+			continue
+		}
+
+		memberProps, memberRequired, memberEmbedded := flattenAllOf(member.Value)
+		for propName, prop := range memberProps {
+			properties[propName] = prop
+		}
+		required = appendUnique(required, memberRequired...)
+		embedded = append(embedded, memberEmbedded...)
+	}
+
+	for propName, prop := range schema.Properties {
+		properties[propName] = prop
+	}
+	required = appendUnique(required, schema.Required...)
+
+	return properties, required, embedded
+}
+
+// SchemaProperty is a property of a schema, flattened through allOf
+type SchemaProperty struct {
+	Ref *openapi3.SchemaRef
+
+	// Owner is the type the property is declared on, which names the
+	// types hoisted out of its inline schemas
+	Owner string
+}
+
+// SchemaProperties returns every property of a schema as its generated type
+// exposes it, including those promoted from referenced allOf members, along
+// with the names of the required ones
+func SchemaProperties(typeName string, schema *openapi3.Schema) (map[string]SchemaProperty, []string) {
+	properties := make(map[string]SchemaProperty)
+	required := []string{}
+
+	ownProps, ownRequired, embedded := flattenAllOf(schema)
+	for _, member := range embedded {
+		if member.Value == nil {
+			continue
+		}
+		memberProps, memberRequired := SchemaProperties(refTypeName(member), member.Value)
+		for propName, prop := range memberProps {
+			properties[propName] = prop
+		}
+		required = appendUnique(required, memberRequired...)
+	}
+
+	// Own properties shadow promoted ones, as in the generated struct
+	for propName, prop := range ownProps {
+		properties[propName] = SchemaProperty{Ref: prop, Owner: typeName}
+	}
+	required = appendUnique(required, ownRequired...)
+
+	return properties, required
+}
+
+// sortedPropertyNames returns the property names in a stable order
+func sortedPropertyNames(properties map[string]SchemaProperty) []string {
+	names := make([]string, 0, len(properties))
+	for propName := range properties {
+		names = append(names, propName)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// buildFields builds struct fields for a set of schema properties, along with
+// the types hoisted out of inline property schemas
+func (g *TypeGenerator) buildFields(parent string, properties openapi3.Schemas, required []string) ([]TypeField, []TypeDefinition, error) {
+	var fields []TypeField
+	var hoisted []TypeDefinition
+
+	// Sort property names for consistent output
+	propNames := make([]string, 0, len(properties))
+	for propName := range properties {
+		propNames = append(propNames, propName)
+	}
+	sort.Strings(propNames)
+
+	// Process each property
+	for _, propName := range propNames {
+		prop := properties[propName]
+		if prop == nil || prop.Value == nil {
+			continue
+		}
+
+		// Get Go type for property, hoisting inline objects into named types
+		nameHint := HoistedTypeName(parent, propName)
+		goType, err := g.formats.ResolvePropertyType(propName, prop, nameHint, "")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to map property %s to Go type: %w", propName, err)
+		}
+
+		nested, err := g.collectHoisted(prop, nameHint)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to build type for property %s: %w", propName, err)
+		}
+		hoisted = append(hoisted, nested...)
+
+		// Format field name properly
+		fieldName := formatFieldName(propName)
+		if goName, ok := propertyGoName(prop); ok {
+			fieldName = goName
+		}
+
+		// Build tags
+		tags := g.generateFieldTags(propName, prop.Value, required)
+
+		// Track absence and null for optional and nullable properties
+		wrapped := g.optional.Wraps(propName, prop.Value, required)
+		validation := fieldValidation(propName, prop, Contains(required, propName), goType, "v."+fieldName, g.optional, wrapped, "")
+		if wrapped {
+			goType = g.optional.WrapType(goType, prop.Value.Nullable, "")
+			if !Contains(required, propName) {
+				tags = g.optional.omitTags(propName, tags)
 			}
+		}
+		tags = applyTagExtensions(propName, prop, tags)
 
-			// Format field name properly
-			fieldName := formatFieldName(propName)
+		// Add comment if available
+		comment := "//"
+		if prop.Value.Description != "" {
+			comment = "// " + prop.Value.Description
+		}
 
-			// Build tags
-			tags := g.generateFieldTags(propName, prop.Value, schema.Required)
+		// Add field to type definition
+		field := TypeField{
+			Name:    fieldName,
+			Type:    goType,
+			Tags:    tags,
+			Comment: comment,
+		}
+		if !IsJSONIgnored(prop) {
+			field.Validation = validation
+		}
+		fields = append(fields, field)
+	}
+
+	return fields, hoisted, nil
+}
+
+// buildUnionDefinition builds a tagged union for a oneOf/anyOf schema.
+// Inline variants are hoisted into named types so they can implement the
+// union's sealed interface.
+func (g *TypeGenerator) buildUnionDefinition(name string, schema *openapi3.Schema) ([]TypeDefinition, error) {
+	kind, members := "oneOf", schema.OneOf
+	if len(members) == 0 {
+		kind, members = "anyOf", schema.AnyOf
+	}
+
+	union := &UnionDefinition{
+		Kind:          kind,
+		InterfaceName: name + "Variant",
+	}
 
-			// Add comment if available
-			comment := "//"
-			if prop.Value.Description != "" {
-				comment = "// " + prop.Value.Description
+	// Invert the discriminator mapping: schema name -> discriminator values
+	mappedValues := make(map[string][]string)
+	if schema.Discriminator != nil {
+		union.Discriminator = schema.Discriminator.PropertyName
+
+		values := make([]string, 0, len(schema.Discriminator.Mapping))
+		for value := range schema.Discriminator.Mapping {
+			values = append(values, value)
+		}
+		sort.Strings(values)
+		for _, value := range values {
+			target := SchemaRefName(schema.Discriminator.Mapping[value])
+			mappedValues[target] = append(mappedValues[target], value)
+		}
+	}
+
+	definitions := []TypeDefinition{{Name: name, Union: union}}
+
+	for i, member := range members {
+		if member == nil {
+			continue
+		}
+
+		var variantName, schemaName string
+		if member.Ref != "" {
+			variantName, schemaName = refTypeName(member), SchemaRefName(member.Ref)
+		} else {
+			if member.Value == nil {
+				continue
+			}
+			variantName = fmt.Sprintf("%sOption%d", name, i+1)
+			schemaName = variantName
+
+			hoisted, err := g.buildTypeDefinition(variantName, member.Value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to build %s variant %d: %w", kind, i+1, err)
 			}
+			definitions = append(definitions, hoisted...)
+		}
 
-			// Add field to type definition
-			typeDef.Fields = append(typeDef.Fields, TypeField{
-				Name:    fieldName,
-				Type:    goType,
-				Tags:    tags,
-				Comment: comment,
-			})
+		variant := UnionVariant{TypeName: variantName}
+		if union.Discriminator != "" {
+			variant.DiscriminatorValues = mappedValues[schemaName]
+			if len(variant.DiscriminatorValues) == 0 {
+				// Without an explicit mapping the schema name is the discriminator
+				// value, which payloads cannot know for an inline variant
+				if member.Ref == "" {
+					return nil, fmt.Errorf("inline %s variant %d has no %s value: map one to %s in the discriminator mapping or reference a component schema",
+						kind, i+1, union.Discriminator, variantName)
+				}
+				variant.DiscriminatorValues = []string{schemaName}
+			}
 		}
+		union.Variants = append(union.Variants, variant)
 	}
 
-	return typeDef, nil
+	return definitions, nil
 }
 
 // generateTypeDefinition generates a Go type definition for a single schema
@@ -279,7 +990,7 @@ func (g *TypeGenerator) mapToGoType(schema *openapi3.Schema, allowNested bool) (
 		return nestedStruct, nil
 	}
 
-	return MapSchemaToGoType(schema)
+	return g.formats.MapSchemaToGoType(schema)
 }
 
 // generateFieldTags generates struct field tags
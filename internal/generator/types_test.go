@@ -2,6 +2,7 @@ package generator
 
 import (
 	"embed"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -191,7 +192,12 @@ func TestMapToGoType(t *testing.T) {
 		{&openapi3.Schema{Type: "string"}, "string"},
 		{&openapi3.Schema{Type: "string", Format: "date-time"}, "time.Time"},
 		{&openapi3.Schema{Type: "string", Format: "binary"}, "[]byte"},
-		{&openapi3.Schema{Type: "string", Format: "uuid"}, "string"},
+		{&openapi3.Schema{Type: "string", Format: "uuid"}, "uuid.UUID"},
+		{&openapi3.Schema{Type: "string", Format: "date"}, "Date"},
+		{&openapi3.Schema{Type: "string", Format: "byte"}, "[]byte"},
+		{&openapi3.Schema{Type: "string", Format: "decimal"}, "decimal.Decimal"},
+		{&openapi3.Schema{Type: "string", Format: "ipv4"}, "net.IP"},
+		{&openapi3.Schema{Type: "string", Format: "email"}, "string"},
 		{&openapi3.Schema{Type: "number"}, "float64"},
 		{&openapi3.Schema{Type: "number", Format: "float"}, "float32"},
 		{&openapi3.Schema{Type: "number", Format: "double"}, "float64"},
@@ -327,3 +333,470 @@ func TestGenerateStructDefinition(t *testing.T) {
 		t.Errorf("Expected validation tag for required field, got: %s", structDef)
 	}
 }
+
+func TestBuildTypeDefinitionAllOf(t *testing.T) {
+	generator := NewTypeGenerator(nil, "models", mockFS)
+
+	schema := &openapi3.Schema{
+		AllOf: openapi3.SchemaRefs{
+			{Ref: "#/components/schemas/Base", Value: &openapi3.Schema{Type: "object"}},
+			{Value: &openapi3.Schema{
+				Type:     "object",
+				Required: []string{"number"},
+				Properties: openapi3.Schemas{
+					"number": {Value: &openapi3.Schema{Type: "string"}},
+				},
+			}},
+		},
+	}
+
+	defs, err := generator.buildTypeDefinition("Card", schema)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(defs) != 1 {
+		t.Fatalf("Expected 1 definition, got %d", len(defs))
+	}
+
+	def := defs[0]
+	if len(def.Embedded) != 1 || def.Embedded[0].Type != "Base" {
+		t.Errorf("Expected Base to be embedded, got: %+v", def.Embedded)
+	}
+	if len(def.Fields) != 1 || def.Fields[0].Name != "Number" {
+		t.Fatalf("Expected flattened Number field, got: %+v", def.Fields)
+	}
+	if !strings.Contains(def.Fields[0].Tags, "validate:\"required\"") {
+		t.Errorf("Expected required tag from allOf member, got: %s", def.Fields[0].Tags)
+	}
+}
+
+func TestSchemaProperties(t *testing.T) {
+	base := &openapi3.Schema{
+		Type:     "object",
+		Required: []string{"id"},
+		Properties: openapi3.Schemas{
+			"id":   {Value: &openapi3.Schema{Type: "string"}},
+			"note": {Value: &openapi3.Schema{Type: "string"}},
+		},
+	}
+	schema := &openapi3.Schema{
+		AllOf: openapi3.SchemaRefs{
+			{Ref: "#/components/schemas/Base", Value: base},
+			{Value: &openapi3.Schema{
+				Type:     "object",
+				Required: []string{"amount"},
+				Properties: openapi3.Schemas{
+					"amount": {Value: &openapi3.Schema{Type: "integer"}},
+					"note":   {Value: &openapi3.Schema{Type: "integer"}},
+				},
+			}},
+		},
+	}
+
+	properties, required := SchemaProperties("Payment", schema)
+
+	if names := sortedPropertyNames(properties); strings.Join(names, ",") != "amount,id,note" {
+		t.Fatalf("Expected own and promoted properties, got: %v", names)
+	}
+	if owner := properties["id"].Owner; owner != "Base" {
+		t.Errorf("Expected id to be owned by Base, got: %s", owner)
+	}
+	if prop := properties["note"]; prop.Owner != "Payment" || prop.Ref.Value.Type != "integer" {
+		t.Errorf("Expected own note to shadow the promoted one, got: %+v", prop)
+	}
+	if !Contains(required, "id") || !Contains(required, "amount") {
+		t.Errorf("Expected required from all members, got: %v", required)
+	}
+}
+
+func TestBuildTypeDefinitionOneOf(t *testing.T) {
+	generator := NewTypeGenerator(nil, "models", mockFS)
+
+	schema := &openapi3.Schema{
+		OneOf: openapi3.SchemaRefs{
+			{Ref: "#/components/schemas/Card", Value: &openapi3.Schema{Type: "object"}},
+			{Ref: "#/components/schemas/Bank", Value: &openapi3.Schema{Type: "object"}},
+			{Value: &openapi3.Schema{
+				Type: "object",
+				Properties: openapi3.Schemas{
+					"wallet": {Value: &openapi3.Schema{Type: "string"}},
+				},
+			}},
+		},
+		Discriminator: &openapi3.Discriminator{
+			PropertyName: "type",
+			Mapping: map[string]string{
+				"card":   "#/components/schemas/Card",
+				"wallet": "PaymentMethodOption3",
+			},
+		},
+	}
+
+	defs, err := generator.buildTypeDefinition("PaymentMethod", schema)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(defs) != 2 {
+		t.Fatalf("Expected union plus hoisted variant, got %d definitions", len(defs))
+	}
+
+	union := defs[0].Union
+	if union == nil {
+		t.Fatal("Expected union definition")
+	}
+	if union.Kind != "oneOf" || union.Discriminator != "type" || union.InterfaceName != "PaymentMethodVariant" {
+		t.Errorf("Unexpected union definition: %+v", union)
+	}
+
+	expected := []UnionVariant{
+		{TypeName: "Card", DiscriminatorValues: []string{"card"}},
+		{TypeName: "Bank", DiscriminatorValues: []string{"Bank"}},
+		{TypeName: "PaymentMethodOption3", DiscriminatorValues: []string{"wallet"}},
+	}
+	for i, variant := range expected {
+		got := union.Variants[i]
+		if got.TypeName != variant.TypeName || strings.Join(got.DiscriminatorValues, ",") != strings.Join(variant.DiscriminatorValues, ",") {
+			t.Errorf("Variant %d: expected %+v, got %+v", i, variant, got)
+		}
+	}
+
+	if defs[1].Name != "PaymentMethodOption3" || len(defs[1].Fields) != 1 {
+		t.Errorf("Expected hoisted inline variant, got: %+v", defs[1])
+	}
+}
+
+func TestBuildTypeDefinitionOneOfUnmappedInlineVariant(t *testing.T) {
+	generator := NewTypeGenerator(nil, "models", mockFS)
+
+	schema := &openapi3.Schema{
+		OneOf: openapi3.SchemaRefs{
+			{Ref: "#/components/schemas/Card", Value: &openapi3.Schema{Type: "object"}},
+			{Value: &openapi3.Schema{Type: "object"}},
+		},
+		Discriminator: &openapi3.Discriminator{PropertyName: "type"},
+	}
+
+	_, err := generator.buildTypeDefinition("PaymentMethod", schema)
+	if err == nil || !strings.Contains(err.Error(), "inline oneOf variant 2 has no type value") {
+		t.Errorf("Expected an error for the unmapped inline variant, got %v", err)
+	}
+}
+
+func TestResolveGoType(t *testing.T) {
+	inlineObject := &openapi3.Schema{
+		Type: "object",
+		Properties: openapi3.Schemas{
+			"street": {Value: &openapi3.Schema{Type: "string"}},
+		},
+	}
+
+	testCases := []struct {
+		name      string
+		ref       *openapi3.SchemaRef
+		nameHint  string
+		qualifier string
+		expected  string
+	}{
+		{"ref", &openapi3.SchemaRef{Ref: "#/components/schemas/User", Value: inlineObject}, "PetOwner", "domain", "domain.User"},
+		{"ref_unqualified", &openapi3.SchemaRef{Ref: "#/components/schemas/User", Value: inlineObject}, "PetOwner", "", "User"},
+		{
+			"array_of_refs",
+			&openapi3.SchemaRef{Value: &openapi3.Schema{
+				Type:  "array",
+				Items: &openapi3.SchemaRef{Ref: "#/components/schemas/Tag", Value: inlineObject},
+			}},
+			"PetTags", "domain", "[]domain.Tag",
+		},
+		{"inline_object", &openapi3.SchemaRef{Value: inlineObject}, "PetOwnerAddress", "", "PetOwnerAddress"},
+		{"inline_object_without_hint", &openapi3.SchemaRef{Value: inlineObject}, "", "domain", "map[string]interface{}"},
+		{
+			"array_of_inline_objects",
+			&openapi3.SchemaRef{Value: &openapi3.Schema{
+				Type:  "array",
+				Items: &openapi3.SchemaRef{Value: inlineObject},
+			}},
+			"PetLabels", "", "[]PetLabelsItem",
+		},
+		{"primitive", &openapi3.SchemaRef{Value: &openapi3.Schema{Type: "integer", Format: "int64"}}, "PetAge", "domain", "int64"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := NewFormatRegistry().ResolveGoType(tc.ref, tc.nameHint, tc.qualifier)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestBuildTypeDefinitionHoistsInlineObjects(t *testing.T) {
+	generator := NewTypeGenerator(nil, "models", mockFS)
+
+	schema := &openapi3.Schema{
+		Type: "object",
+		Properties: openapi3.Schemas{
+			"owner": {Ref: "#/components/schemas/User", Value: &openapi3.Schema{Type: "object"}},
+			"address": {Value: &openapi3.Schema{
+				Type: "object",
+				Properties: openapi3.Schemas{
+					"street": {Value: &openapi3.Schema{Type: "string"}},
+				},
+			}},
+		},
+	}
+
+	defs, err := generator.buildTypeDefinition("Pet", schema)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(defs) != 2 || defs[1].Name != "PetAddress" {
+		t.Fatalf("Expected PetAddress to be hoisted, got: %+v", defs)
+	}
+
+	fieldTypes := map[string]string{}
+	for _, field := range defs[0].Fields {
+		fieldTypes[field.Name] = field.Type
+	}
+	if fieldTypes["Owner"] != "User" {
+		t.Errorf("Expected Owner to be User, got %q", fieldTypes["Owner"])
+	}
+	if fieldTypes["Address"] != "PetAddress" {
+		t.Errorf("Expected Address to be PetAddress, got %q", fieldTypes["Address"])
+	}
+}
+
+func TestBuildTypeDefinitionsNameCollision(t *testing.T) {
+	owner := &openapi3.Schema{
+		Type: "object",
+		Properties: openapi3.Schemas{
+			"name": {Value: &openapi3.Schema{Type: "string"}},
+		},
+	}
+	apiParser := &parser.OpenAPIParser{Doc: &openapi3.T{
+		Components: &openapi3.Components{Schemas: openapi3.Schemas{
+			"Pet": {Value: &openapi3.Schema{
+				Type:       "object",
+				Properties: openapi3.Schemas{"owner": {Value: owner}},
+			}},
+			"PetOwner": {Value: &openapi3.Schema{Type: "object"}},
+		}},
+	}}
+	generator := NewTypeGenerator(apiParser, "models", mockFS)
+
+	_, err := generator.buildTypeDefinitions()
+	if err == nil || !strings.Contains(err.Error(), "PetOwner") {
+		t.Fatalf("Expected a PetOwner collision error, got: %v", err)
+	}
+
+	// Renaming the component schema resolves the collision
+	apiParser.Doc.Components.Schemas["PetOwner"].Value.Extensions = map[string]interface{}{"x-go-name": "Owner"}
+	if _, err := generator.buildTypeDefinitions(); err != nil {
+		t.Errorf("Unexpected error after renaming: %v", err)
+	}
+}
+
+func TestBuildTypeDefinitionVendorExtensions(t *testing.T) {
+	generator := NewTypeGenerator(nil, "models", mockFS)
+
+	schema := &openapi3.Schema{
+		Type: "object",
+		Properties: openapi3.Schemas{
+			"owner": {Ref: "#/components/schemas/User", Value: &openapi3.Schema{
+				Type:       "object",
+				Extensions: map[string]interface{}{"x-go-name": "Person"},
+			}},
+			"nick": {Value: &openapi3.Schema{
+				Type:       "string",
+				Extensions: map[string]interface{}{"x-go-name": "Nickname", "x-omitempty": true},
+			}},
+			"secret": {Value: &openapi3.Schema{
+				Type:       "string",
+				Extensions: map[string]interface{}{"x-go-json-ignore": true, "x-bson-name": "_secret"},
+			}},
+		},
+	}
+
+	defs, err := generator.buildTypeDefinition("Pet", schema)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	fields := map[string]TypeField{}
+	for _, field := range defs[0].Fields {
+		fields[field.Name] = field
+	}
+	if fields["Owner"].Type != "Person" {
+		t.Errorf("Expected Owner to use the x-go-name of User, got %+v", fields["Owner"])
+	}
+	if !strings.Contains(fields["Nickname"].Tags, `json:"nick,omitempty"`) {
+		t.Errorf("Expected Nickname with omitempty json tag, got %+v", fields["Nickname"])
+	}
+	if tags := fields["Secret"].Tags; !strings.Contains(tags, `json:"-"`) || !strings.Contains(tags, `bson:"_secret"`) {
+		t.Errorf("Unexpected Secret tags: %s", tags)
+	}
+
+	alias, err := generator.buildTypeDefinition("Money", &openapi3.Schema{
+		Type:       "string",
+		Extensions: map[string]interface{}{"x-go-type": "github.com/shopspring/decimal.Decimal"},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !alias[0].Alias || alias[0].BaseType != "decimal.Decimal" {
+		t.Errorf("Expected Money to alias decimal.Decimal, got %+v", alias[0])
+	}
+}
+
+func TestBuildTypeDefinitionEnum(t *testing.T) {
+	generator := NewTypeGenerator(nil, "models", mockFS)
+
+	schema := &openapi3.Schema{
+		Type: "object",
+		Properties: openapi3.Schemas{
+			"status": {Value: &openapi3.Schema{
+				Type: "string",
+				Enum: []interface{}{"available", "on-hold", "sold"},
+			}},
+		},
+	}
+
+	defs, err := generator.buildTypeDefinition("Pet", schema)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(defs) != 2 {
+		t.Fatalf("Expected Pet and PetStatus definitions, got %d", len(defs))
+	}
+	if defs[0].Fields[0].Type != "PetStatus" {
+		t.Errorf("Expected Status field to use PetStatus, got %q", defs[0].Fields[0].Type)
+	}
+
+	enumDef := defs[1]
+	if enumDef.Name != "PetStatus" || enumDef.Enum == nil {
+		t.Fatalf("Expected PetStatus enum definition, got: %+v", enumDef)
+	}
+
+	expected := []EnumValue{
+		{Name: "PetStatusAvailable", Value: "available"},
+		{Name: "PetStatusOnHold", Value: "on-hold"},
+		{Name: "PetStatusSold", Value: "sold"},
+	}
+	for i, value := range expected {
+		if enumDef.Enum.Values[i] != value {
+			t.Errorf("Value %d: expected %+v, got %+v", i, value, enumDef.Enum.Values[i])
+		}
+	}
+}
+
+func TestBuildEnumDefinitionCollisions(t *testing.T) {
+	// -1 collides with 1, and its numbered name ThingLevel13 with 13
+	schema := &openapi3.Schema{Type: "string", Enum: []interface{}{"13", "1", "-1", "ON", "on"}}
+
+	enumDef := buildEnumDefinition("ThingLevel", schema)
+	expected := []EnumValue{
+		{Name: "ThingLevel13", Value: "13"},
+		{Name: "ThingLevel1", Value: "1"},
+		{Name: "ThingLevel14", Value: "-1"},
+		{Name: "ThingLevelOn", Value: "ON"},
+		{Name: "ThingLevelOn5", Value: "on"},
+	}
+	if !reflect.DeepEqual(enumDef.Enum.Values, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, enumDef.Enum.Values)
+	}
+}
+
+func TestBuildTypeDefinitionOptionalStrategy(t *testing.T) {
+	schema := &openapi3.Schema{
+		Type: "object",
+		Properties: openapi3.Schemas{
+			"id":       {Value: &openapi3.Schema{Type: "string"}},
+			"name":     {Value: &openapi3.Schema{Type: "string"}},
+			"nickname": {Value: &openapi3.Schema{Type: "string"}},
+			"owner":    {Value: &openapi3.Schema{Type: "string", Nullable: true}},
+			"tags": {Value: &openapi3.Schema{
+				Type:  "array",
+				Items: &openapi3.SchemaRef{Value: &openapi3.Schema{Type: "string"}},
+			}},
+		},
+		Required: []string{"name", "owner"},
+	}
+
+	tests := []struct {
+		strategy OptionalStrategy
+		expected map[string]string
+	}{
+		{OptionalValue, map[string]string{
+			"ID": "string", "Name": "string", "Nickname": "string", "Owner": "string", "Tags": "[]string",
+		}},
+		{OptionalPointer, map[string]string{
+			"ID": "string", "Name": "string", "Nickname": "*string", "Owner": "*string", "Tags": "[]string",
+		}},
+		{OptionalGeneric, map[string]string{
+			"ID": "string", "Name": "string", "Nickname": "Optional[string]", "Owner": "Nullable[string]",
+			"Tags": "Optional[[]string]",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.strategy), func(t *testing.T) {
+			generator := NewTypeGenerator(nil, "models", mockFS)
+			generator.SetOptionalStrategy(tt.strategy)
+
+			defs, err := generator.buildTypeDefinition("Pet", schema)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			for _, field := range defs[0].Fields {
+				if field.Type != tt.expected[field.Name] {
+					t.Errorf("Field %s: expected %q, got %q", field.Name, tt.expected[field.Name], field.Type)
+				}
+
+				// Only absent-able fields are omitted from encoded documents
+				omitted := strings.Contains(field.Tags, `bson:"nickname,omitempty"`)
+				if field.Name == "Nickname" && omitted != (tt.strategy != OptionalValue) {
+					t.Errorf("Unexpected Nickname tags for %s strategy: %s", tt.strategy, field.Tags)
+				}
+				// Slices are nil when absent, so pointers only add the omit options
+				if field.Name == "Tags" && strings.Contains(field.Tags, `json:"tags,omitempty"`) != (tt.strategy == OptionalPointer) {
+					t.Errorf("Unexpected Tags tags for %s strategy: %s", tt.strategy, field.Tags)
+				}
+				if field.Name == "Owner" && strings.Contains(field.Tags, "omit") {
+					t.Errorf("Required nullable Owner should not be omitted: %s", field.Tags)
+				}
+			}
+		})
+	}
+}
+
+func TestParseOptionalStrategy(t *testing.T) {
+	for _, name := range []string{"value", "pointer", "generic"} {
+		if _, err := ParseOptionalStrategy(name); err != nil {
+			t.Errorf("Unexpected error for %q: %v", name, err)
+		}
+	}
+	if _, err := ParseOptionalStrategy("maybe"); err == nil {
+		t.Error("Expected error for unknown strategy")
+	}
+}
+
+func TestTypeFileName(t *testing.T) {
+	tests := map[string]string{
+		"Pet":           "pet.go",
+		"PurchaseOrder": "purchase_order.go",
+		"Errors":        "errors_type.go",
+		"PetTest":       "pet_test_type.go",
+		"KernelLinux":   "kernel_linux_type.go",
+		"Linux":         "linux.go",
+	}
+
+	for typeName, want := range tests {
+		if got := TypeFileName(typeName); got != want {
+			t.Errorf("TypeFileName(%q) = %q, want %q", typeName, got, want)
+		}
+	}
+}
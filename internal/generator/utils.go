@@ -2,6 +2,10 @@ package generator
 
 import (
 	"fmt"
+	goparser "go/parser"
+	"go/token"
+	"sort"
+	"strconv"
 	"strings"
 	"unicode"
 
@@ -118,22 +122,47 @@ func Contains(slice []string, item string) bool {
 	return false
 }
 
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys[M ~map[string]V, V any](m M) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// appendUnique appends items to a slice, skipping ones already present
+func appendUnique(slice []string, items ...string) []string {
+	for _, item := range items {
+		if !Contains(slice, item) {
+			slice = append(slice, item)
+		}
+	}
+	return slice
+}
+
+// SchemaRefName extracts the schema name from a $ref such as
+// "#/components/schemas/User"
+func SchemaRefName(ref string) string {
+	parts := strings.Split(ref, "/")
+	return parts[len(parts)-1]
+}
+
 // Type mapping functions
 
 // MapSchemaToGoType maps an OpenAPI schema to a Go type
-func MapSchemaToGoType(schema *openapi3.Schema) (string, error) {
+func (r *FormatRegistry) MapSchemaToGoType(schema *openapi3.Schema) (string, error) {
+	if goType, ok := goTypeExtension(schema); ok {
+		return goType, nil
+	}
+
 	switch schema.Type {
 	case "string":
-		switch schema.Format {
-		case "date-time":
-			return "time.Time", nil
-		case "binary":
-			return "[]byte", nil
-		case "uuid":
-			return "string", nil
-		default:
-			return "string", nil
+		if mapping, ok := r.lookupFormat(schema.Format); ok {
+			return mapping.Type, nil
 		}
+		return "string", nil
 	case "number":
 		switch schema.Format {
 		case "float":
@@ -154,7 +183,7 @@ func MapSchemaToGoType(schema *openapi3.Schema) (string, error) {
 		return "bool", nil
 	case "array":
 		if schema.Items != nil && schema.Items.Value != nil {
-			itemType, err := MapSchemaToGoType(schema.Items.Value)
+			itemType, err := r.MapSchemaToGoType(schema.Items.Value)
 			if err != nil {
 				return "", err
 			}
@@ -164,7 +193,7 @@ func MapSchemaToGoType(schema *openapi3.Schema) (string, error) {
 	case "object":
 		// Check for additional properties (maps)
 		if schema.AdditionalProperties.Schema != nil && schema.AdditionalProperties.Schema.Value != nil {
-			valueType, err := MapSchemaToGoType(schema.AdditionalProperties.Schema.Value)
+			valueType, err := r.MapSchemaToGoType(schema.AdditionalProperties.Schema.Value)
 			if err != nil {
 				return "", err
 			}
@@ -181,6 +210,157 @@ func MapSchemaToGoType(schema *openapi3.Schema) (string, error) {
 	}
 }
 
+// ResolveGoType maps a schema reference to a Go type without losing $ref names.
+// Referenced schemas resolve to their component name, and inline object or
+// composed schemas resolve to the hoisted type named nameHint (or to built-in
+// types when nameHint is empty). Named types are prefixed with qualifier
+// (e.g. "domain") when it is not empty.
+func (r *FormatRegistry) ResolveGoType(ref *openapi3.SchemaRef, nameHint, qualifier string) (string, error) {
+	if ref == nil || ref.Value == nil {
+		return "interface{}", nil
+	}
+
+	if ref.Ref != "" {
+		return qualifyTypeName(refTypeName(ref), qualifier), nil
+	}
+
+	schema := ref.Value
+	if goType, ok := goTypeExtension(schema); ok {
+		return goType, nil
+	}
+	if localType, ok := r.localFormatType(schema); ok {
+		return qualifyTypeName(localType, qualifier), nil
+	}
+
+	switch {
+	case IsHoistedSchema(schema) && nameHint != "":
+		return qualifyTypeName(nameHint, qualifier), nil
+	case schema.Type == "array" && schema.Items != nil:
+		itemType, err := r.ResolveGoType(schema.Items, nestedTypeName(nameHint, "Item"), qualifier)
+		if err != nil {
+			return "", err
+		}
+		return "[]" + itemType, nil
+	case schema.Type == "object" && schema.AdditionalProperties.Schema != nil:
+		valueType, err := r.ResolveGoType(schema.AdditionalProperties.Schema, nestedTypeName(nameHint, "Value"), qualifier)
+		if err != nil {
+			return "", err
+		}
+		return "map[string]" + valueType, nil
+	}
+
+	return r.MapSchemaToGoType(schema)
+}
+
+// ResolvePropertyType maps a schema property to a Go type like ResolveGoType.
+// String identifiers stay plain strings whatever their format, since the
+// generated services, repositories and handlers address entities by string ID.
+func (r *FormatRegistry) ResolvePropertyType(propName string, ref *openapi3.SchemaRef, nameHint, qualifier string) (string, error) {
+	if (propName == "id" || propName == "ID") && ref != nil && ref.Value != nil && ref.Value.Type == "string" {
+		return "string", nil
+	}
+	return r.ResolveGoType(ref, nameHint, qualifier)
+}
+
+// IsHoistedSchema reports whether an inline schema is generated as its own
+// named type rather than mapped to a built-in Go type
+func IsHoistedSchema(schema *openapi3.Schema) bool {
+	if schema == nil {
+		return false
+	}
+	return len(schema.Properties) > 0 || len(schema.AllOf) > 0 ||
+		len(schema.OneOf) > 0 || len(schema.AnyOf) > 0 || IsStringEnum(schema)
+}
+
+// IsStringEnum reports whether a schema is a string with enumerated values
+func IsStringEnum(schema *openapi3.Schema) bool {
+	return schema != nil && schema.Type == "string" && len(schema.Enum) > 0
+}
+
+// EnumStringValues returns the enumerated values of a string enum schema
+func EnumStringValues(schema *openapi3.Schema) []string {
+	if !IsStringEnum(schema) {
+		return nil
+	}
+
+	values := make([]string, 0, len(schema.Enum))
+	for _, enumVal := range schema.Enum {
+		if strVal, ok := enumVal.(string); ok {
+			values = append(values, strVal)
+		}
+	}
+	return values
+}
+
+// EnumConstName returns the Go constant name for an enum value,
+// e.g. PetStatus + "in-stock" -> PetStatusInStock
+func EnumConstName(typeName, value string) string {
+	suffix := ToPascalCase(value)
+	if suffix == "" {
+		suffix = "Empty"
+	}
+	return typeName + suffix
+}
+
+// HoistedTypeName returns the name of the type hoisted out of an inline
+// property schema, e.g. Pet + owner_address -> PetOwnerAddress
+func HoistedTypeName(parent, propName string) string {
+	return parent + ToPascalCase(propName)
+}
+
+// IsManagedProperty reports whether a property is filled in by the generated
+// services and repositories (identifiers and timestamps)
+func IsManagedProperty(propName string) bool {
+	switch propName {
+	case "id", "ID", "created_at", "createdAt", "updated_at", "updatedAt":
+		return true
+	}
+	return false
+}
+
+// nestedTypeName derives the hoisted name for array items and map values
+func nestedTypeName(nameHint, suffix string) string {
+	if nameHint == "" {
+		return ""
+	}
+	return nameHint + suffix
+}
+
+// qualifyTypeName prefixes a named type with its package qualifier
+func qualifyTypeName(name, qualifier string) string {
+	if qualifier == "" {
+		return name
+	}
+	return qualifier + "." + name
+}
+
+// requestFieldImports returns the import specs needed by the types of
+// request fields and by their test values, reporting separately whether
+// the time package is needed
+func (r *FormatRegistry) requestFieldImports(fields []RequestField) ([]string, []string, bool) {
+	var imports, testImports []string
+	importTime := false
+
+	for _, field := range fields {
+		for _, imp := range r.TypeImports(field.Type) {
+			if imp == strconv.Quote("time") {
+				importTime = true
+				continue
+			}
+			imports = appendUnique(imports, imp)
+
+			// Test values of optional pointer fields are nil
+			if field.Provided == "" || !strings.HasPrefix(field.Type, "*") {
+				testImports = appendUnique(testImports, imp)
+			}
+		}
+	}
+
+	sort.Strings(imports)
+	sort.Strings(testImports)
+	return imports, testImports, importTime
+}
+
 // MapParameterTypeToGo maps an OpenAPI parameter type to a Go type
 func MapParameterTypeToGo(param *openapi3.Parameter) string {
 	if param.Schema == nil || param.Schema.Value == nil {
@@ -204,7 +384,7 @@ func MapParameterTypeToGo(param *openapi3.Parameter) string {
 }
 
 // GetTestValueForProperty generates appropriate test values for different property types
-func GetTestValueForProperty(schema *openapi3.Schema) string {
+func (r *FormatRegistry) GetTestValueForProperty(schema *openapi3.Schema) string {
 	switch schema.Type {
 	case "string":
 		switch schema.Format {
@@ -234,8 +414,8 @@ func GetTestValueForProperty(schema *openapi3.Schema) string {
 		return "true"
 	case "array":
 		if schema.Items != nil && schema.Items.Value != nil {
-			itemValue := GetTestValueForProperty(schema.Items.Value)
-			itemType, _ := MapSchemaToGoType(schema.Items.Value)
+			itemValue := r.GetTestValueForProperty(schema.Items.Value)
+			itemType, _ := r.MapSchemaToGoType(schema.Items.Value)
 			return fmt.Sprintf("[]%s{%s}", itemType, itemValue)
 		}
 		return "[]interface{}{}"
@@ -245,3 +425,57 @@ func GetTestValueForProperty(schema *openapi3.Schema) string {
 		return "nil"
 	}
 }
+
+// GetTestValueForSchemaRef generates a test value for a property, using the
+// named Go type for referenced and hoisted schemas
+func (r *FormatRegistry) GetTestValueForSchemaRef(ref *openapi3.SchemaRef, nameHint, qualifier string) string {
+	if ref == nil || ref.Value == nil {
+		return "nil"
+	}
+
+	goType, err := r.ResolveGoType(ref, nameHint, qualifier)
+	if err != nil {
+		return "nil"
+	}
+
+	builtinType, err := r.MapSchemaToGoType(ref.Value)
+	if err != nil || goType == builtinType {
+		return r.GetTestValueForProperty(ref.Value)
+	}
+
+	// Composite and struct types use their zero value, named primitives such
+	// as enums a conversion
+	if strings.HasPrefix(goType, "[]") || strings.HasPrefix(goType, "map[") ||
+		ref.Value.Type == "object" || (IsHoistedSchema(ref.Value) && !IsStringEnum(ref.Value)) {
+		return goType + "{}"
+	}
+	return fmt.Sprintf("%s(%s)", goType, r.GetTestValueForProperty(ref.Value))
+}
+
+// ExternalImports returns the non-standard-library packages imported by
+// generated Go sources, so they can be added to the target module
+func ExternalImports(sources ...string) ([]string, error) {
+	var imports []string
+	fset := token.NewFileSet()
+
+	for _, source := range sources {
+		file, err := goparser.ParseFile(fset, "", source, goparser.ImportsOnly)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse generated imports: %w", err)
+		}
+
+		for _, spec := range file.Imports {
+			importPath, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse import %s: %w", spec.Path.Value, err)
+			}
+			// Standard library paths have no dot in their first element
+			if first, _, _ := strings.Cut(importPath, "/"); strings.Contains(first, ".") {
+				imports = appendUnique(imports, importPath)
+			}
+		}
+	}
+
+	sort.Strings(imports)
+	return imports, nil
+}
@@ -0,0 +1,259 @@
+package generator
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// ValidationRule is a single generated check of a field value
+type ValidationRule struct {
+	Condition string // Go condition that holds when the value is invalid
+	Message   string // Message reported for the field
+}
+
+// FieldValidation holds the checks generated for a struct field. The
+// templates render it inside a Validate method collecting failures in errs.
+type FieldValidation struct {
+	Field    string           // Property name reported in field errors
+	Required string           // Condition that holds when a required value is missing
+	Guard    string           // Condition that must hold before Rules are checked
+	Rules    []ValidationRule // Constraint checks of the value
+	Nested   string           // Value validated with its own Validate method
+	Items    string           // Slice whose items are validated with their Validate method
+	Values   string           // Map whose values are validated with their Validate method
+}
+
+// HasChecks reports whether any check is generated for the field
+func (v FieldValidation) HasChecks() bool {
+	return v.Required != "" || len(v.Rules) > 0 || v.Nested != "" || v.Items != "" || v.Values != ""
+}
+
+// integerTypes and floatTypes are the Go types numeric constraints apply to
+var (
+	integerTypes = map[string]bool{"int": true, "int32": true, "int64": true}
+	floatTypes   = map[string]bool{"float32": true, "float64": true}
+)
+
+// fieldValidation builds the checks of a struct field holding the value of
+// prop. expr is the field expression and goType its Go type before wrapping
+// with the optional strategy; wrapped reports whether the field is wrapped.
+// Helpers of the domain package are prefixed with qualifier.
+func fieldValidation(propName string, prop *openapi3.SchemaRef, required bool, goType, expr string, strategy OptionalStrategy, wrapped bool, qualifier string) FieldValidation {
+	validation := FieldValidation{Field: propName}
+	if prop == nil || prop.Value == nil {
+		return validation
+	}
+	schema := prop.Value
+
+	value := expr
+	if wrapped {
+		value = strategy.ValueOf(expr, goType)
+		validation.Guard = strategy.ValueCheck(expr)
+		// Nil pointers of required nullable fields may hold an explicit null
+		if required && strategy != OptionalPointer {
+			validation.Required = "!(" + strategy.ProvidedCheck(expr) + ")"
+		}
+	} else {
+		check := missingCheck(schema, goType, value, qualifier)
+		if required && !IsManagedProperty(propName) {
+			validation.Required = check
+		}
+		// Empty strings and nil slices of plain fields mean absent
+		if strings.HasSuffix(check, `== ""`) || strings.HasSuffix(check, "== nil") {
+			validation.Guard = strings.Replace(check, "==", "!=", 1)
+		}
+	}
+
+	helper := func(name string) string { return qualifyTypeName(name, qualifier) }
+	isEnum := len(EnumStringValues(schema)) > 0 && goType != "string"
+
+	switch {
+	case goType == "string" || isEnum:
+		str := value
+		if isEnum {
+			str = "string(" + value + ")"
+			validation.Rules = append(validation.Rules, ValidationRule{
+				Condition: fmt.Sprintf("!%s.IsValid()", value),
+				Message:   "must be one of: " + strings.Join(EnumStringValues(schema), ", "),
+			})
+		}
+		if schema.MinLength > 0 {
+			validation.Rules = append(validation.Rules, ValidationRule{
+				Condition: fmt.Sprintf("%s(%s) < %d", helper("StringLength"), str, schema.MinLength),
+				Message:   fmt.Sprintf("must be at least %d characters long", schema.MinLength),
+			})
+		}
+		if schema.MaxLength != nil {
+			validation.Rules = append(validation.Rules, ValidationRule{
+				Condition: fmt.Sprintf("%s(%s) > %d", helper("StringLength"), str, *schema.MaxLength),
+				Message:   fmt.Sprintf("must be at most %d characters long", *schema.MaxLength),
+			})
+		}
+		// Patterns RE2 cannot compile (e.g. with lookaheads) are left unchecked
+		if _, err := regexp.Compile(schema.Pattern); schema.Pattern != "" && err == nil {
+			validation.Rules = append(validation.Rules, ValidationRule{
+				Condition: fmt.Sprintf("!%s(%q, %s)", helper("MatchesPattern"), schema.Pattern, str),
+				Message:   "must match pattern " + schema.Pattern,
+			})
+		}
+
+	case integerTypes[goType] || floatTypes[goType]:
+		validation.Rules = append(validation.Rules, numberRules(schema, goType, value, helper)...)
+
+	case strings.HasPrefix(goType, "[]") && schema.Type == "array":
+		if schema.MinItems > 0 {
+			validation.Rules = append(validation.Rules, ValidationRule{
+				Condition: fmt.Sprintf("len(%s) < %d", value, schema.MinItems),
+				Message:   fmt.Sprintf("must contain at least %d items", schema.MinItems),
+			})
+		}
+		if schema.MaxItems != nil {
+			validation.Rules = append(validation.Rules, ValidationRule{
+				Condition: fmt.Sprintf("len(%s) > %d", value, *schema.MaxItems),
+				Message:   fmt.Sprintf("must contain at most %d items", *schema.MaxItems),
+			})
+		}
+		if schema.UniqueItems {
+			validation.Rules = append(validation.Rules, ValidationRule{
+				Condition: fmt.Sprintf("%s(%s)", helper("HasDuplicates"), value),
+				Message:   "must not contain duplicate items",
+			})
+		}
+		if hasValidateMethod(schema.Items) {
+			validation.Items = value
+		}
+
+	case strings.HasPrefix(goType, "map[") && schema.Type == "object":
+		if schema.MinProps > 0 {
+			validation.Rules = append(validation.Rules, ValidationRule{
+				Condition: fmt.Sprintf("len(%s) < %d", value, schema.MinProps),
+				Message:   fmt.Sprintf("must contain at least %d properties", schema.MinProps),
+			})
+		}
+		if schema.MaxProps != nil {
+			validation.Rules = append(validation.Rules, ValidationRule{
+				Condition: fmt.Sprintf("len(%s) > %d", value, *schema.MaxProps),
+				Message:   fmt.Sprintf("must contain at most %d properties", *schema.MaxProps),
+			})
+		}
+		if hasValidateMethod(schema.AdditionalProperties.Schema) {
+			validation.Values = value
+		}
+
+	case hasValidateMethod(prop):
+		validation.Nested = value
+	}
+
+	return validation
+}
+
+// missingCheck returns a condition reporting whether a required value that
+// is not wrapped was absent from the payload. Zero numbers and booleans are
+// valid values, so they cannot be checked. The generated Date and URI types
+// are prefixed with qualifier.
+func missingCheck(schema *openapi3.Schema, goType, value, qualifier string) string {
+	_, extension := goTypeExtension(schema)
+	switch {
+	case goType == "string" || len(EnumStringValues(schema)) > 0:
+		return value + ` == ""`
+	case strings.HasPrefix(goType, "[]") || strings.HasPrefix(goType, "map["):
+		return value + " == nil"
+	case extension:
+		return ""
+	case len(schema.OneOf) > 0 || len(schema.AnyOf) > 0:
+		return value + ".Value == nil"
+	case schema.Type == "array" || (schema.Type == "object" && !isStructSchema(schema)):
+		// Named slice and map types
+		return value + " == nil"
+	case goType == "time.Time" || goType == qualifyTypeName("Date", qualifier) || goType == qualifyTypeName("URI", qualifier):
+		return value + ".IsZero()"
+	}
+	return ""
+}
+
+// numberRules builds the minimum, maximum and multipleOf checks of a number
+func numberRules(schema *openapi3.Schema, goType, value string, helper func(string) string) []ValidationRule {
+	var rules []ValidationRule
+
+	if schema.Min != nil {
+		operator, message := "<", "must be at least %v"
+		if schema.ExclusiveMin {
+			operator, message = "<=", "must be greater than %v"
+		}
+		rules = append(rules, ValidationRule{
+			Condition: compareNumber(value, goType, operator, *schema.Min),
+			Message:   fmt.Sprintf(message, *schema.Min),
+		})
+	}
+	if schema.Max != nil {
+		operator, message := ">", "must be at most %v"
+		if schema.ExclusiveMax {
+			operator, message = ">=", "must be less than %v"
+		}
+		rules = append(rules, ValidationRule{
+			Condition: compareNumber(value, goType, operator, *schema.Max),
+			Message:   fmt.Sprintf(message, *schema.Max),
+		})
+	}
+	if schema.MultipleOf != nil && *schema.MultipleOf > 0 {
+		divisor := *schema.MultipleOf
+		condition := fmt.Sprintf("!%s(float64(%s), %s)", helper("IsMultipleOf"), value, formatNumber(divisor))
+		if integerTypes[goType] && divisor == math.Trunc(divisor) {
+			condition = fmt.Sprintf("%s%%%s != 0", value, formatNumber(divisor))
+		}
+		rules = append(rules, ValidationRule{
+			Condition: condition,
+			Message:   fmt.Sprintf("must be a multiple of %v", divisor),
+		})
+	}
+
+	return rules
+}
+
+// compareNumber compares a numeric value with a bound, converting integers
+// to float64 when the bound is fractional
+func compareNumber(value, goType, operator string, bound float64) string {
+	if integerTypes[goType] && bound != math.Trunc(bound) {
+		value = "float64(" + value + ")"
+	}
+	return fmt.Sprintf("%s %s %s", value, operator, formatNumber(bound))
+}
+
+// formatNumber formats a schema bound as a Go constant
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+// hasValidateMethod reports whether a schema maps to a generated type with
+// a Validate method: a struct, a union or a named type other than an enum
+func hasValidateMethod(ref *openapi3.SchemaRef) bool {
+	if ref == nil || ref.Value == nil {
+		return false
+	}
+	schema := ref.Value
+	if _, ok := goTypeExtension(schema); ok {
+		return false
+	}
+	if ref.Ref == "" && !IsHoistedSchema(schema) {
+		return false
+	}
+	if len(schema.OneOf) > 0 || len(schema.AnyOf) > 0 {
+		return true
+	}
+	if IsStringEnum(schema) {
+		return false
+	}
+	return isStructSchema(schema) || hasNamedValidate(schema)
+}
+
+// hasNamedValidate reports whether the named non-struct type generated for
+// schema has a Validate method. Schemas without a type map to interface{},
+// which cannot have methods.
+func hasNamedValidate(schema *openapi3.Schema) bool {
+	return schema.Type != ""
+}
@@ -0,0 +1,136 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+func TestFieldValidation(t *testing.T) {
+	maxLength := uint64(10)
+	prop := &openapi3.SchemaRef{Value: &openapi3.Schema{
+		Type:      "string",
+		MinLength: 2,
+		MaxLength: &maxLength,
+		Pattern:   "^[a-z]+$",
+	}}
+
+	validation := fieldValidation("name", prop, true, "string", "r.Name", OptionalValue, false, "domain")
+	if validation.Required != `r.Name == ""` {
+		t.Errorf("Unexpected required check: %q", validation.Required)
+	}
+	expected := []string{
+		"domain.StringLength(r.Name) < 2",
+		"domain.StringLength(r.Name) > 10",
+		`!domain.MatchesPattern("^[a-z]+$", r.Name)`,
+	}
+	if len(validation.Rules) != len(expected) {
+		t.Fatalf("Expected %d rules, got %+v", len(expected), validation.Rules)
+	}
+	for i, condition := range expected {
+		if validation.Rules[i].Condition != condition {
+			t.Errorf("Rule %d: expected %q, got %q", i, condition, validation.Rules[i].Condition)
+		}
+	}
+
+	// Wrapped fields are only checked when they hold a value
+	minimum, multipleOf := 1.0, 3.0
+	count := &openapi3.SchemaRef{Value: &openapi3.Schema{Type: "integer", Min: &minimum, MultipleOf: &multipleOf}}
+	validation = fieldValidation("count", count, false, "int", "v.Count", OptionalPointer, true, "")
+	if validation.Required != "" || validation.Guard != "v.Count != nil" {
+		t.Errorf("Unexpected checks for wrapped field: %+v", validation)
+	}
+	if len(validation.Rules) != 2 || validation.Rules[0].Condition != "(*v.Count) < 1" || validation.Rules[1].Condition != "(*v.Count)%3 != 0" {
+		t.Errorf("Unexpected rules for wrapped field: %+v", validation.Rules)
+	}
+
+	// Referenced objects are validated with their own Validate method
+	part := &openapi3.SchemaRef{Ref: "#/components/schemas/Part", Value: &openapi3.Schema{
+		Type:       "object",
+		Properties: openapi3.Schemas{"sku": {Value: &openapi3.Schema{Type: "string"}}},
+	}}
+	parts := &openapi3.SchemaRef{Value: &openapi3.Schema{Type: "array", Items: part, UniqueItems: true}}
+	validation = fieldValidation("parts", parts, false, "[]Part", "v.Parts", OptionalValue, false, "")
+	if validation.Items != "v.Parts" || len(validation.Rules) != 1 || validation.Rules[0].Condition != "HasDuplicates(v.Parts)" {
+		t.Errorf("Unexpected checks for array field: %+v", validation)
+	}
+	if validation = fieldValidation("main", part, false, "Part", "v.Main", OptionalValue, false, ""); validation.Nested != "v.Main" {
+		t.Errorf("Expected nested validation, got %+v", validation)
+	}
+}
+
+func TestFieldValidationComposedTypes(t *testing.T) {
+	part := &openapi3.SchemaRef{Ref: "#/components/schemas/Part", Value: &openapi3.Schema{
+		Type:       "object",
+		Properties: openapi3.Schemas{"sku": {Value: &openapi3.Schema{Type: "string"}}},
+	}}
+
+	// Unions are required through their active variant and validate it
+	method := &openapi3.SchemaRef{Ref: "#/components/schemas/Method", Value: &openapi3.Schema{
+		OneOf: openapi3.SchemaRefs{part},
+	}}
+	validation := fieldValidation("method", method, true, "Method", "v.Method", OptionalValue, false, "")
+	if validation.Required != "v.Method.Value == nil" || validation.Guard != "v.Method.Value != nil" || validation.Nested != "v.Method" {
+		t.Errorf("Unexpected checks for union field: %+v", validation)
+	}
+
+	// Named slice and map types validate their own constraints
+	tags := &openapi3.SchemaRef{Ref: "#/components/schemas/Tags", Value: &openapi3.Schema{
+		Type:     "array",
+		MinItems: 1,
+		Items:    &openapi3.SchemaRef{Value: &openapi3.Schema{Type: "string"}},
+	}}
+	validation = fieldValidation("tags", tags, true, "Tags", "v.Tags", OptionalValue, false, "")
+	if validation.Required != "v.Tags == nil" || validation.Nested != "v.Tags" || len(validation.Rules) != 0 {
+		t.Errorf("Unexpected checks for named slice field: %+v", validation)
+	}
+
+	maxProps := uint64(2)
+	labels := &openapi3.SchemaRef{Value: &openapi3.Schema{
+		Type:                 "object",
+		MaxProps:             &maxProps,
+		AdditionalProperties: openapi3.AdditionalProperties{Schema: part},
+	}}
+	validation = fieldValidation("", labels, false, "map[string]Part", "v", OptionalValue, false, "")
+	if validation.Values != "v" || len(validation.Rules) != 1 || validation.Rules[0].Condition != "len(v) > 2" {
+		t.Errorf("Unexpected checks for map value: %+v", validation)
+	}
+
+	// Enums and x-go-type schemas have no Validate method
+	status := &openapi3.SchemaRef{Ref: "#/components/schemas/Status", Value: &openapi3.Schema{Type: "string", Enum: []interface{}{"open"}}}
+	external := &openapi3.SchemaRef{Ref: "#/components/schemas/Money", Value: &openapi3.Schema{
+		Type:       "string",
+		Extensions: map[string]interface{}{"x-go-type": "decimal.Decimal"},
+	}}
+	for _, ref := range []*openapi3.SchemaRef{status, external} {
+		if hasValidateMethod(ref) {
+			t.Errorf("Expected no Validate method for %s", ref.Ref)
+		}
+	}
+}
+
+func TestBuildNamedTypeDefinitionValidation(t *testing.T) {
+	generator := NewTypeGenerator(nil, "models", mockFS)
+
+	schema := &openapi3.Schema{Type: "string", Pattern: "^[A-Z]{3}$"}
+	defs, err := generator.buildTypeDefinition("Code", schema)
+	if err != nil {
+		t.Fatalf("Failed to build type definition: %v", err)
+	}
+	def := defs[0]
+	if !def.Validated || len(def.Validations) != 1 {
+		t.Fatalf("Expected a validated named type, got: %+v", def)
+	}
+	if rules := def.Validations[0].Rules; len(rules) != 1 || rules[0].Condition != `!MatchesPattern("^[A-Z]{3}$", string(v))` {
+		t.Errorf("Unexpected rules: %+v", rules)
+	}
+
+	// Untyped schemas map to interface{}, which cannot have methods
+	defs, err = generator.buildTypeDefinition("Anything", &openapi3.Schema{})
+	if err != nil {
+		t.Fatalf("Failed to build type definition: %v", err)
+	}
+	if defs[0].Validated {
+		t.Errorf("Expected no Validate method for %s", defs[0].BaseType)
+	}
+}
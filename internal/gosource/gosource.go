@@ -0,0 +1,80 @@
+// Package gosource formats generated Go source and fixes its imports as
+// goimports does: unused imports are removed, missing ones added, and
+// standard library, other and local imports grouped apart.
+package gosource
+
+import (
+	"fmt"
+	"go/scanner"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/imports"
+)
+
+// contextLines is the number of lines shown around a syntax error
+const contextLines = 2
+
+// localPrefixMu guards imports.LocalPrefix, which the imports package reads
+// from a variable
+var localPrefixMu sync.Mutex
+
+// Error is a syntax error in Go source
+type Error struct {
+	Line    int
+	Column  int
+	Msg     string
+	Context string // The numbered lines around the error, which is marked
+}
+
+// Error returns the position and message of the error followed by the lines
+// around it
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d:%d: %s\n%s", e.Line, e.Column, e.Msg, e.Context)
+}
+
+// Format formats Go source and fixes its imports. The imports of packages
+// under localPrefix, usually the path of the module the source belongs to,
+// are grouped after the others. Source that does not parse returns an
+// *Error.
+func Format(src []byte, localPrefix string) ([]byte, error) {
+	localPrefixMu.Lock()
+	defer localPrefixMu.Unlock()
+	imports.LocalPrefix = localPrefix
+
+	formatted, err := imports.Process("", src, &imports.Options{Comments: true, TabIndent: true, TabWidth: 8})
+	if err != nil {
+		return nil, syntaxError(src, err)
+	}
+	return formatted, nil
+}
+
+// syntaxError converts the first error of a parse of src to an *Error
+func syntaxError(src []byte, err error) error {
+	var list scanner.ErrorList
+	if errList, ok := err.(scanner.ErrorList); ok {
+		list = errList
+	}
+	if len(list) == 0 {
+		return err
+	}
+
+	first := list[0]
+	lines := strings.Split(strings.TrimSuffix(string(src), "\n"), "\n")
+	from, to := max(first.Pos.Line-contextLines, 1), min(first.Pos.Line+contextLines, len(lines))
+	var context strings.Builder
+	for line := from; line <= to; line++ {
+		marker := " "
+		if line == first.Pos.Line {
+			marker = ">"
+		}
+		context.WriteString(strings.TrimRight(fmt.Sprintf("%s %4d | %s", marker, line, lines[line-1]), " ") + "\n")
+	}
+
+	return &Error{
+		Line:    first.Pos.Line,
+		Column:  first.Pos.Column,
+		Msg:     first.Msg,
+		Context: strings.TrimSuffix(context.String(), "\n"),
+	}
+}
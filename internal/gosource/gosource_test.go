@@ -0,0 +1,197 @@
+package gosource
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		expected string
+	}{
+		{
+			name:     "formats",
+			src:      "package api\n\n\n\nfunc  Sum(a,b int)int{\n    return a+b\n}\n",
+			expected: "package api\n\nfunc Sum(a, b int) int {\n\treturn a + b\n}\n",
+		},
+		{
+			name: "removes unused imports",
+			src: `package api
+
+import (
+	"fmt"
+	"time"
+	"strings"
+
+	chi "github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	_ "embed"
+)
+
+func Join(parts []string) string { return strings.Join(parts, fmt.Sprint(uuid.New())) }
+`,
+			expected: `package api
+
+import (
+	"fmt"
+	"strings"
+
+	_ "embed"
+
+	"github.com/google/uuid"
+)
+
+func Join(parts []string) string { return strings.Join(parts, fmt.Sprint(uuid.New())) }
+`,
+		},
+		{
+			name:     "removes an unused import declaration",
+			src:      "package api\n\nimport \"time\"\n\ntype Pet struct{}\n",
+			expected: "package api\n\ntype Pet struct{}\n",
+		},
+		{
+			name: "adds missing standard library imports",
+			src: `package api
+
+import (
+	"github.com/google/uuid"
+)
+
+func Now(ctx context.Context) string {
+	return time.Now().Format(time.RFC3339) + uuid.NewString()
+}
+`,
+			expected: `package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func Now(ctx context.Context) string {
+	return time.Now().Format(time.RFC3339) + uuid.NewString()
+}
+`,
+		},
+		{
+			name:     "adds an import declaration",
+			src:      "package api\n\nfunc Fail() error { return errors.New(fmt.Sprint(1)) }\n",
+			expected: "package api\n\nimport (\n\t\"errors\"\n\t\"fmt\"\n)\n\nfunc Fail() error { return errors.New(fmt.Sprint(1)) }\n",
+		},
+		{
+			name: "groups standard library and local imports",
+			src: `package pet
+
+import (
+	"api/internal/pkg/domain"
+	"context"
+	"github.com/google/uuid"
+	"time"
+	// goapigen:keep begin imports
+	"github.com/example/audit"
+	"errors"
+	// goapigen:keep end
+)
+
+var _ = []interface{}{domain.Pet{}, context.Background, uuid.New, time.Now, audit.Log, errors.New}
+`,
+			expected: `package pet
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"api/internal/pkg/domain"
+
+	// goapigen:keep begin imports
+	"errors"
+
+	"github.com/example/audit"
+	// goapigen:keep end
+)
+
+var _ = []interface{}{domain.Pet{}, context.Background, uuid.New, time.Now, audit.Log, errors.New}
+`,
+		},
+		{
+			name: "names packages after their import path",
+			src: `package api
+
+import (
+	"github.com/example/go-client"
+	"github.com/example/go-unused"
+	"github.com/go-chi/chi/v5"
+	"gopkg.in/unused.v2"
+	"gopkg.in/yaml.v3"
+)
+
+var _ = []interface{}{client.New, chi.NewRouter, yaml.Marshal}
+`,
+			expected: `package api
+
+import (
+	"github.com/example/go-client"
+	"github.com/go-chi/chi/v5"
+	"gopkg.in/yaml.v3"
+)
+
+var _ = []interface{}{client.New, chi.NewRouter, yaml.Marshal}
+`,
+		},
+		{
+			name: "ignores shadowed and unknown names",
+			src: `package api
+
+import (
+	"github.com/example/go-client"
+)
+
+func Path(url Location) string {
+	return url.Path + client.Name
+}
+`,
+			expected: `package api
+
+import (
+	"github.com/example/go-client"
+)
+
+func Path(url Location) string {
+	return url.Path + client.Name
+}
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formatted, err := Format([]byte(tt.src), "api")
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(formatted))
+		})
+	}
+}
+
+func TestFormat_SyntaxError(t *testing.T) {
+	src := "package api\n\nfunc Sum(a, b int) int {\n\treturn a +\n}\n\nfunc Other() {}\n"
+
+	_, err := Format([]byte(src), "api")
+	require.Error(t, err)
+
+	var syntaxErr *Error
+	require.True(t, errors.As(err, &syntaxErr))
+	assert.Equal(t, 5, syntaxErr.Line)
+	assert.Equal(t, 1, syntaxErr.Column)
+	assert.Contains(t, syntaxErr.Msg, "expected operand")
+	assert.Equal(t, "     3 | func Sum(a, b int) int {\n     4 | \treturn a +\n>    5 | }\n     6 |\n     7 | func Other() {}", syntaxErr.Context)
+	assert.Contains(t, err.Error(), "line 5:1: expected operand")
+}
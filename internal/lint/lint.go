@@ -0,0 +1,315 @@
+// Package lint reports the problems of an OpenAPI spec that break or degrade
+// the code goapigen generates from it, which the spec validation alone lets
+// through.
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/zeek-r/goapigen/internal/generator"
+	"github.com/zeek-r/goapigen/internal/parser"
+)
+
+// Rules reported by Lint
+const (
+	RuleMissingOperationID     = "missing-operation-id"
+	RuleUntaggedOperation      = "untagged-operation"
+	RuleTagSchemaMismatch      = "tag-schema-mismatch"
+	RuleUnsupportedComposition = "unsupported-composition"
+	RuleNameCollision          = "name-collision"
+)
+
+// Issue is a problem found in a spec
+type Issue struct {
+	Rule     string          `json:"rule"`
+	Message  string          `json:"message"`
+	Pointer  string          `json:"pointer"`
+	Location parser.Location `json:"location"`
+}
+
+// String formats the issue as file:line:column: message (rule)
+func (i Issue) String() string {
+	return fmt.Sprintf("%s: %s (%s)", i.Location, i.Message, i.Rule)
+}
+
+// Lint checks the spec of a parser and returns its issues ordered by location
+func Lint(p *parser.OpenAPIParser) []Issue {
+	l := &linter{parser: p}
+	l.checkOperations()
+	l.checkSchemas()
+
+	sort.SliceStable(l.issues, func(i, j int) bool {
+		a, b := l.issues[i].Location, l.issues[j].Location
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return l.issues
+}
+
+// linter collects the issues of a spec
+type linter struct {
+	parser *parser.OpenAPIParser
+	issues []Issue
+}
+
+// report adds an issue located at a JSON pointer
+func (l *linter) report(rule, pointer, format string, args ...interface{}) {
+	l.issues = append(l.issues, Issue{
+		Rule:     rule,
+		Message:  fmt.Sprintf(format, args...),
+		Pointer:  pointer,
+		Location: l.parser.Locate(pointer),
+	})
+}
+
+// operation is an operation along with where it is declared
+type operation struct {
+	*openapi3.Operation
+	method  string
+	path    string
+	pointer string
+}
+
+// String names the operation by its method and path
+func (o operation) String() string {
+	return o.method + " " + o.path
+}
+
+// operations returns the operations of the spec in path and method order
+func (l *linter) operations() []operation {
+	paths := l.parser.GetPaths()
+	names := make([]string, 0, len(paths))
+	for name := range paths {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var result []operation
+	for _, name := range names {
+		ops := paths[name].Operations()
+		methods := make([]string, 0, len(ops))
+		for method := range ops {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+		for _, method := range methods {
+			result = append(result, operation{
+				Operation: ops[method],
+				method:    method,
+				path:      name,
+				pointer:   parser.JSONPointer("paths", name, strings.ToLower(method)),
+			})
+		}
+	}
+	return result
+}
+
+// checkOperations reports operations the HTTP and service generators skip
+// or cannot tie to a domain type, and colliding operation and tag names
+func (l *linter) checkOperations() {
+	operationNames := newCollisions()
+	tagNames := newCollisions()
+	checkedTags := make(map[string]bool)
+
+	for _, op := range l.operations() {
+		if op.OperationID == "" {
+			l.report(RuleMissingOperationID, op.pointer, "operation %s has no operationId and is skipped by the HTTP generator", op)
+		} else if other, ok := operationNames.add(generator.ToPascalCase(op.OperationID), op.OperationID); ok {
+			l.report(RuleNameCollision, op.pointer+"/operationId", "operationId %s of %s collides with operationId %s: both generate %s names", op.OperationID, op, other, generator.ToPascalCase(op.OperationID))
+		}
+
+		if len(op.Tags) == 0 {
+			l.report(RuleUntaggedOperation, op.pointer, "operation %s has no tags, so it belongs to no domain and gets no HTTP handler", op)
+			continue
+		}
+
+		// Tags are reported at the first operation using them
+		tag := op.Tags[0]
+		if checkedTags[tag] {
+			continue
+		}
+		checkedTags[tag] = true
+
+		tagPointer := op.pointer + "/tags/0"
+		if other, ok := tagNames.add(generator.ToPascalCase(tag), tag); ok {
+			l.report(RuleNameCollision, tagPointer, "tag %s of %s collides with tag %s: both generate domain %s", tag, op, other, strings.ToLower(generator.ToPascalCase(tag)))
+		}
+
+		schemaName := generator.ToPascalCase(tag)
+		if _, ok := l.parser.GetSchemaByName(tag); ok {
+			continue
+		}
+		if _, ok := l.parser.GetSchemaByName(schemaName); ok {
+			l.report(RuleTagSchemaMismatch, tagPointer, "tag %s of %s only matches schema %s after PascalCase; CRUD operations are only detected for tags equal to the schema name", tag, op, schemaName)
+		} else {
+			l.report(RuleTagSchemaMismatch, tagPointer, "tag %s of %s matches no schema; the generated handlers expect a schema named %s", tag, op, schemaName)
+		}
+	}
+}
+
+// checkSchemas reports colliding type and field names and compositions the
+// type generator ignores
+func (l *linter) checkSchemas() {
+	schemas := l.parser.GetSchemas()
+	names := make([]string, 0, len(schemas))
+	for name := range schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	typeNames, typeFiles, domains := newCollisions(), newCollisions(), newCollisions()
+	for _, name := range names {
+		schema := schemas[name]
+		pointer := parser.JSONPointer("components", "schemas", name)
+		typeName := generator.GoTypeName(name, schema)
+
+		// Types sharing a name share their file as well
+		if other, ok := typeNames.add(typeName, name); ok {
+			l.report(RuleNameCollision, pointer, "schema %s collides with schema %s: both declare Go type %s", name, other, typeName)
+		} else if other, ok := typeFiles.add(generator.TypeFileName(typeName), name); ok {
+			l.report(RuleNameCollision, pointer, "schema %s collides with schema %s: both generate types file %s with --split-types", name, other, generator.TypeFileName(typeName))
+		}
+		if other, ok := domains.add(strings.ToLower(name), name); ok {
+			l.report(RuleNameCollision, pointer, "schema %s collides with schema %s: both generate the %s directory of the services, repositories and handlers", name, other, strings.ToLower(name))
+		}
+
+		l.checkSchema(typeName, schema, pointer, true)
+	}
+}
+
+// checkSchema checks a schema and the inline schemas nested in it. The
+// fields of inline allOf members are checked along with their struct.
+func (l *linter) checkSchema(typeName string, schema *openapi3.Schema, pointer string, fields bool) {
+	l.checkComposition(schema, pointer)
+
+	if fields && (len(schema.Properties) > 0 || len(schema.AllOf) > 0) {
+		l.checkFields(typeName, schema, pointer)
+	}
+
+	propNames := make([]string, 0, len(schema.Properties))
+	for propName := range schema.Properties {
+		propNames = append(propNames, propName)
+	}
+	sort.Strings(propNames)
+	for _, propName := range propNames {
+		l.checkInline(generator.HoistedTypeName(typeName, propName), schema.Properties[propName], pointer+parser.JSONPointer("properties", propName))
+	}
+
+	l.checkInline(typeName+"Item", schema.Items, pointer+"/items")
+	l.checkInline(typeName+"Value", schema.AdditionalProperties.Schema, pointer+"/additionalProperties")
+	for i, member := range schema.AllOf {
+		if member != nil && member.Ref == "" && member.Value != nil {
+			l.checkSchema(typeName, member.Value, pointer+"/allOf/"+strconv.Itoa(i), false)
+		}
+	}
+	for i, member := range schema.OneOf {
+		l.checkInline(typeName, member, pointer+"/oneOf/"+strconv.Itoa(i))
+	}
+	for i, member := range schema.AnyOf {
+		l.checkInline(typeName, member, pointer+"/anyOf/"+strconv.Itoa(i))
+	}
+}
+
+// checkInline checks a nested schema unless it references a component
+// schema, which is checked on its own
+func (l *linter) checkInline(typeName string, ref *openapi3.SchemaRef, pointer string) {
+	if ref == nil || ref.Ref != "" || ref.Value == nil {
+		return
+	}
+	l.checkSchema(typeName, ref.Value, pointer, true)
+}
+
+// checkComposition reports the parts of a composition the type generator
+// ignores
+func (l *linter) checkComposition(schema *openapi3.Schema, pointer string) {
+	if schema.Not != nil {
+		l.report(RuleUnsupportedComposition, pointer+"/not", "not is ignored by the type generator")
+	}
+
+	if len(schema.OneOf) > 0 && len(schema.AnyOf) > 0 {
+		l.report(RuleUnsupportedComposition, pointer+"/anyOf", "anyOf is ignored next to oneOf; only the oneOf variants are generated")
+	}
+	if len(schema.OneOf) > 0 || len(schema.AnyOf) > 0 {
+		if len(schema.Properties) > 0 {
+			l.report(RuleUnsupportedComposition, pointer+"/properties", "properties are ignored on a oneOf or anyOf union; move them into each variant")
+		}
+		if len(schema.AllOf) > 0 {
+			l.report(RuleUnsupportedComposition, pointer+"/allOf", "allOf is ignored on a oneOf or anyOf union")
+		}
+		return
+	}
+
+	for i, member := range schema.AllOf {
+		if member == nil || member.Value == nil {
+			continue
+		}
+		if !isObjectSchema(member.Value) {
+			l.report(RuleUnsupportedComposition, pointer+"/allOf/"+strconv.Itoa(i), "allOf member %s is not an object; only object members are merged into the generated struct", memberName(member, i))
+		}
+	}
+}
+
+// checkFields reports properties of a struct schema generating the same
+// field name
+func (l *linter) checkFields(typeName string, schema *openapi3.Schema, pointer string) {
+	properties, _ := generator.SchemaProperties(typeName, schema)
+	propNames := make([]string, 0, len(properties))
+	for propName, prop := range properties {
+		// Properties promoted from embedded members are checked on their schema
+		if prop.Owner == typeName {
+			propNames = append(propNames, propName)
+		}
+	}
+	sort.Strings(propNames)
+
+	fieldNames := newCollisions()
+	for _, propName := range propNames {
+		fieldName := generator.GoFieldName(propName, properties[propName].Ref)
+		if other, ok := fieldNames.add(fieldName, propName); ok {
+			l.report(RuleNameCollision, pointer+parser.JSONPointer("properties", propName), "property %s collides with property %s: both generate field %s.%s", propName, other, typeName, fieldName)
+		}
+	}
+}
+
+// isObjectSchema reports whether an allOf member can be merged into a struct
+func isObjectSchema(schema *openapi3.Schema) bool {
+	if len(schema.OneOf) > 0 || len(schema.AnyOf) > 0 {
+		return false
+	}
+	return schema.Type == "object" || schema.Type == "" && (len(schema.Properties) > 0 || len(schema.AllOf) > 0)
+}
+
+// memberName names a composition member in messages
+func memberName(member *openapi3.SchemaRef, index int) string {
+	if member.Ref != "" {
+		return generator.SchemaRefName(member.Ref)
+	}
+	return "#" + strconv.Itoa(index)
+}
+
+// collisions tracks the spec names generating the same Go name
+type collisions map[string]string
+
+func newCollisions() collisions {
+	return make(collisions)
+}
+
+// add records that name generates goName and returns the name that
+// generated it first, if another one did
+func (c collisions) add(goName, name string) (string, bool) {
+	other, ok := c[goName]
+	if !ok {
+		c[goName] = name
+		return "", false
+	}
+	return other, other != name
+}
@@ -0,0 +1,182 @@
+package lint
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zeek-r/goapigen/internal/parser"
+	"github.com/zeek-r/goapigen/internal/testutil"
+)
+
+// lintSpec breaks every rule once, with one operation and one schema in
+// referenced files
+var lintSpec = map[string]string{
+	"openapi.yaml": `openapi: 3.0.3
+info: {title: Lint, version: "1"}
+paths:
+  /pets:
+    get:
+      tags: [Pet]
+      responses:
+        "200": {description: OK}
+    post:
+      operationId: createPet
+      tags: [pet]
+      responses:
+        "201": {description: Created}
+  /pets/{id}:
+    $ref: paths/pet.yaml
+  /health:
+    get:
+      operationId: health
+      responses:
+        "200": {description: OK}
+components:
+  schemas:
+    Pet:
+      type: object
+      properties:
+        pet_id: {type: string}
+        petId: {type: string}
+        owner: {$ref: schemas/owner.yaml}
+    pet:
+      type: object
+    Shape:
+      oneOf:
+        - {type: string}
+        - {type: integer}
+      anyOf:
+        - {type: string}
+    Combo:
+      allOf:
+        - $ref: '#/components/schemas/Status'
+        - type: object
+          properties:
+            a: {type: string}
+      not: {type: string}
+    Status: {type: string, enum: [a]}
+`,
+	"paths/pet.yaml": `get:
+  operationId: getPet
+  tags: [Pets]
+  parameters:
+    - {in: path, name: id, required: true, schema: {type: string}}
+  responses:
+    "200": {description: OK}
+`,
+	"schemas/owner.yaml": `type: object
+properties:
+  Name: {type: string}
+  name: {type: string}
+`,
+}
+
+func TestLint(t *testing.T) {
+	dir := testutil.CreateTempTree(t, lintSpec)
+	specFile := filepath.Join(dir, "openapi.yaml")
+	apiParser, err := parser.NewOpenAPIParser(specFile)
+	require.NoError(t, err)
+
+	type found struct {
+		rule     string
+		pointer  string
+		location string
+	}
+	var issues []found
+	for _, issue := range Lint(apiParser) {
+		location := issue.Location.String()
+		rel, err := filepath.Rel(dir, issue.Location.File)
+		require.NoError(t, err)
+		location = rel + location[len(issue.Location.File):]
+		issues = append(issues, found{issue.Rule, issue.Pointer, location})
+	}
+
+	assert.Equal(t, []found{
+		{RuleMissingOperationID, "/paths/~1pets/get", "openapi.yaml:5:5"},
+		{RuleNameCollision, "/paths/~1pets/post/tags/0", "openapi.yaml:11:14"},
+		{RuleUntaggedOperation, "/paths/~1health/get", "openapi.yaml:17:5"},
+		{RuleNameCollision, "/components/schemas/Pet/properties/pet_id", "openapi.yaml:26:9"},
+		{RuleNameCollision, "/components/schemas/pet", "openapi.yaml:29:5"},
+		{RuleNameCollision, "/components/schemas/pet", "openapi.yaml:29:5"},
+		{RuleUnsupportedComposition, "/components/schemas/Shape/anyOf", "openapi.yaml:35:7"},
+		{RuleUnsupportedComposition, "/components/schemas/Combo/allOf/0", "openapi.yaml:39:11"},
+		{RuleUnsupportedComposition, "/components/schemas/Combo/not", "openapi.yaml:43:7"},
+		{RuleTagSchemaMismatch, "/paths/~1pets~1{id}/get/tags/0", "paths/pet.yaml:3:10"},
+		{RuleNameCollision, "/components/schemas/Owner/properties/name", "schemas/owner.yaml:4:3"},
+	}, issues)
+}
+
+func TestLint_TagCase(t *testing.T) {
+	spec := `openapi: 3.0.3
+info: {title: Lint, version: "1"}
+paths:
+  /pets:
+    get:
+      operationId: listPets
+      tags: [pet]
+      responses:
+        "200": {description: OK}
+components:
+  schemas:
+    Pet: {type: object}
+`
+	apiParser, err := parser.NewOpenAPIParser(testutil.CreateTempFile(t, "openapi.yaml", spec))
+	require.NoError(t, err)
+
+	issues := Lint(apiParser)
+	require.Len(t, issues, 1)
+	assert.Equal(t, RuleTagSchemaMismatch, issues[0].Rule)
+	assert.Contains(t, issues[0].Message, "only matches schema Pet after PascalCase")
+	assert.Equal(t, 7, issues[0].Location.Line)
+}
+
+func TestLint_SchemaCollisions(t *testing.T) {
+	tests := []struct {
+		name    string
+		schemas string
+		message string
+	}{
+		{
+			name: "go_type",
+			schemas: `    Pet: {type: object}
+    Animal: {type: object, x-go-name: Pet}
+`,
+			message: "schema Pet collides with schema Animal: both declare Go type Pet",
+		},
+		{
+			name: "types_file",
+			schemas: `    PetItem: {type: object}
+    pet_item: {type: object}
+`,
+			message: "schema pet_item collides with schema PetItem: both generate types file pet_item.go with --split-types",
+		},
+		{
+			name: "directory",
+			schemas: `    PetItem: {type: object}
+    Petitem: {type: object}
+`,
+			message: "schema Petitem collides with schema PetItem: both generate the petitem directory of the services, repositories and handlers",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := "openapi: 3.0.3\ninfo: {title: Lint, version: \"1\"}\npaths: {}\ncomponents:\n  schemas:\n" + tt.schemas
+			apiParser, err := parser.NewOpenAPIParser(testutil.CreateTempFile(t, "openapi.yaml", spec))
+			require.NoError(t, err)
+
+			issues := Lint(apiParser)
+			require.Len(t, issues, 1)
+			assert.Equal(t, RuleNameCollision, issues[0].Rule)
+			assert.Equal(t, tt.message, issues[0].Message)
+		})
+	}
+}
+
+func TestLint_Clean(t *testing.T) {
+	apiParser, err := parser.NewOpenAPIParser(testutil.CreateTempFile(t, "openapi.yaml", testutil.SimpleOpenAPISpec()))
+	require.NoError(t, err)
+	assert.Empty(t, Lint(apiParser))
+}
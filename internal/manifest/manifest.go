@@ -0,0 +1,86 @@
+// Package manifest records the files goapigen generated in a project, so
+// that regeneration can delete the files the spec no longer produces and
+// tell the generated files edited by hand since.
+package manifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Location of the manifest in the output directory
+const (
+	Dir  = ".goapigen"
+	File = "manifest.json"
+)
+
+// Manifest lists the generated files of a project by slash-separated path
+// relative to the output directory
+type Manifest struct {
+	Files map[string]Entry `json:"files"`
+}
+
+// Entry records a generated file as it was written
+type Entry struct {
+	// Scope is the part of the generation the file belongs to, e.g. http
+	Scope string `json:"scope"`
+	// Hash of the content written, see Hash
+	Hash string `json:"hash"`
+	// Generator is the version of goapigen that wrote the file
+	Generator string `json:"generator"`
+}
+
+// Path returns the path of the manifest of an output directory
+func Path(outputDir string) string {
+	return filepath.Join(outputDir, Dir, File)
+}
+
+// Load reads the manifest of an output directory. A directory without one
+// has an empty manifest.
+func Load(outputDir string) (*Manifest, error) {
+	m := &Manifest{Files: make(map[string]Entry)}
+	data, err := os.ReadFile(Path(outputDir))
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", Path(outputDir), err)
+	}
+	if m.Files == nil {
+		m.Files = make(map[string]Entry)
+	}
+	return m, nil
+}
+
+// Save writes the manifest to an output directory. Files are listed in path
+// order, so that the manifest diffs well under version control.
+func (m *Manifest) Save(outputDir string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Join(outputDir, Dir), 0755); err != nil {
+		return fmt.Errorf("failed to create manifest directory: %w", err)
+	}
+	return os.WriteFile(Path(outputDir), append(data, '\n'), 0644)
+}
+
+// Edited reports whether content differs from the content recorded for a
+// file. Files missing from the manifest are not known to be edited.
+func (m *Manifest) Edited(path string, content []byte) bool {
+	entry, ok := m.Files[path]
+	return ok && entry.Hash != Hash(content)
+}
+
+// Hash returns the hash recorded for content
+func Hash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,48 @@
+package manifest
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	// A directory without manifest has an empty one
+	m, err := Load(dir)
+	require.NoError(t, err)
+	assert.Empty(t, m.Files)
+
+	content := []byte("package pet\n")
+	m.Files["internal/adapters/http/pet/getpet_handler.go"] = Entry{Scope: "http", Hash: Hash(content), Generator: "v1.2.0"}
+	m.Files["internal/pkg/domain/types.go"] = Entry{Scope: "types", Hash: Hash([]byte("package domain\n")), Generator: "v1.2.0"}
+	require.NoError(t, m.Save(dir))
+
+	reloaded, err := Load(dir)
+	require.NoError(t, err)
+	assert.Equal(t, m, reloaded)
+
+	assert.False(t, reloaded.Edited("internal/adapters/http/pet/getpet_handler.go", content))
+	assert.True(t, reloaded.Edited("internal/adapters/http/pet/getpet_handler.go", []byte("package pet // edited\n")))
+	assert.False(t, reloaded.Edited("internal/adapters/http/pet/handler.go", content), "Unknown files are not edited")
+
+	// Files are listed in path order
+	data, err := os.ReadFile(Path(dir))
+	require.NoError(t, err)
+	assert.Less(t, bytes.Index(data, []byte("internal/adapters")), bytes.Index(data, []byte("internal/pkg")))
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, Dir), 0755))
+	require.NoError(t, os.WriteFile(Path(dir), []byte("{"), 0644))
+
+	_, err := Load(dir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), File)
+}
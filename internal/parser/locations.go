@@ -0,0 +1,225 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Location is a position in a spec document
+type Location struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+// String formats the location as file:line:column
+func (l Location) String() string {
+	if l.Line == 0 {
+		return l.File
+	}
+	return fmt.Sprintf("%s:%d:%d", l.File, l.Line, l.Column)
+}
+
+// Locate returns where the node at a JSON pointer of the merged document,
+// e.g. /paths/~1pets/post, is declared. Nodes without a known position, such
+// as those created while converting Swagger 2.0, report the position of the
+// closest enclosing node.
+func (p *OpenAPIParser) Locate(pointer string) Location {
+	for {
+		if location, ok := p.locations[pointer]; ok {
+			return location
+		}
+		if pointer == "" {
+			return Location{}
+		}
+		pointer = pointer[:strings.LastIndex(pointer, "/")]
+	}
+}
+
+// JSONPointer joins path segments into a JSON pointer
+func JSONPointer(segments ...string) string {
+	var pointer strings.Builder
+	for _, segment := range segments {
+		pointer.WriteString("/")
+		pointer.WriteString(strings.NewReplacer("~", "~0", "/", "~1").Replace(segment))
+	}
+	return pointer.String()
+}
+
+// locationIndex maps the JSON pointers of a merged document to the
+// positions they are declared at. The first document declaring a node wins,
+// as when merging.
+type locationIndex map[string]Location
+
+// add indexes a document read from a source. The files it references are
+// indexed under the pointers of the references when they are on disk.
+func (index locationIndex) add(name string, data []byte, documentPath string) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil || len(root.Content) == 0 {
+		return
+	}
+
+	indexer := &locationIndexer{
+		index:        index,
+		aliases:      make(locationIndex),
+		files:        make(map[string]*yaml.Node),
+		active:       make(map[string]bool),
+		rootName:     name,
+		documentPath: documentPath,
+	}
+	indexer.walk(root.Content[0], "", name, documentPath)
+
+	// Schemas declared in the components take precedence over those moved there
+	for pointer, location := range indexer.aliases {
+		if _, ok := index[pointer]; !ok {
+			index[pointer] = location
+		}
+	}
+}
+
+// locationIndexer walks the YAML nodes of documents
+type locationIndexer struct {
+	index locationIndex
+	files map[string]*yaml.Node
+
+	// aliases holds the positions of the schemas internalizeRefs moves to
+	// the components
+	aliases locationIndex
+
+	// active guards against references cycling through files
+	active map[string]bool
+
+	// Referenced files are named relative to the document as given
+	rootName     string
+	documentPath string
+}
+
+// walk records the position of node and of the nodes below it. documentPath
+// is the path of the file on disk, or empty when references cannot be read.
+func (x *locationIndexer) walk(node *yaml.Node, pointer, file, documentPath string) {
+	x.record(pointer, Location{File: file, Line: node.Line, Column: node.Column})
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if key.Value == "$ref" {
+				x.follow(value.Value, pointer, documentPath)
+				continue
+			}
+			child := pointer + JSONPointer(key.Value)
+			x.record(child, Location{File: file, Line: key.Line, Column: key.Column})
+			x.walk(value, child, file, documentPath)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			x.walk(item, pointer+"/"+strconv.Itoa(i), file, documentPath)
+		}
+	}
+}
+
+// follow indexes the target of an external reference under the pointer of
+// the reference. Schemas moved to the components by internalizeRefs are
+// indexed under their component name too.
+func (x *locationIndexer) follow(ref, pointer, documentPath string) {
+	if !isExternalRef(ref) || documentPath == "" {
+		return
+	}
+
+	file, fragment, _ := strings.Cut(ref, "#")
+	targetPath := path.Join(path.Dir(documentPath), file)
+	if x.active[targetPath+"#"+fragment] {
+		return
+	}
+
+	target := x.load(targetPath)
+	for _, segment := range strings.Split(strings.Trim(fragment, "/"), "/") {
+		if target == nil || segment == "" {
+			break
+		}
+		target = mappingValue(target, strings.NewReplacer("~1", "/", "~0", "~").Replace(segment))
+	}
+	if target == nil {
+		return
+	}
+
+	x.active[targetPath+"#"+fragment] = true
+	defer delete(x.active, targetPath+"#"+fragment)
+
+	if isSchemaPointer(pointer) {
+		aliases := *x
+		aliases.index = x.aliases
+		aliases.walk(target, JSONPointer("components", "schemas", refSchemaName(ref)), x.displayPath(targetPath), targetPath)
+	}
+	x.walk(target, pointer, x.displayPath(targetPath), targetPath)
+}
+
+// load parses a referenced file once
+func (x *locationIndexer) load(filePath string) *yaml.Node {
+	if node, ok := x.files[filePath]; ok {
+		return node
+	}
+
+	var root yaml.Node
+	x.files[filePath] = nil
+	if data, err := os.ReadFile(filepath.FromSlash(filePath)); err == nil {
+		if yaml.Unmarshal(data, &root) == nil && len(root.Content) > 0 {
+			x.files[filePath] = root.Content[0]
+		}
+	}
+	return x.files[filePath]
+}
+
+// record keeps the first position of a pointer. Swagger 2.0 definitions are
+// known under their OpenAPI 3 pointer as well.
+func (x *locationIndexer) record(pointer string, location Location) {
+	if _, ok := x.index[pointer]; !ok {
+		x.index[pointer] = location
+	}
+	if rest, ok := strings.CutPrefix(pointer, "/definitions/"); ok {
+		x.record("/components/schemas/"+rest, location)
+	}
+}
+
+// displayPath names a referenced file as its reference does, relative to the
+// name the document was given by, e.g. api/schemas/pet.yaml for a reference
+// to schemas/pet.yaml in api/openapi.yaml
+func (x *locationIndexer) displayPath(filePath string) string {
+	rel, err := filepath.Rel(filepath.Dir(filepath.FromSlash(x.documentPath)), filepath.FromSlash(filePath))
+	if err != nil {
+		return filePath
+	}
+	return filepath.Join(filepath.Dir(x.rootName), rel)
+}
+
+// mappingValue returns the value of a key of a mapping node
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// isSchemaPointer reports whether a pointer addresses a schema
+func isSchemaPointer(pointer string) bool {
+	if strings.HasPrefix(pointer, "/components/schemas/") || strings.HasPrefix(pointer, "/definitions/") {
+		return true
+	}
+	for _, keyword := range []string{"/schema", "/items", "/properties/", "/additionalProperties", "/allOf/", "/anyOf/", "/oneOf/", "/not"} {
+		if strings.Contains(pointer, keyword) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package parser
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zeek-r/goapigen/internal/testutil"
+)
+
+func TestOpenAPIParser_Locate(t *testing.T) {
+	dir := testutil.CreateTempTree(t, splitSpec)
+	root := filepath.Join(dir, "openapi.yaml")
+
+	parser, err := NewOpenAPIParser(root)
+	require.NoError(t, err)
+
+	tests := []struct {
+		pointer string
+		want    Location
+	}{
+		{"", Location{File: root, Line: 2, Column: 1}},
+		{JSONPointer("paths", "/pets"), Location{File: root, Line: 5, Column: 3}},
+		{JSONPointer("paths", "/pets", "post"), Location{File: filepath.Join(dir, "paths", "pets.yaml"), Line: 2, Column: 1}},
+		{JSONPointer("paths", "/pets", "post", "operationId"), Location{File: filepath.Join(dir, "paths", "pets.yaml"), Line: 3, Column: 3}},
+		// Schemas moved to the components are found in their own file
+		{JSONPointer("components", "schemas", "Pet", "properties", "name"), Location{File: filepath.Join(dir, "schemas", "pet.yaml"), Line: 5, Column: 3}},
+		{JSONPointer("components", "schemas", "Owner", "properties"), Location{File: filepath.Join(dir, "schemas", "people.yaml"), Line: 4, Column: 3}},
+		// Unknown nodes report their closest known parent
+		{JSONPointer("components", "schemas", "Error", "x-unknown", "deeper"), Location{File: root, Line: 9, Column: 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pointer, func(t *testing.T) {
+			assert.Equal(t, tt.want, parser.Locate(tt.pointer))
+		})
+	}
+}
+
+func TestOpenAPIParser_LocateSwaggerDefinitions(t *testing.T) {
+	spec := `swagger: "2.0"
+info: {title: Pets, version: "1"}
+paths: {}
+definitions:
+  Pet:
+    type: object
+`
+	parser, err := NewOpenAPIParserFromSources(&ReaderSource{Name: "<stdin>", Reader: strings.NewReader(spec)})
+	require.NoError(t, err)
+
+	assert.Equal(t, "<stdin>:5:3", parser.Locate(JSONPointer("components", "schemas", "Pet")).String())
+}
+
+func TestJSONPointer(t *testing.T) {
+	assert.Equal(t, "/paths/~1pets~1{id}/get", JSONPointer("paths", "/pets/{id}", "get"))
+	assert.Equal(t, "/a~0b", JSONPointer("a~b"))
+	assert.Equal(t, "", JSONPointer())
+}
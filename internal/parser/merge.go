@@ -0,0 +1,206 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// mergeOrigins records the spec file declaring each schema, operationId and
+// path operation, so conflicts name both files
+type mergeOrigins struct {
+	schemas    map[string]string
+	operations map[string]string
+	routes     map[string]string // Keyed by method and path, e.g. "GET /pets"
+}
+
+// newMergeOrigins creates empty merge origins
+func newMergeOrigins() *mergeOrigins {
+	return &mergeOrigins{
+		schemas:    make(map[string]string),
+		operations: make(map[string]string),
+		routes:     make(map[string]string),
+	}
+}
+
+// record notes file as the origin of the schemas and operations of doc
+// that have no origin yet
+func (o *mergeOrigins) record(doc *openapi3.T, file string) {
+	if doc.Components != nil {
+		for name := range doc.Components.Schemas {
+			if _, ok := o.schemas[name]; !ok {
+				o.schemas[name] = file
+			}
+		}
+	}
+	for _, operation := range documentOperations(doc) {
+		if _, ok := o.operations[operation.OperationID]; !ok && operation.OperationID != "" {
+			o.operations[operation.OperationID] = file
+		}
+	}
+	if doc.Paths != nil {
+		for pathName, pathItem := range doc.Paths.Map() {
+			for method := range pathItem.Operations() {
+				if _, ok := o.routes[method+" "+pathName]; !ok {
+					o.routes[method+" "+pathName] = file
+				}
+			}
+		}
+	}
+}
+
+// mergeDocuments merges the paths, components and tags of from, loaded from
+// file, into into. Schemas and other components declared in both must be
+// identical, and operationIds and path operations must be unique.
+func mergeDocuments(into, from *openapi3.T, file string, origins *mergeOrigins) error {
+	for _, operation := range documentOperations(from) {
+		if origin, ok := origins.operations[operation.OperationID]; ok {
+			return fmt.Errorf("operationId %s is already declared in %s", operation.OperationID, origin)
+		}
+	}
+
+	if from.Paths != nil {
+		if into.Paths == nil {
+			into.Paths = openapi3.NewPaths()
+		}
+		for _, pathName := range from.Paths.InMatchingOrder() {
+			if err := mergePathItem(into.Paths, pathName, from.Paths.Value(pathName), origins); err != nil {
+				return err
+			}
+		}
+	}
+
+	if from.Components != nil {
+		if into.Components == nil {
+			into.Components = &openapi3.Components{}
+		}
+		if err := mergeComponents(into.Components, from.Components, origins); err != nil {
+			return err
+		}
+	}
+
+	for _, tag := range from.Tags {
+		if tag != nil && into.Tags.Get(tag.Name) == nil {
+			into.Tags = append(into.Tags, tag)
+		}
+	}
+
+	origins.record(from, file)
+	return nil
+}
+
+// mergePathItem adds the operations of item to the path named pathName.
+// Parameters declared on item are copied into its operations, as the path
+// may already declare different ones.
+func mergePathItem(paths *openapi3.Paths, pathName string, item *openapi3.PathItem, origins *mergeOrigins) error {
+	existing := paths.Value(pathName)
+	if existing == nil {
+		paths.Set(pathName, item)
+		return nil
+	}
+
+	operations := item.Operations()
+	for _, method := range sortedKeys(operations) {
+		if existing.GetOperation(method) != nil {
+			return fmt.Errorf("operation %s %s is already declared in %s", method, pathName, origins.routes[method+" "+pathName])
+		}
+
+		operation := operations[method]
+		parameters := append(openapi3.Parameters{}, item.Parameters...)
+		operation.Parameters = append(parameters, operation.Parameters...)
+		existing.SetOperation(method, operation)
+	}
+	return nil
+}
+
+// mergeComponents adds the components of from to into
+func mergeComponents(into, from *openapi3.Components, origins *mergeOrigins) error {
+	if into.Schemas == nil && len(from.Schemas) > 0 {
+		into.Schemas = make(openapi3.Schemas)
+	}
+	for _, name := range sortedKeys(from.Schemas) {
+		existing, ok := into.Schemas[name]
+		if !ok {
+			into.Schemas[name] = from.Schemas[name]
+			continue
+		}
+		if !sameJSON(existing, from.Schemas[name]) {
+			return fmt.Errorf("schema %s differs from the one declared in %s", name, origins.schemas[name])
+		}
+	}
+
+	var err error
+	if into.Parameters, err = mergeComponentMap("parameter", into.Parameters, from.Parameters); err != nil {
+		return err
+	}
+	if into.Headers, err = mergeComponentMap("header", into.Headers, from.Headers); err != nil {
+		return err
+	}
+	if into.RequestBodies, err = mergeComponentMap("request body", into.RequestBodies, from.RequestBodies); err != nil {
+		return err
+	}
+	if into.Responses, err = mergeComponentMap("response", into.Responses, from.Responses); err != nil {
+		return err
+	}
+	if into.SecuritySchemes, err = mergeComponentMap("security scheme", into.SecuritySchemes, from.SecuritySchemes); err != nil {
+		return err
+	}
+	if into.Examples, err = mergeComponentMap("example", into.Examples, from.Examples); err != nil {
+		return err
+	}
+	if into.Links, err = mergeComponentMap("link", into.Links, from.Links); err != nil {
+		return err
+	}
+	if into.Callbacks, err = mergeComponentMap("callback", into.Callbacks, from.Callbacks); err != nil {
+		return err
+	}
+	return nil
+}
+
+// mergeComponentMap adds the components of from to into, which must declare
+// shared names identically, and returns the merged map
+func mergeComponentMap[M ~map[string]V, V any](kind string, into, from M) (M, error) {
+	if into == nil && len(from) > 0 {
+		into = make(M, len(from))
+	}
+	for _, name := range sortedKeys(from) {
+		existing, ok := into[name]
+		if !ok {
+			into[name] = from[name]
+			continue
+		}
+		if !sameJSON(existing, from[name]) {
+			return nil, fmt.Errorf("%s %s is declared differently in an earlier spec", kind, name)
+		}
+	}
+	return into, nil
+}
+
+// sameJSON reports whether two values encode to the same JSON
+func sameJSON(a, b interface{}) bool {
+	aJSON, aErr := json.Marshal(a)
+	bJSON, bErr := json.Marshal(b)
+	return aErr == nil && bErr == nil && bytes.Equal(aJSON, bJSON)
+}
+
+// documentOperations returns the operations of doc in path and method order
+func documentOperations(doc *openapi3.T) []*openapi3.Operation {
+	var result []*openapi3.Operation
+	if doc.Paths == nil {
+		return result
+	}
+
+	for _, pathName := range doc.Paths.InMatchingOrder() {
+		pathItem := doc.Paths.Value(pathName)
+		if pathItem == nil {
+			continue
+		}
+		operations := pathItem.Operations()
+		for _, method := range sortedKeys(operations) {
+			result = append(result, operations[method])
+		}
+	}
+	return result
+}
@@ -0,0 +1,176 @@
+package parser
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zeek-r/goapigen/internal/testutil"
+)
+
+const petsSpec = `
+openapi: 3.0.3
+info: {title: Pets, version: "1"}
+tags: [{name: Pet}]
+paths:
+  /pets:
+    get:
+      operationId: listPets
+      tags: [Pet]
+      responses:
+        "200": {description: OK}
+components:
+  schemas:
+    Pet:
+      type: object
+      properties:
+        name: {type: string}
+    Error:
+      type: object
+      properties:
+        message: {type: string}
+`
+
+const ordersSpec = `
+openapi: 3.0.3
+info: {title: Orders, version: "1"}
+tags: [{name: Order}, {name: Pet}]
+paths:
+  /pets:
+    parameters:
+      - {name: shop, in: query, schema: {type: string}}
+    post:
+      operationId: createPet
+      tags: [Pet]
+      responses:
+        "201": {description: Created}
+  /orders:
+    get:
+      operationId: listOrders
+      tags: [Order]
+      responses:
+        "200": {description: OK}
+components:
+  schemas:
+    Order:
+      type: object
+      properties:
+        pet: {$ref: '#/components/schemas/Pet'}
+    Pet:
+      type: object
+      properties:
+        name: {type: string}
+    Error:
+      type: object
+      properties:
+        message: {type: string}
+`
+
+func TestNewOpenAPIParser_MergesSpecs(t *testing.T) {
+	dir := testutil.CreateTempTree(t, map[string]string{
+		"pets.yaml":   petsSpec,
+		"orders.yaml": ordersSpec,
+	})
+
+	parser, err := NewOpenAPIParser(filepath.Join(dir, "pets.yaml"), filepath.Join(dir, "orders.yaml"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "Pets", parser.GetInfo().Title, "the first spec provides the info")
+	assert.Len(t, parser.GetSchemas(), 3)
+	assert.Len(t, parser.GetOperations(), 3)
+	assert.Len(t, parser.Doc.Tags, 2)
+
+	pets := parser.GetPaths()["/pets"]
+	require.NotNil(t, pets.Get)
+	require.NotNil(t, pets.Post)
+	require.Len(t, pets.Post.Parameters, 1, "path parameters move into merged operations")
+	assert.Equal(t, "shop", pets.Post.Parameters[0].Value.Name)
+	assert.Empty(t, pets.Get.Parameters)
+}
+
+func TestNewOpenAPIParser_Directory(t *testing.T) {
+	dir := testutil.CreateTempTree(t, map[string]string{
+		"orders.yaml": ordersSpec,
+		"pets.yaml":   petsSpec,
+		"pet.yaml":    "type: object\n",
+		"README.md":   "# API\n",
+	})
+
+	parser, err := NewOpenAPIParser(dir)
+	require.NoError(t, err)
+
+	// Documents are merged in name order and fragments are skipped
+	assert.Equal(t, "Orders", parser.GetInfo().Title)
+	assert.Len(t, parser.GetOperations(), 3)
+
+	_, err = NewOpenAPIParser(t.TempDir())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no OpenAPI documents found")
+}
+
+func TestNewOpenAPIParser_MergeConflicts(t *testing.T) {
+	tests := []struct {
+		name   string
+		spec   string
+		errMsg string
+	}{
+		{
+			name: "duplicate operationId",
+			spec: `
+openapi: 3.0.3
+info: {title: Dup, version: "1"}
+paths:
+  /animals:
+    get:
+      operationId: listPets
+      responses:
+        "200": {description: OK}
+`,
+			errMsg: "operationId listPets is already declared in",
+		},
+		{
+			name: "duplicate operation",
+			spec: `
+openapi: 3.0.3
+info: {title: Dup, version: "1"}
+paths:
+  /pets:
+    get:
+      operationId: findPets
+      responses:
+        "200": {description: OK}
+`,
+			errMsg: "operation GET /pets is already declared",
+		},
+		{
+			name: "conflicting schema",
+			spec: `
+openapi: 3.0.3
+info: {title: Dup, version: "1"}
+paths: {}
+components:
+  schemas:
+    Pet:
+      type: object
+      properties:
+        id: {type: integer}
+`,
+			errMsg: "schema Pet differs from the one declared in",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := testutil.CreateTempTree(t, map[string]string{
+				"pets.yaml":  petsSpec,
+				"other.yaml": tt.spec,
+			})
+
+			_, err := NewOpenAPIParser(filepath.Join(dir, "pets.yaml"), filepath.Join(dir, "other.yaml"))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errMsg)
+			assert.Contains(t, err.Error(), "pets.yaml")
+		})
+	}
+}
@@ -1,7 +1,10 @@
 package parser
 
 import (
+	"context"
 	"fmt"
+	"net/http"
+	"net/url"
 	"path/filepath"
 
 	"github.com/getkin/kin-openapi/openapi3"
@@ -10,33 +13,133 @@ import (
 // OpenAPIParser represents a parser for OpenAPI specifications
 type OpenAPIParser struct {
 	Doc *openapi3.T // Exported for testing
+
+	locations locationIndex
+	files     sourceFiles
+}
+
+// NewOpenAPIParser creates a new OpenAPI parser from the specified specs,
+// each a path or another source accepted by ParseSpecSource. Relative
+// external $refs are resolved and moved into the components of the document.
+// A directory stands for the root documents at its top level, and several
+// documents are merged into one, failing on conflicting definitions.
+func NewOpenAPIParser(specs ...string) (*OpenAPIParser, error) {
+	sources, err := expandSpecs(specs)
+	if err != nil {
+		return nil, err
+	}
+	return NewOpenAPIParserFromSources(sources...)
+}
+
+// NewOpenAPIParserFromSources creates a new OpenAPI parser from documents
+// read from the given sources, merged in order
+func NewOpenAPIParserFromSources(sources ...SpecSource) (*OpenAPIParser, error) {
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no OpenAPI specification given")
+	}
+
+	var doc *openapi3.T
+	origins := newMergeOrigins()
+	locations := make(locationIndex)
+	files := make(sourceFiles)
+	for _, source := range sources {
+		loaded, err := loadSpec(source, locations, files)
+		if err != nil {
+			return nil, err
+		}
+
+		if doc == nil {
+			doc = loaded
+			origins.record(doc, source.String())
+			continue
+		}
+		if err := mergeDocuments(doc, loaded, source.String(), origins); err != nil {
+			return nil, fmt.Errorf("failed to merge %s: %w", source, err)
+		}
+	}
+
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI specification: %w", err)
+	}
+
+	return &OpenAPIParser{Doc: doc, locations: locations, files: files}, nil
+}
+
+// Files returns the files on disk the spec was read from: the documents
+// given and the files their references point to, in order
+func (p *OpenAPIParser) Files() []string {
+	return sortedKeys(p.files)
+}
+
+// sourceFiles collects the paths of the files documents are read from
+type sourceFiles map[string]bool
+
+// reader wraps a reader of referenced documents to record the files it reads
+func (files sourceFiles) reader(read openapi3.ReadFromURIFunc) openapi3.ReadFromURIFunc {
+	return func(loader *openapi3.Loader, location *url.URL) ([]byte, error) {
+		if location.Scheme == "" || location.Scheme == "file" {
+			files[filepath.FromSlash(location.Path)] = true
+		}
+		return read(loader, location)
+	}
 }
 
-// NewOpenAPIParser creates a new OpenAPI parser from the specified file path
-func NewOpenAPIParser(filePath string) (*OpenAPIParser, error) {
-	ext := filepath.Ext(filePath)
+// loadSpec loads a single document, resolving its external references, and
+// indexes the positions of its nodes. The files read are added to files.
+func loadSpec(source SpecSource, locations locationIndex, files sourceFiles) (*openapi3.T, error) {
+	data, location, err := source.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load OpenAPI spec: %w", err)
+	}
+	if file, ok := source.(*FileSource); ok {
+		absPath, err := filepath.Abs(file.Path)
+		if err != nil {
+			return nil, err
+		}
+		files[absPath] = true
+	}
+
+	format, err := sniffFormat(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load OpenAPI spec %s: %w", source, err)
+	}
+
+	// Referenced files are read anew by every load, not from the process-wide
+	// cache of kin-openapi, so edited files are picked up
+	readURI := files.reader(openapi3.URIMapCache(openapi3.ReadFromURIs(openapi3.ReadFromHTTP(http.DefaultClient), openapi3.ReadFromFile)))
+
 	loader := openapi3.NewLoader()
-	
+	loader.IsExternalRefsAllowed = true
+	loader.ReadFromURIFunc = readURI
+
 	var doc *openapi3.T
-	var err error
-	
-	// Handle both JSON and YAML formats
-	switch ext {
-	case ".json", ".yaml", ".yml":
-		doc, err = loader.LoadFromFile(filePath)
+	switch version := detectSpecVersion(data); {
+	case version.isSwagger2():
+		doc, err = loadSwagger2(loader, data, format, location)
+	case version.isOpenAPI31():
+		loader.ReadFromURIFunc = normalizingReader(readURI)
+		if data, err = normalizeOpenAPI31(data); err == nil {
+			doc, err = loader.LoadFromDataWithPath(data, location)
+		}
 	default:
-		return nil, fmt.Errorf("unsupported file extension: %s", ext)
+		doc, err = loader.LoadFromDataWithPath(data, location)
 	}
-	
 	if err != nil {
-		return nil, fmt.Errorf("failed to load OpenAPI spec: %w", err)
+		return nil, fmt.Errorf("failed to load OpenAPI spec %s: %w", source, err)
 	}
-	
-	if err := doc.Validate(loader.Context); err != nil {
-		return nil, fmt.Errorf("invalid OpenAPI specification: %w", err)
+
+	if err := internalizeRefs(doc); err != nil {
+		return nil, fmt.Errorf("failed to resolve external references of %s: %w", source, err)
 	}
-	
-	return &OpenAPIParser{Doc: doc}, nil
+
+	// References are only followed on disk
+	documentPath := ""
+	if location.Scheme == "" {
+		documentPath = location.Path
+	}
+	locations.add(source.String(), data, documentPath)
+
+	return doc, nil
 }
 
 // GetSchemas returns all schemas defined in the OpenAPI spec
@@ -101,41 +204,57 @@ func (p *OpenAPIParser) GetOperations() map[string]*openapi3.Operation {
 }
 
 // GetCrudOperationsForSchema identifies CRUD operations for a given schema
-// Returns a map of CRUD type to operation ID
+// Returns a map of CRUD type to operation ID. When several operations fit a
+// CRUD type, the first by operation ID is used.
 func (p *OpenAPIParser) GetCrudOperationsForSchema(schemaName string) map[string]string {
 	result := make(map[string]string)
 	operations := p.GetOperations()
-	
-	for opID, operation := range operations {
+	set := func(crudType, opID string) {
+		if _, exists := result[crudType]; !exists {
+			result[crudType] = opID
+		}
+	}
+
+	for _, opID := range sortedKeys(operations) {
+		operation := operations[opID]
 		// Check if operation has tags matching the schema name
 		for _, tag := range operation.Tags {
 			if tag == schemaName {
-				// Determine CRUD type based on operation ID or path/method
-				if p.Doc.Paths != nil {
-					// Infer method from operation by checking which method field it is in the path item
-					for _, pathItem := range p.Doc.Paths.Map() {
-						if pathItem.Get == operation {
-							if p.isListOperation(operation) {
-								result["list"] = opID
-							} else {
-								result["get"] = opID
-							}
-						} else if pathItem.Post == operation {
-							result["create"] = opID
-						} else if pathItem.Put == operation || pathItem.Patch == operation {
-							result["update"] = opID
-						} else if pathItem.Delete == operation {
-							result["delete"] = opID
-						}
-					}
+				if crudType := p.CrudType(operation); crudType != "" {
+					set(crudType, opID)
 				}
 			}
 		}
 	}
-	
+
 	return result
 }
 
+// CrudType returns the CRUD type of an operation from its method: list or
+// get for GET, as it returns an array or not, create for POST, update for
+// PUT and PATCH and delete for DELETE. Other operations have none.
+func (p *OpenAPIParser) CrudType(operation *openapi3.Operation) string {
+	if p.Doc.Paths == nil {
+		return ""
+	}
+	for _, pathItem := range p.Doc.Paths.Map() {
+		switch operation {
+		case pathItem.Get:
+			if p.isListOperation(operation) {
+				return "list"
+			}
+			return "get"
+		case pathItem.Post:
+			return "create"
+		case pathItem.Put, pathItem.Patch:
+			return "update"
+		case pathItem.Delete:
+			return "delete"
+		}
+	}
+	return ""
+}
+
 // isListOperation determines if an operation returns a list of items
 // by checking response schemas
 func (p *OpenAPIParser) isListOperation(operation *openapi3.Operation) bool {
@@ -0,0 +1,247 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// schemaRefPrefix starts the references to component schemas
+const schemaRefPrefix = "#/components/schemas/"
+
+// internalizeRefs moves the schemas referenced from other files into the
+// components of doc and points their references there, so generators only
+// see local references. The remaining external references, such as those of
+// parameters or path items, are internalized by kin-openapi.
+func internalizeRefs(doc *openapi3.T) error {
+	if doc.Components == nil {
+		doc.Components = &openapi3.Components{}
+	}
+	if doc.Components.Schemas == nil {
+		doc.Components.Schemas = make(openapi3.Schemas)
+	}
+	schemas := doc.Components.Schemas
+
+	// Components defined by a reference to another file keep their own name
+	for name, ref := range schemas {
+		if ref != nil && isExternalRef(ref.Ref) {
+			schemas[name] = &openapi3.SchemaRef{Value: ref.Value}
+		}
+	}
+
+	internalizer := &refInternalizer{schemas: schemas, visited: make(map[*openapi3.Schema]bool)}
+	if err := walkSchemaRefs(doc, internalizer.internalize); err != nil {
+		return err
+	}
+
+	doc.InternalizeRefs(context.Background(), nil)
+	return nil
+}
+
+// refInternalizer rewrites schema references to component schemas
+type refInternalizer struct {
+	schemas openapi3.Schemas
+	visited map[*openapi3.Schema]bool
+}
+
+// internalize points ref and the references nested in its schema at
+// component schemas, adding the schemas loaded from other files
+func (r *refInternalizer) internalize(ref *openapi3.SchemaRef) error {
+	if ref == nil || ref.Value == nil {
+		return nil
+	}
+
+	// Nested references come first, so schemas are compared with local references
+	if !r.visited[ref.Value] {
+		r.visited[ref.Value] = true
+		for _, child := range childSchemaRefs(ref.Value) {
+			if err := r.internalize(child); err != nil {
+				return err
+			}
+		}
+	}
+
+	if ref.Ref == "" {
+		return nil
+	}
+
+	name, existing := r.lookup(refSchemaName(ref.Ref))
+	if existing == nil {
+		r.schemas[name] = &openapi3.SchemaRef{Value: ref.Value}
+	} else if existing.Value != ref.Value {
+		if err := r.internalize(&openapi3.SchemaRef{Value: existing.Value}); err != nil {
+			return err
+		}
+		if !sameJSON(existing.Value, ref.Value) {
+			return fmt.Errorf("schema %q referenced as %s differs from the component schema %s", name, ref.Ref, name)
+		}
+	}
+	ref.Ref = schemaRefPrefix + name
+	return nil
+}
+
+// lookup returns the component schema named name, matching names that only
+// differ in case when there is no exact match
+func (r *refInternalizer) lookup(name string) (string, *openapi3.SchemaRef) {
+	if ref, ok := r.schemas[name]; ok {
+		return name, ref
+	}
+
+	names := make([]string, 0, len(r.schemas))
+	for candidate := range r.schemas {
+		names = append(names, candidate)
+	}
+	sort.Strings(names)
+	for _, candidate := range names {
+		if strings.EqualFold(candidate, name) {
+			return candidate, r.schemas[candidate]
+		}
+	}
+	return name, nil
+}
+
+// isExternalRef reports whether a reference points into another file
+func isExternalRef(ref string) bool {
+	return ref != "" && !strings.HasPrefix(ref, "#")
+}
+
+// refSchemaName names the component schema of a reference after the last
+// segment of its JSON pointer, or after the file it points to with the first
+// letter upper-cased, e.g. schemas/pet.yaml becomes Pet
+func refSchemaName(ref string) string {
+	file, fragment, _ := strings.Cut(ref, "#")
+	if strings.Trim(fragment, "/") != "" {
+		name := path.Base(fragment)
+		return strings.NewReplacer("~1", "/", "~0", "~").Replace(name)
+	}
+
+	name := path.Base(file)
+	for ext := path.Ext(name); ext != ""; ext = path.Ext(name) {
+		name = strings.TrimSuffix(name, ext)
+	}
+	first, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToUpper(first)) + name[size:]
+}
+
+// childSchemaRefs returns the schemas nested directly in schema
+func childSchemaRefs(schema *openapi3.Schema) []*openapi3.SchemaRef {
+	children := make([]*openapi3.SchemaRef, 0, len(schema.Properties)+len(schema.AllOf)+len(schema.AnyOf)+len(schema.OneOf)+3)
+
+	names := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		children = append(children, schema.Properties[name])
+	}
+
+	children = append(children, schema.Items, schema.AdditionalProperties.Schema, schema.Not)
+	children = append(children, schema.AllOf...)
+	children = append(children, schema.AnyOf...)
+	return append(children, schema.OneOf...)
+}
+
+// walkSchemaRefs calls visit with every schema reference at the top of a
+// component, parameter, request body, response or header of doc, in a
+// stable order
+func walkSchemaRefs(doc *openapi3.T, visit func(*openapi3.SchemaRef) error) error {
+	var refs []*openapi3.SchemaRef
+
+	addContent := func(content openapi3.Content) {
+		for _, mediaType := range sortedKeys(content) {
+			if content[mediaType] != nil {
+				refs = append(refs, content[mediaType].Schema)
+			}
+		}
+	}
+	addParameters := func(parameters openapi3.Parameters) {
+		for _, parameter := range parameters {
+			if parameter != nil && parameter.Value != nil {
+				refs = append(refs, parameter.Value.Schema)
+				addContent(parameter.Value.Content)
+			}
+		}
+	}
+	addHeaders := func(headers openapi3.Headers) {
+		for _, name := range sortedKeys(headers) {
+			if header := headers[name]; header != nil && header.Value != nil {
+				refs = append(refs, header.Value.Schema)
+				addContent(header.Value.Content)
+			}
+		}
+	}
+	addRequestBody := func(body *openapi3.RequestBodyRef) {
+		if body != nil && body.Value != nil {
+			addContent(body.Value.Content)
+		}
+	}
+	addResponse := func(response *openapi3.ResponseRef) {
+		if response != nil && response.Value != nil {
+			addContent(response.Value.Content)
+			addHeaders(response.Value.Headers)
+		}
+	}
+
+	if components := doc.Components; components != nil {
+		for _, name := range sortedKeys(components.Schemas) {
+			refs = append(refs, components.Schemas[name])
+		}
+		for _, name := range sortedKeys(components.Parameters) {
+			addParameters(openapi3.Parameters{components.Parameters[name]})
+		}
+		addHeaders(components.Headers)
+		for _, name := range sortedKeys(components.RequestBodies) {
+			addRequestBody(components.RequestBodies[name])
+		}
+		for _, name := range sortedKeys(components.Responses) {
+			addResponse(components.Responses[name])
+		}
+	}
+
+	if doc.Paths != nil {
+		for _, pathName := range doc.Paths.InMatchingOrder() {
+			pathItem := doc.Paths.Value(pathName)
+			if pathItem == nil {
+				continue
+			}
+			addParameters(pathItem.Parameters)
+
+			operations := pathItem.Operations()
+			for _, method := range sortedKeys(operations) {
+				operation := operations[method]
+				addParameters(operation.Parameters)
+				addRequestBody(operation.RequestBody)
+				if operation.Responses != nil {
+					responses := operation.Responses.Map()
+					for _, status := range sortedKeys(responses) {
+						addResponse(responses[status])
+					}
+				}
+			}
+		}
+	}
+
+	for _, ref := range refs {
+		if err := visit(ref); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys[M ~map[string]V, V any](m M) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
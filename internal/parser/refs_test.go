@@ -0,0 +1,146 @@
+package parser
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zeek-r/goapigen/internal/testutil"
+)
+
+// splitSpec is a spec split into path and schema files, as produced by
+// bundlers in reverse
+var splitSpec = map[string]string{
+	"openapi.yaml": `
+openapi: 3.0.3
+info: {title: Split, version: "1"}
+paths:
+  /pets:
+    $ref: paths/pets.yaml
+components:
+  schemas:
+    Error:
+      $ref: schemas/error.yaml
+`,
+	"paths/pets.yaml": `
+post:
+  operationId: createPet
+  tags: [Pet]
+  requestBody:
+    required: true
+    content:
+      application/json:
+        schema: {$ref: '../schemas/pet.yaml'}
+  responses:
+    "201":
+      description: Created
+      content:
+        application/json:
+          schema: {$ref: '../schemas/pet.yaml'}
+    default:
+      description: Error
+      content:
+        application/json:
+          schema: {$ref: '../schemas/error.yaml'}
+`,
+	"schemas/pet.yaml": `
+type: object
+required: [name]
+properties:
+  name: {type: string}
+  owner: {$ref: 'people.yaml#/Owner'}
+`,
+	"schemas/people.yaml": `
+Owner:
+  type: object
+  properties:
+    name: {type: string}
+`,
+	"schemas/error.yaml": `
+type: object
+properties:
+  message: {type: string}
+`,
+}
+
+func TestNewOpenAPIParser_ExternalRefs(t *testing.T) {
+	dir := testutil.CreateTempTree(t, splitSpec)
+
+	parser, err := NewOpenAPIParser(filepath.Join(dir, "openapi.yaml"))
+	require.NoError(t, err)
+
+	schemas := parser.GetSchemas()
+	for _, name := range []string{"Pet", "Owner", "Error"} {
+		assert.Contains(t, schemas, name)
+	}
+	assert.Len(t, schemas, 3, "schemas referenced through different paths must not be duplicated")
+
+	pet := schemas["Pet"]
+	assert.Equal(t, "#/components/schemas/Owner", pet.Properties["owner"].Ref)
+
+	operation, ok := parser.GetOperationByID("createPet")
+	require.True(t, ok)
+	assert.Equal(t, "#/components/schemas/Pet", operation.RequestBody.Value.Content["application/json"].Schema.Ref)
+	assert.Equal(t, "#/components/schemas/Error", operation.Responses.Value("default").Value.Content["application/json"].Schema.Ref)
+}
+
+func TestOpenAPIParser_Files(t *testing.T) {
+	dir := testutil.CreateTempTree(t, splitSpec)
+
+	parser, err := NewOpenAPIParser(filepath.Join(dir, "openapi.yaml"))
+	require.NoError(t, err)
+
+	absDir, err := filepath.Abs(dir)
+	require.NoError(t, err)
+	var expected []string
+	for _, name := range []string{"openapi.yaml", "paths/pets.yaml", "schemas/error.yaml", "schemas/people.yaml", "schemas/pet.yaml"} {
+		expected = append(expected, filepath.Join(absDir, filepath.FromSlash(name)))
+	}
+	assert.Equal(t, expected, parser.Files())
+}
+
+func TestNewOpenAPIParser_ExternalRefConflict(t *testing.T) {
+	files := map[string]string{
+		"openapi.yaml": `
+openapi: 3.0.3
+info: {title: Conflict, version: "1"}
+paths: {}
+components:
+  schemas:
+    Pet:
+      type: object
+      properties:
+        id: {type: string}
+    Shop:
+      type: object
+      properties:
+        pet: {$ref: 'pet.yaml'}
+`,
+		"pet.yaml": `
+type: object
+properties:
+  name: {type: string}
+`,
+	}
+	dir := testutil.CreateTempTree(t, files)
+
+	_, err := NewOpenAPIParser(filepath.Join(dir, "openapi.yaml"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "differs from the component schema Pet")
+}
+
+func TestRefSchemaName(t *testing.T) {
+	tests := map[string]string{
+		"schemas/pet.yaml":                      "Pet",
+		"../schemas/pet_owner.v1.yaml":          "Pet_owner",
+		"people.yaml#/Owner":                    "Owner",
+		"common.yaml#/components/schemas/Error": "Error",
+		"#/components/schemas/Pet":              "Pet",
+		"defs.json#/definitions/a~1b":           "a/b",
+	}
+
+	for ref, want := range tests {
+		assert.Equal(t, want, refSchemaName(ref), ref)
+	}
+}
@@ -0,0 +1,242 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// StdinSpec is the spec argument reading the document from standard input
+const StdinSpec = "-"
+
+// moduleSpecPrefix starts the spec arguments naming a file of a Go module
+const moduleSpecPrefix = "go:"
+
+// SpecSource is a place an OpenAPI document is read from
+type SpecSource interface {
+	// String identifies the document in messages
+	String() string
+	// Read returns the document and the location its relative references
+	// are resolved against
+	Read() ([]byte, *url.URL, error)
+}
+
+// ParseSpecSource returns the source named by a spec argument:
+//   - "-" reads standard input, resolving references from the working directory
+//   - http:// and https:// URLs are downloaded, as are their references
+//   - file:// URLs and plain paths are read from disk
+//   - go:<module>[@version]/<file> reads a file of a Go module the current
+//     module depends on, e.g. go:github.com/acme/api/openapi.yaml
+func ParseSpecSource(spec string) (SpecSource, error) {
+	switch {
+	case spec == StdinSpec:
+		return &ReaderSource{Name: "<stdin>", Reader: os.Stdin}, nil
+	case strings.HasPrefix(spec, moduleSpecPrefix):
+		return &ModuleSource{Path: strings.TrimPrefix(spec, moduleSpecPrefix)}, nil
+	case strings.HasPrefix(spec, "http://"), strings.HasPrefix(spec, "https://"):
+		location, err := url.Parse(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid spec URL %s: %w", spec, err)
+		}
+		return &URLSource{URL: location}, nil
+	case strings.HasPrefix(spec, "file://"):
+		location, err := url.Parse(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid spec URL %s: %w", spec, err)
+		}
+		return &FileSource{Path: filepath.FromSlash(location.Path)}, nil
+	}
+	return &FileSource{Path: spec}, nil
+}
+
+// FileSource reads a document from disk
+type FileSource struct {
+	Path string
+}
+
+func (s *FileSource) String() string {
+	return s.Path
+}
+
+// Read returns the file and its absolute path
+func (s *FileSource) Read() ([]byte, *url.URL, error) {
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+	absPath, err := filepath.Abs(s.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, &url.URL{Path: filepath.ToSlash(absPath)}, nil
+}
+
+// ReaderSource reads a document from a stream such as standard input.
+// References are resolved from BaseDir, or the working directory if empty.
+type ReaderSource struct {
+	Name    string
+	Reader  io.Reader
+	BaseDir string
+}
+
+func (s *ReaderSource) String() string {
+	return s.Name
+}
+
+// Read consumes the stream
+func (s *ReaderSource) Read() ([]byte, *url.URL, error) {
+	data, err := io.ReadAll(s.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	baseDir, err := filepath.Abs(s.BaseDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The document is named after the stream so that kin-openapi tells it
+	// apart from the files it references
+	name := strings.Trim(s.Name, "<>")
+	if name == "" {
+		name = "stream"
+	}
+	return data, &url.URL{Path: filepath.ToSlash(filepath.Join(baseDir, name))}, nil
+}
+
+// URLSource downloads a document over HTTP. Its references are resolved
+// against the URL and downloaded too.
+type URLSource struct {
+	URL    *url.URL
+	Client *http.Client
+}
+
+func (s *URLSource) String() string {
+	return s.URL.String()
+}
+
+// Read downloads the document
+func (s *URLSource) Read() ([]byte, *url.URL, error) {
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Get(s.URL.String())
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("GET %s returned %s", s.URL, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, s.URL, nil
+}
+
+// ModuleSource reads a document shipped in a Go module, named by the module
+// path followed by the path of the file in the module. The module is looked
+// up with the go command from the working directory: it must be the main
+// module or one of its dependencies, unless a version is given as in
+// github.com/acme/api@v1.2.0/openapi.yaml.
+type ModuleSource struct {
+	Path string
+}
+
+func (s *ModuleSource) String() string {
+	return moduleSpecPrefix + s.Path
+}
+
+// Read reads the file from the module directory
+func (s *ModuleSource) Read() ([]byte, *url.URL, error) {
+	file, err := s.resolve()
+	if err != nil {
+		return nil, nil, err
+	}
+	return (&FileSource{Path: file}).Read()
+}
+
+// resolve returns the path of the file on disk
+func (s *ModuleSource) resolve() (string, error) {
+	if module, rest, ok := strings.Cut(s.Path, "@"); ok {
+		version, file, ok := strings.Cut(rest, "/")
+		if !ok || file == "" {
+			return "", fmt.Errorf("%s does not name a file in the module", s)
+		}
+		dir, err := moduleDir("mod", "download", "-json", module+"@"+version)
+		if err != nil {
+			return "", fmt.Errorf("failed to download module %s@%s: %w", module, version, err)
+		}
+		return filepath.Join(dir, filepath.FromSlash(file)), nil
+	}
+
+	// The module path ends at the longest prefix the go command knows about
+	segments := strings.Split(s.Path, "/")
+	for i := len(segments) - 1; i > 0; i-- {
+		module := path.Join(segments[:i]...)
+		dir, err := moduleDir("list", "-m", "-json", module)
+		if err == nil && dir != "" {
+			return filepath.Join(dir, filepath.Join(segments[i:]...)), nil
+		}
+	}
+	return "", fmt.Errorf("no module of the current build provides %s", s.Path)
+}
+
+// moduleDir runs a go command printing a module as JSON and returns the
+// directory of the module
+func moduleDir(args ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("go", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var module struct{ Dir string }
+	if err := json.Unmarshal(out, &module); err != nil {
+		return "", fmt.Errorf("failed to decode go %s output: %w", args[0], err)
+	}
+	return module.Dir, nil
+}
+
+// sniffFormat tells JSON from YAML documents by their content rather than
+// their name, which streams and URLs may not have. Content that is neither,
+// such as an HTML error page, is rejected.
+func sniffFormat(data []byte) (string, error) {
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	if len(trimmed) == 0 {
+		return "", fmt.Errorf("document is empty")
+	}
+
+	// A document starting with { may also be a YAML flow mapping
+	if trimmed[0] == '{' && json.Valid(trimmed) {
+		return "json", nil
+	}
+	if trimmed[0] == '<' {
+		return "", fmt.Errorf("document looks like HTML or XML, not an OpenAPI document")
+	}
+
+	var document interface{}
+	if err := yaml.Unmarshal(trimmed, &document); err != nil {
+		return "", fmt.Errorf("document is neither JSON nor valid YAML: %w", err)
+	}
+	switch document.(type) {
+	case map[string]interface{}, map[interface{}]interface{}:
+		return "yaml", nil
+	}
+	return "", fmt.Errorf("document is not a JSON object or YAML mapping")
+}
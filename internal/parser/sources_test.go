@@ -0,0 +1,112 @@
+package parser
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zeek-r/goapigen/internal/testutil"
+)
+
+func TestParseSpecSource(t *testing.T) {
+	tests := []struct {
+		spec string
+		want SpecSource
+	}{
+		{spec: "-", want: &ReaderSource{}},
+		{spec: "api/openapi.yaml", want: &FileSource{Path: "api/openapi.yaml"}},
+		{spec: "file:///srv/api/openapi.yaml", want: &FileSource{Path: filepath.FromSlash("/srv/api/openapi.yaml")}},
+		{spec: "https://example.com/openapi.yaml", want: &URLSource{}},
+		{spec: "go:github.com/acme/api/openapi.yaml", want: &ModuleSource{Path: "github.com/acme/api/openapi.yaml"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			source, err := ParseSpecSource(tt.spec)
+			require.NoError(t, err)
+			assert.IsType(t, tt.want, source)
+			if _, isStream := tt.want.(*ReaderSource); !isStream {
+				assert.Equal(t, strings.TrimPrefix(tt.spec, "file://"), source.String())
+			}
+		})
+	}
+}
+
+func TestNewOpenAPIParserFromSources_Reader(t *testing.T) {
+	dir := testutil.CreateTempTree(t, splitSpec)
+	root := splitSpec["openapi.yaml"]
+
+	parser, err := NewOpenAPIParserFromSources(&ReaderSource{Name: "<stdin>", Reader: strings.NewReader(root), BaseDir: dir})
+	require.NoError(t, err)
+
+	schemas := parser.GetSchemas()
+	for _, name := range []string{"Pet", "Owner", "Error"} {
+		assert.Contains(t, schemas, name)
+	}
+}
+
+func TestNewOpenAPIParser_URL(t *testing.T) {
+	dir := testutil.CreateTempTree(t, splitSpec)
+	server := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	defer server.Close()
+
+	parser, err := NewOpenAPIParser(server.URL + "/openapi.yaml")
+	require.NoError(t, err)
+
+	schemas := parser.GetSchemas()
+	for _, name := range []string{"Pet", "Owner", "Error"} {
+		assert.Contains(t, schemas, name)
+	}
+	assert.Equal(t, "#/components/schemas/Owner", schemas["Pet"].Properties["owner"].Ref)
+
+	_, err = NewOpenAPIParser(server.URL + "/missing.yaml")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "404")
+}
+
+func TestNewOpenAPIParser_Module(t *testing.T) {
+	parser, err := NewOpenAPIParser("go:github.com/zeek-r/goapigen/examples/petstore/openapi.yaml")
+	require.NoError(t, err)
+	assert.Contains(t, parser.GetSchemas(), "Pet")
+
+	_, err = NewOpenAPIParser("go:example.com/unknown/openapi.yaml")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no module of the current build provides")
+}
+
+func TestNewOpenAPIParser_SniffsContent(t *testing.T) {
+	// The extension does not matter, the content does
+	specFile := testutil.CreateTempFile(t, "spec.txt", testutil.SimpleOpenAPISpec())
+	_, err := NewOpenAPIParser(specFile)
+	require.NoError(t, err)
+
+	for name, content := range map[string]string{
+		"html":   "<!DOCTYPE html><html><body>Not found</body></html>",
+		"empty":  "  \n",
+		"scalar": "just a string",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewOpenAPIParserFromSources(&ReaderSource{Name: name, Reader: strings.NewReader(content)})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "failed to load OpenAPI spec "+name)
+		})
+	}
+}
+
+func TestSniffFormat(t *testing.T) {
+	format, err := sniffFormat([]byte(`{"openapi": "3.0.3"}`))
+	require.NoError(t, err)
+	assert.Equal(t, "json", format)
+
+	format, err = sniffFormat([]byte("{openapi: 3.0.3}"))
+	require.NoError(t, err)
+	assert.Equal(t, "yaml", format)
+
+	format, err = sniffFormat([]byte("\xef\xbb\xbfopenapi: 3.0.3\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "yaml", format)
+}
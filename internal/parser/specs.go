@@ -0,0 +1,91 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// specExtensions are the file extensions of spec documents
+var specExtensions = map[string]bool{".json": true, ".yaml": true, ".yml": true}
+
+// expandSpecs returns the sources named by spec arguments, replacing
+// directories with the root documents at their top level, in name order.
+// Fragments referenced by the documents, e.g. under schemas/, live in
+// subdirectories or lack an openapi version and are not roots.
+func expandSpecs(specs []string) ([]SpecSource, error) {
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("no OpenAPI specification given")
+	}
+
+	var sources []SpecSource
+	for _, spec := range specs {
+		source, err := ParseSpecSource(spec)
+		if err != nil {
+			return nil, err
+		}
+
+		file, ok := source.(*FileSource)
+		if !ok {
+			sources = append(sources, source)
+			continue
+		}
+		info, err := os.Stat(file.Path)
+		if err != nil || !info.IsDir() {
+			// Missing files are reported when loading them
+			sources = append(sources, source)
+			continue
+		}
+
+		roots, err := rootDocuments(file.Path)
+		if err != nil {
+			return nil, err
+		}
+		if len(roots) == 0 {
+			return nil, fmt.Errorf("no OpenAPI documents found in %s", file.Path)
+		}
+		for _, root := range roots {
+			sources = append(sources, &FileSource{Path: root})
+		}
+	}
+	return sources, nil
+}
+
+// rootDocuments returns the spec documents at the top level of dir
+func rootDocuments(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spec directory: %w", err)
+	}
+
+	var roots []string
+	for _, entry := range entries {
+		if entry.IsDir() || !specExtensions[filepath.Ext(entry.Name())] {
+			continue
+		}
+
+		file := filepath.Join(dir, entry.Name())
+		root, err := isRootDocument(file)
+		if err != nil {
+			return nil, err
+		}
+		if root {
+			roots = append(roots, file)
+		}
+	}
+	sort.Strings(roots)
+	return roots, nil
+}
+
+// isRootDocument reports whether a file declares an OpenAPI or Swagger version
+func isRootDocument(file string) (bool, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return false, fmt.Errorf("failed to read spec file: %w", err)
+	}
+
+	// JSON is valid YAML, and fragments that fail to parse are not roots
+	version := detectSpecVersion(data)
+	return version.OpenAPI != "" || version.Swagger != "", nil
+}
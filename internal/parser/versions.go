@@ -0,0 +1,332 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi2"
+	"github.com/getkin/kin-openapi/openapi2conv"
+	"github.com/getkin/kin-openapi/openapi3"
+	"gopkg.in/yaml.v3"
+)
+
+// specVersion is the version declared at the top of a spec document
+type specVersion struct {
+	OpenAPI string `yaml:"openapi"`
+	Swagger string `yaml:"swagger"`
+}
+
+// isSwagger2 reports whether the document is a Swagger 2.0 document
+func (v specVersion) isSwagger2() bool {
+	return strings.HasPrefix(v.Swagger, "2.")
+}
+
+// isOpenAPI31 reports whether the document is an OpenAPI 3.1 document
+func (v specVersion) isOpenAPI31() bool {
+	return strings.HasPrefix(v.OpenAPI, "3.1")
+}
+
+// detectSpecVersion reads the version fields of a YAML or JSON document.
+// Documents that fail to parse report no version and are left to the loader.
+func detectSpecVersion(data []byte) specVersion {
+	var version specVersion
+	_ = yaml.Unmarshal(data, &version)
+	return version
+}
+
+// loadSwagger2 converts a Swagger 2.0 document, in the sniffed format, to
+// OpenAPI 3.0 and resolves its references relative to location
+func loadSwagger2(loader *openapi3.Loader, data []byte, format string, location *url.URL) (*openapi3.T, error) {
+	jsonData := data
+	if format != "json" {
+		var err error
+		if jsonData, err = yamlToJSON(data); err != nil {
+			return nil, err
+		}
+	}
+
+	var doc2 openapi2.T
+	if err := json.Unmarshal(jsonData, &doc2); err != nil {
+		return nil, fmt.Errorf("failed to decode Swagger 2.0 document: %w", err)
+	}
+
+	doc, err := openapi2conv.ToV3WithLoader(&doc2, loader, location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert Swagger 2.0 document: %w", err)
+	}
+	// An empty paths object is dropped by the conversion but required by OpenAPI 3
+	if doc.Paths == nil {
+		doc.Paths = openapi3.NewPaths()
+	}
+	return doc, nil
+}
+
+// normalizingReader wraps the reader of the files referenced by an OpenAPI
+// 3.1 document to normalize them as the document itself
+func normalizingReader(read openapi3.ReadFromURIFunc) openapi3.ReadFromURIFunc {
+	return func(loader *openapi3.Loader, location *url.URL) ([]byte, error) {
+		data, err := read(loader, location)
+		if err != nil {
+			return nil, err
+		}
+
+		normalized, err := normalizeOpenAPI31(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to normalize %s: %w", location, err)
+		}
+		return normalized, nil
+	}
+}
+
+// normalizeOpenAPI31 rewrites the OpenAPI 3.1 constructs of a YAML or JSON
+// document into their OpenAPI 3.0 equivalents and returns it as JSON
+func normalizeOpenAPI31(data []byte) ([]byte, error) {
+	var document interface{}
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	document = stringKeys(document)
+
+	if root, ok := document.(map[string]interface{}); ok {
+		if version, ok := root["openapi"].(string); ok && strings.HasPrefix(version, "3.1") {
+			root["openapi"] = "3.0.3"
+			if _, ok := root["paths"]; !ok {
+				root["paths"] = map[string]interface{}{}
+			}
+			delete(root, "webhooks")
+			delete(root, "jsonSchemaDialect")
+			if info, ok := root["info"].(map[string]interface{}); ok {
+				delete(info, "summary")
+				if license, ok := info["license"].(map[string]interface{}); ok {
+					delete(license, "identifier")
+				}
+			}
+		}
+	}
+
+	return json.Marshal(normalizeNode(document))
+}
+
+// namedMaps are the keys whose values map names, rather than keywords, to
+// objects. Their entries are normalized but the maps themselves are not.
+var namedMaps = map[string]bool{
+	"properties": true, "patternProperties": true, "definitions": true, "$defs": true,
+	"schemas": true, "parameters": true, "responses": true, "headers": true,
+	"requestBodies": true, "securitySchemes": true, "links": true, "callbacks": true,
+	"content": true, "encoding": true, "paths": true, "variables": true,
+	"mapping": true, "scopes": true,
+}
+
+// literalValues are the keys whose values are data rather than spec objects
+var literalValues = map[string]bool{
+	"example": true, "default": true, "enum": true, "value": true,
+}
+
+// droppedKeywords are OpenAPI 3.1 schema keywords without an OpenAPI 3.0
+// equivalent, which kin-openapi would reject as unknown fields
+var droppedKeywords = []string{
+	"$schema", "$id", "$anchor", "$comment", "$dynamicAnchor", "$dynamicRef", "$defs",
+	"contentEncoding", "contentMediaType", "contentSchema", "prefixItems",
+	"unevaluatedItems", "unevaluatedProperties", "dependentRequired", "dependentSchemas",
+	"if", "then", "else", "propertyNames", "contains", "minContains", "maxContains",
+	"patternProperties",
+}
+
+// normalizeNode normalizes an object of a document and everything below it
+func normalizeNode(node interface{}) interface{} {
+	switch value := node.(type) {
+	case map[string]interface{}:
+		normalizeObject(value)
+		for key, child := range value {
+			if literalValues[key] || strings.HasPrefix(key, "x-") {
+				continue
+			}
+			if namedMaps[key] {
+				if entries, ok := child.(map[string]interface{}); ok {
+					for name, entry := range entries {
+						entries[name] = normalizeNode(entry)
+					}
+					continue
+				}
+			}
+			value[key] = normalizeNode(child)
+		}
+		return collapseNullableVariant(value)
+	case []interface{}:
+		for i, child := range value {
+			value[i] = normalizeNode(child)
+		}
+	}
+	return node
+}
+
+// normalizeObject rewrites the OpenAPI 3.1 keywords of a single object
+func normalizeObject(object map[string]interface{}) {
+	// References replace their siblings in OpenAPI 3.0
+	if ref, ok := object["$ref"]; ok {
+		for key := range object {
+			delete(object, key)
+		}
+		object["$ref"] = ref
+		return
+	}
+
+	// type: [string, "null"] becomes a nullable string, and several
+	// non-null types become a oneOf of them
+	if types, ok := object["type"].([]interface{}); ok {
+		delete(object, "type")
+		var nonNull []interface{}
+		for _, t := range types {
+			if t == "null" {
+				object["nullable"] = true
+			} else {
+				nonNull = append(nonNull, t)
+			}
+		}
+		switch len(nonNull) {
+		case 0:
+		case 1:
+			object["type"] = nonNull[0]
+		default:
+			variants := make([]interface{}, len(nonNull))
+			for i, t := range nonNull {
+				variants[i] = map[string]interface{}{"type": t}
+			}
+			object["oneOf"] = variants
+		}
+	}
+
+	// const usually stands alone in OpenAPI 3.1, the type following from the value
+	if constant, ok := object["const"]; ok {
+		delete(object, "const")
+		if _, ok := object["enum"]; !ok {
+			object["enum"] = []interface{}{constant}
+		}
+		if _, ok := object["type"]; !ok {
+			if constType := jsonType(constant); constType != "" {
+				object["type"] = constType
+			}
+		}
+	}
+
+	// Schema examples are a list in OpenAPI 3.1; other objects map names to examples
+	if examples, ok := object["examples"].([]interface{}); ok {
+		delete(object, "examples")
+		if _, ok := object["example"]; !ok && len(examples) > 0 {
+			object["example"] = examples[0]
+		}
+	}
+
+	for _, bound := range []struct{ exclusive, inclusive string }{
+		{"exclusiveMinimum", "minimum"},
+		{"exclusiveMaximum", "maximum"},
+	} {
+		if _, isFlag := object[bound.exclusive].(bool); isFlag {
+			continue
+		}
+		if limit, ok := object[bound.exclusive]; ok {
+			object[bound.inclusive] = limit
+			object[bound.exclusive] = true
+		}
+	}
+
+	for _, keyword := range droppedKeywords {
+		delete(object, keyword)
+	}
+
+	for _, composition := range []string{"oneOf", "anyOf"} {
+		variants, ok := object[composition].([]interface{})
+		if !ok {
+			continue
+		}
+		var kept []interface{}
+		for _, variant := range variants {
+			if isNullSchema(variant) {
+				object["nullable"] = true
+			} else {
+				kept = append(kept, variant)
+			}
+		}
+		object[composition] = kept
+	}
+}
+
+// collapseNullableVariant replaces an object whose oneOf or anyOf only kept
+// a single variant after dropping {type: "null"} by that variant. Inline
+// variants stay nullable; OpenAPI 3.0 ignores nullable next to a $ref.
+func collapseNullableVariant(object map[string]interface{}) interface{} {
+	if object["nullable"] != true {
+		return object
+	}
+
+	for _, composition := range []string{"oneOf", "anyOf"} {
+		variants, ok := object[composition].([]interface{})
+		if !ok || len(variants) != 1 || len(object) != 2 {
+			continue
+		}
+		variant, ok := variants[0].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if _, isRef := variant["$ref"]; !isRef {
+			variant["nullable"] = true
+		}
+		return variant
+	}
+	return object
+}
+
+// jsonType returns the JSON schema type of a value decoded from YAML
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case int, int64, uint64:
+		return "integer"
+	case float64:
+		return "number"
+	}
+	return ""
+}
+
+// isNullSchema reports whether a schema only allows null
+func isNullSchema(node interface{}) bool {
+	object, ok := node.(map[string]interface{})
+	return ok && len(object) == 1 && object["type"] == "null"
+}
+
+// yamlToJSON converts a YAML or JSON document to JSON
+func yamlToJSON(data []byte) ([]byte, error) {
+	var document interface{}
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse document: %w", err)
+	}
+	return json.Marshal(stringKeys(document))
+}
+
+// stringKeys converts the maps decoded from YAML, whose keys may be numbers
+// such as response codes, to maps with string keys so they encode as JSON
+func stringKeys(node interface{}) interface{} {
+	switch value := node.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			value[key] = stringKeys(child)
+		}
+		return value
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(value))
+		for key, child := range value {
+			converted[fmt.Sprint(key)] = stringKeys(child)
+		}
+		return converted
+	case []interface{}:
+		for i, child := range value {
+			value[i] = stringKeys(child)
+		}
+	}
+	return node
+}
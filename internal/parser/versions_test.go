@@ -0,0 +1,169 @@
+package parser
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zeek-r/goapigen/internal/testutil"
+)
+
+func TestNewOpenAPIParser_Swagger2(t *testing.T) {
+	dir := testutil.CreateTempTree(t, map[string]string{
+		"swagger.yaml": `
+swagger: "2.0"
+info: {title: Pets, version: "1"}
+basePath: /v1
+consumes: [application/json]
+produces: [application/json]
+paths:
+  /pets:
+    post:
+      operationId: createPet
+      tags: [Pet]
+      parameters:
+        - in: body
+          name: pet
+          required: true
+          schema: {$ref: '#/definitions/Pet'}
+      responses:
+        201:
+          description: Created
+          schema: {$ref: '#/definitions/Pet'}
+  /pets/{id}:
+    get:
+      operationId: getPet
+      tags: [Pet]
+      parameters:
+        - {in: path, name: id, required: true, type: string}
+      responses:
+        200:
+          description: OK
+          schema: {$ref: '#/definitions/Pet'}
+definitions:
+  Pet:
+    type: object
+    required: [name]
+    properties:
+      name: {type: string, minLength: 1}
+      owner: {$ref: 'owner.yaml'}
+`,
+		"owner.yaml": `
+type: object
+properties:
+  name: {type: string}
+`,
+	})
+
+	parser, err := NewOpenAPIParser(filepath.Join(dir, "swagger.yaml"))
+	require.NoError(t, err)
+
+	schemas := parser.GetSchemas()
+	require.Contains(t, schemas, "Pet")
+	require.Contains(t, schemas, "Owner")
+	assert.Equal(t, "#/components/schemas/Owner", schemas["Pet"].Properties["owner"].Ref)
+
+	create, ok := parser.GetOperationByID("createPet")
+	require.True(t, ok)
+	require.NotNil(t, create.RequestBody)
+	assert.Equal(t, "#/components/schemas/Pet", create.RequestBody.Value.Content.Get("application/json").Schema.Ref)
+
+	crud := parser.GetCrudOperationsForSchema("Pet")
+	assert.Equal(t, "createPet", crud["create"])
+	assert.Equal(t, "getPet", crud["get"])
+}
+
+func TestNewOpenAPIParser_OpenAPI31(t *testing.T) {
+	dir := testutil.CreateTempTree(t, map[string]string{
+		"openapi.yaml": `
+openapi: 3.1.0
+info: {title: Pets, version: "1", summary: Pets API}
+jsonSchemaDialect: https://spec.openapis.org/oas/3.1/dialect/base
+webhooks: {}
+paths:
+  /pets:
+    post:
+      operationId: createPet
+      tags: [Pet]
+      requestBody:
+        content:
+          application/json:
+            schema: {$ref: '#/components/schemas/Pet', description: A pet}
+      responses:
+        "201": {description: Created}
+components:
+  schemas:
+    Pet:
+      $schema: https://json-schema.org/draft/2020-12/schema
+      type: object
+      required: [name, kind]
+      properties:
+        name: {type: string, examples: [Rex]}
+        type: {type: [string, "null"]}
+        kind: {const: dog}
+        age: {type: integer, exclusiveMinimum: 0}
+        owner:
+          oneOf:
+            - $ref: 'owner.yaml'
+            - type: "null"
+        tag:
+          anyOf:
+            - {type: string, maxLength: 10}
+            - {type: "null"}
+        id: {type: [string, integer]}
+`,
+		"owner.yaml": `
+type: object
+properties:
+  name: {type: [string, "null"]}
+`,
+	})
+
+	parser, err := NewOpenAPIParser(filepath.Join(dir, "openapi.yaml"))
+	require.NoError(t, err)
+
+	pet, ok := parser.GetSchemaByName("Pet")
+	require.True(t, ok)
+
+	name := pet.Properties["name"].Value
+	assert.Equal(t, "Rex", name.Example)
+
+	typ := pet.Properties["type"].Value
+	assert.Equal(t, "string", typ.Type)
+	assert.True(t, typ.Nullable)
+
+	kind := pet.Properties["kind"].Value
+	assert.Equal(t, "string", kind.Type)
+	assert.Equal(t, []interface{}{"dog"}, kind.Enum)
+
+	age := pet.Properties["age"].Value
+	require.NotNil(t, age.Min)
+	assert.Equal(t, 0.0, *age.Min)
+	assert.True(t, age.ExclusiveMin)
+
+	assert.Equal(t, "#/components/schemas/Owner", pet.Properties["owner"].Ref)
+	owner, ok := parser.GetSchemaByName("Owner")
+	require.True(t, ok)
+	assert.True(t, owner.Properties["name"].Value.Nullable)
+
+	tag := pet.Properties["tag"].Value
+	assert.Equal(t, "string", tag.Type)
+	assert.True(t, tag.Nullable)
+	assert.Equal(t, uint64(10), *tag.MaxLength)
+
+	assert.Len(t, pet.Properties["id"].Value.OneOf, 2)
+}
+
+func TestRootDocumentsIncludeSwagger(t *testing.T) {
+	dir := testutil.CreateTempTree(t, map[string]string{
+		"a.yaml":         "openapi: 3.1.0\n",
+		"b.json":         `{"swagger": "2.0"}`,
+		"fragment.yaml":  "type: object\n",
+		"not-a-spec.txt": "swagger: 2.0\n",
+	})
+
+	roots, err := rootDocuments(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "a.yaml"), filepath.Join(dir, "b.json")}, roots)
+}
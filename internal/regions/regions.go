@@ -0,0 +1,197 @@
+// Package regions merges regenerated files with the protected regions of
+// their previous version, so that code written inside them survives
+// regeneration. A region is delimited by marker comments:
+//
+//	// goapigen:keep begin Create
+//	... code ...
+//	// goapigen:keep end
+//
+// The begin marker of a generated region records a hash of the generated
+// code, which tells whether the region was edited since: edited regions are
+// kept, the others follow the template.
+package regions
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Marker prefixes of the region comments
+const (
+	BeginMarker = "// goapigen:keep begin"
+	EndMarker   = "// goapigen:keep end"
+)
+
+// conflictMarker starts the code of a region the template no longer has
+const conflictMarker = "<<<<<<< existing: region "
+
+// region is a protected region of a file
+type region struct {
+	name  string
+	hash  string // hash recorded in the begin marker, if any
+	begin int    // line of the begin marker
+	end   int    // line of the end marker
+	body  []string
+}
+
+// edited reports whether the code of a region differs from the code it was
+// generated with
+func (r region) edited() bool {
+	return r.hash == "" || r.hash != hashBody(r.body)
+}
+
+// Result is the outcome of a merge
+type Result struct {
+	Content []byte
+	// Kept names the edited regions kept from the existing file
+	Kept []string
+	// Conflicts names the edited regions the generated file no longer has,
+	// appended between conflict markers for the developer to move or delete
+	Conflicts []string
+}
+
+// Has reports whether content has protected regions
+func Has(content []byte) bool {
+	return bytes.Contains(content, []byte(BeginMarker))
+}
+
+// Stamp records the hash of each region of generated content in its begin
+// marker
+func Stamp(generated []byte) ([]byte, error) {
+	lines := splitLines(generated)
+	found, err := parse(lines)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range found {
+		lines[r.begin] = beginLine(lines[r.begin], r.name, hashBody(r.body))
+	}
+	return joinLines(lines), nil
+}
+
+// Merge returns generated content with the edited regions of the existing
+// content in place of its own. Generated content is stamped first. Existing
+// content with unresolved conflicts is an error, as merging it again would
+// drop the code between the conflict markers.
+func Merge(existing, generated []byte) (Result, error) {
+	existingLines := splitLines(existing)
+	for i, line := range existingLines {
+		if strings.HasPrefix(line, conflictMarker) {
+			return Result{}, fmt.Errorf("line %d: unresolved conflict, move or delete the code between the conflict markers", i+1)
+		}
+	}
+	previous, err := parse(existingLines)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to read regions of the existing file: %w", err)
+	}
+	stamped, err := Stamp(generated)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to read regions of the generated file: %w", err)
+	}
+
+	kept := make(map[string]region)
+	for _, r := range previous {
+		if r.edited() {
+			kept[r.name] = r
+		}
+	}
+
+	var result Result
+	lines := splitLines(stamped)
+	current, _ := parse(lines)
+	out := make([]string, 0, len(lines))
+	next := 0
+	for _, r := range current {
+		out = append(out, lines[next:r.begin+1]...)
+		if old, ok := kept[r.name]; ok {
+			out = append(out, old.body...)
+			result.Kept = append(result.Kept, r.name)
+			delete(kept, r.name)
+		} else {
+			out = append(out, r.body...)
+		}
+		next = r.end
+	}
+	out = append(out, lines[next:]...)
+
+	// Code of regions the template dropped has nowhere to go
+	for _, r := range previous {
+		if _, orphaned := kept[r.name]; !orphaned {
+			continue
+		}
+		if len(out) > 0 && out[len(out)-1] == "" {
+			out = out[:len(out)-1]
+		}
+		out = append(out, conflictMarker+r.name+" is no longer generated")
+		out = append(out, r.body...)
+		out = append(out, "=======", ">>>>>>> generated", "")
+		result.Conflicts = append(result.Conflicts, r.name)
+	}
+
+	result.Content = joinLines(out)
+	return result, nil
+}
+
+// parse returns the regions of a file in order. Regions cannot nest and
+// their names must be unique.
+func parse(lines []string) ([]region, error) {
+	var regions []region
+	names := make(map[string]bool)
+	var open *region
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, BeginMarker):
+			fields := strings.Fields(strings.TrimPrefix(trimmed, BeginMarker))
+			if open != nil {
+				return nil, fmt.Errorf("line %d: region %s begins inside region %s", i+1, strings.Join(fields, " "), open.name)
+			}
+			if len(fields) == 0 || len(fields) > 2 {
+				return nil, fmt.Errorf("line %d: expected %s <name>", i+1, BeginMarker)
+			}
+			if names[fields[0]] {
+				return nil, fmt.Errorf("line %d: duplicate region %s", i+1, fields[0])
+			}
+			names[fields[0]] = true
+			open = &region{name: fields[0], begin: i}
+			if len(fields) == 2 {
+				open.hash = fields[1]
+			}
+		case strings.HasPrefix(trimmed, EndMarker):
+			if open == nil {
+				return nil, fmt.Errorf("line %d: %s outside of a region", i+1, EndMarker)
+			}
+			open.end = i
+			open.body = lines[open.begin+1 : i]
+			regions = append(regions, *open)
+			open = nil
+		}
+	}
+	if open != nil {
+		return nil, fmt.Errorf("line %d: region %s has no %s", open.begin+1, open.name, EndMarker)
+	}
+	return regions, nil
+}
+
+// beginLine rewrites a begin marker with a hash, keeping its indentation
+func beginLine(line, name, hash string) string {
+	indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+	return indent + BeginMarker + " " + name + " " + hash
+}
+
+// hashBody returns the short hash recorded for the code of a region
+func hashBody(body []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(body, "\n")))
+	return hex.EncodeToString(sum[:4])
+}
+
+func splitLines(content []byte) []string {
+	return strings.Split(string(content), "\n")
+}
+
+func joinLines(lines []string) []byte {
+	return []byte(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,128 @@
+package regions
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// generatedV1 is a template output with two regions
+const generatedV1 = `package pet
+
+func Create() {
+	// goapigen:keep begin Create
+	create()
+	// goapigen:keep end
+}
+
+func Delete() {
+	// goapigen:keep begin Delete
+	remove()
+	// goapigen:keep end
+}
+`
+
+func stamp(t *testing.T, content string) string {
+	t.Helper()
+	stamped, err := Stamp([]byte(content))
+	require.NoError(t, err)
+	return string(stamped)
+}
+
+func TestStamp(t *testing.T) {
+	stamped := stamp(t, generatedV1)
+	assert.Contains(t, stamped, "\t// goapigen:keep begin Create "+hashBody([]string{"\tcreate()"})+"\n")
+
+	// Stamping is idempotent
+	assert.Equal(t, stamped, stamp(t, stamped))
+	assert.True(t, Has([]byte(stamped)))
+	assert.False(t, Has([]byte("package pet\n")))
+}
+
+func TestMerge(t *testing.T) {
+	existing := stamp(t, generatedV1)
+
+	t.Run("unedited", func(t *testing.T) {
+		// Unedited regions follow the template
+		generated := strings.Replace(generatedV1, "create()", "validate()\n\tcreate()", 1)
+		result, err := Merge([]byte(existing), []byte(generated))
+		require.NoError(t, err)
+		assert.Equal(t, stamp(t, generated), string(result.Content))
+		assert.Empty(t, result.Kept)
+		assert.Empty(t, result.Conflicts)
+	})
+
+	t.Run("edited", func(t *testing.T) {
+		edited := strings.Replace(existing, "\tcreate()", "\tcustom()", 1)
+		generated := strings.Replace(generatedV1, "func Delete", "func Update() {}\n\nfunc Delete", 1)
+		result, err := Merge([]byte(edited), []byte(generated))
+		require.NoError(t, err)
+
+		content := string(result.Content)
+		assert.Contains(t, content, "\tcustom()")
+		assert.NotContains(t, content, "\tcreate()")
+		assert.Contains(t, content, "func Update() {}", "Code outside regions should follow the template")
+		assert.Equal(t, []string{"Create"}, result.Kept)
+
+		// The kept region stays edited for the next merge
+		again, err := Merge(result.Content, []byte(generated))
+		require.NoError(t, err)
+		assert.Equal(t, content, string(again.Content))
+	})
+
+	t.Run("dropped", func(t *testing.T) {
+		generated := generatedV1[:strings.Index(generatedV1, "\nfunc Delete")]
+
+		// Unedited regions go with their code
+		result, err := Merge([]byte(existing), []byte(generated))
+		require.NoError(t, err)
+		assert.Equal(t, stamp(t, generated), string(result.Content))
+
+		// Edited ones are left between conflict markers
+		edited := strings.Replace(existing, "\tremove()", "\tarchive()", 1)
+		result, err = Merge([]byte(edited), []byte(generated))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Delete"}, result.Conflicts)
+		assert.True(t, strings.HasSuffix(string(result.Content),
+			"<<<<<<< existing: region Delete is no longer generated\n\tarchive()\n=======\n>>>>>>> generated\n"))
+
+		// and must be resolved before merging again
+		_, err = Merge(result.Content, []byte(generated))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unresolved conflict")
+	})
+
+	t.Run("unstamped", func(t *testing.T) {
+		// Regions written by hand have no hash and are always kept
+		handWritten := strings.Replace(generatedV1, "create()", "custom()", 1)
+		result, err := Merge([]byte(handWritten), []byte(generatedV1))
+		require.NoError(t, err)
+		assert.Contains(t, string(result.Content), "custom()")
+	})
+}
+
+func TestMerge_Malformed(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		errMsg  string
+	}{
+		{"unclosed", "// goapigen:keep begin A\n", "region A has no"},
+		{"nested", "// goapigen:keep begin A\n// goapigen:keep begin B\n", "begins inside region A"},
+		{"stray_end", "// goapigen:keep end\n", "outside of a region"},
+		{"duplicate", "// goapigen:keep begin A\n// goapigen:keep end\n// goapigen:keep begin A\n// goapigen:keep end\n", "duplicate region A"},
+		{"unnamed", "// goapigen:keep begin\n// goapigen:keep end\n", "expected"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Merge([]byte(tt.content), []byte(generatedV1))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errMsg)
+
+			_, err = Stamp([]byte(tt.content))
+			assert.Error(t, err)
+		})
+	}
+}
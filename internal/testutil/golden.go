@@ -0,0 +1,107 @@
+package testutil
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/stretchr/testify/require"
+)
+
+// GoldenSuffix ends the name of every golden file, so that golden Go files
+// are neither built nor formatted as part of the module
+const GoldenSuffix = ".golden"
+
+// AssertGolden compares files keyed by their slash-separated path with the
+// golden files of dir, each named after its file followed by GoldenSuffix.
+// Every file must match its golden file and every golden file must still
+// be produced. With update, typically the -update flag of the test
+// package, dir is replaced with the files instead.
+func AssertGolden(t *testing.T, dir string, files map[string][]byte, update bool) {
+	t.Helper()
+
+	if update {
+		require.NoError(t, os.RemoveAll(dir))
+		for name, content := range files {
+			goldenPath := filepath.Join(dir, filepath.FromSlash(name)+GoldenSuffix)
+			require.NoError(t, os.MkdirAll(filepath.Dir(goldenPath), 0755))
+			require.NoError(t, os.WriteFile(goldenPath, content, 0644))
+		}
+		return
+	}
+
+	golden := readGolden(t, dir)
+	for _, name := range sortedNames(files) {
+		expected, ok := golden[name]
+		if !ok {
+			t.Errorf("%s has no golden file in %s, run the test with -update to add it", name, dir)
+			continue
+		}
+		if string(expected) != string(files[name]) {
+			t.Errorf("%s differs from its golden file, run the test with -update to accept the change:\n%s",
+				name, goldenDiff(name, expected, files[name]))
+		}
+	}
+	for _, name := range sortedNames(golden) {
+		if _, ok := files[name]; !ok {
+			t.Errorf("%s is no longer produced, run the test with -update to remove its golden file", name)
+		}
+	}
+}
+
+// readGolden reads the golden files of dir, keyed by the slash-separated
+// path of their file
+func readGolden(t *testing.T, dir string) map[string][]byte {
+	t.Helper()
+
+	golden := make(map[string][]byte)
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || !strings.HasSuffix(path, GoldenSuffix) {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, strings.TrimSuffix(path, GoldenSuffix))
+		if err != nil {
+			return err
+		}
+		golden[filepath.ToSlash(rel)] = content
+		return nil
+	})
+	if os.IsNotExist(err) {
+		t.Fatalf("no golden files in %s, run the test with -update to create them", dir)
+	}
+	require.NoError(t, err)
+	return golden
+}
+
+// goldenDiff returns the unified diff from a golden file to the output
+func goldenDiff(name string, expected, actual []byte) string {
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(expected)),
+		B:        difflib.SplitLines(string(actual)),
+		FromFile: "golden/" + name,
+		ToFile:   "output/" + name,
+		Context:  3,
+	})
+	if err != nil {
+		return err.Error()
+	}
+	return diff
+}
+
+// sortedNames returns the names of files in order
+func sortedNames(files map[string][]byte) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -27,6 +27,21 @@ func CreateTempFile(t *testing.T, filename, content string) string {
 	return filePath
 }
 
+// CreateTempTree creates a temporary directory holding files keyed by their
+// slash-separated path relative to it, and returns the directory
+func CreateTempTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	tempDir := t.TempDir()
+	for name, content := range files {
+		filePath := filepath.Join(tempDir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0755))
+		require.NoError(t, os.WriteFile(filePath, []byte(content), 0644))
+	}
+
+	return tempDir
+}
+
 // SimpleOpenAPISpec returns a basic OpenAPI spec for testing
 func SimpleOpenAPISpec() string {
 	return `
@@ -0,0 +1,287 @@
+package verify
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// errExternal is returned for the packages of other modules, which Tree
+// does not load
+var errExternal = errors.New("package of another module")
+
+// Tree type-checks the Go files of a module held in memory, keyed by their
+// slash-separated path in the module, tests included. It needs neither a
+// go.mod nor the dependencies: the standard library is read from GOROOT, the
+// packages of other modules are left out and the code using them is not
+// checked. Problems are positioned in the files of the tree by their key.
+func Tree(module string, files map[string][]byte) ([]Problem, error) {
+	t := &tree{
+		module:   module,
+		fset:     token.NewFileSet(),
+		dirs:     make(map[string][]*ast.File),
+		checked:  make(map[string]*types.Package),
+		loading:  make(map[string]bool),
+		std:      importer.ForCompiler(token.NewFileSet(), "source", nil),
+		problems: make(map[string]Problem),
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		if strings.HasSuffix(name, ".go") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		file, err := parser.ParseFile(t.fset, name, files[name], parser.SkipObjectResolution)
+		var list scanner.ErrorList
+		if errors.As(err, &list) {
+			for _, parseErr := range list {
+				t.add(Problem{File: parseErr.Pos.Filename, Line: parseErr.Pos.Line, Column: parseErr.Pos.Column, Msg: parseErr.Msg})
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		dir := path.Dir(name)
+		t.dirs[dir] = append(t.dirs[dir], file)
+	}
+
+	dirs := make([]string, 0, len(t.dirs))
+	for dir := range t.dirs {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	for _, dir := range dirs {
+		t.checkTests(dir)
+	}
+
+	problems := make([]Problem, 0, len(t.problems))
+	for _, problem := range t.problems {
+		problems = append(problems, problem)
+	}
+	sortProblems(problems)
+	return problems, nil
+}
+
+// tree type-checks the packages of a module held in memory
+type tree struct {
+	module   string
+	fset     *token.FileSet
+	dirs     map[string][]*ast.File // Parsed files by directory
+	checked  map[string]*types.Package
+	loading  map[string]bool // Packages being checked, to report import cycles
+	std      types.Importer
+	problems map[string]Problem // Keyed by their description, as test variants check files again
+}
+
+// Import imports a package of the module, type-checking it first, or of the
+// standard library
+func (t *tree) Import(importPath string) (*types.Package, error) {
+	if pkg, ok := t.checked[importPath]; ok {
+		return pkg, nil
+	}
+	if importPath == t.module || strings.HasPrefix(importPath, t.module+"/") {
+		dir := strings.TrimPrefix(strings.TrimPrefix(importPath, t.module), "/")
+		if dir == "" {
+			dir = "."
+		}
+		files := t.packageFiles(dir, false)
+		if len(files) == 0 {
+			return nil, fmt.Errorf("no Go files in module directory %q", dir)
+		}
+		if t.loading[importPath] {
+			return nil, fmt.Errorf("import cycle through %s", importPath)
+		}
+		t.loading[importPath] = true
+		pkg := t.check(importPath, files)
+		delete(t.loading, importPath)
+		t.checked[importPath] = pkg
+		return pkg, nil
+	}
+	if t.otherModule(importPath) {
+		return nil, errExternal
+	}
+	return t.std.Import(importPath)
+}
+
+// otherModule reports whether a package is neither in the module nor in the
+// standard library, whose paths have no dot in their first element as for
+// the go command
+func (t *tree) otherModule(importPath string) bool {
+	if importPath == t.module || strings.HasPrefix(importPath, t.module+"/") {
+		return false
+	}
+	first, _, _ := strings.Cut(importPath, "/")
+	return strings.Contains(first, ".")
+}
+
+// checkTests type-checks a directory with its tests: the files of the
+// package and its internal tests, then its external tests if any
+func (t *tree) checkTests(dir string) {
+	importPath := t.module
+	if dir != "." {
+		importPath += "/" + dir
+	}
+	if files := t.packageFiles(dir, true); len(files) > 0 {
+		t.check(importPath, files)
+	}
+	var external []*ast.File
+	for _, file := range t.dirs[dir] {
+		if strings.HasSuffix(file.Name.Name, "_test") {
+			external = append(external, file)
+		}
+	}
+	if len(external) > 0 {
+		t.check(importPath+"_test", external)
+	}
+}
+
+// packageFiles returns the files of the package in a directory, with or
+// without its internal tests
+func (t *tree) packageFiles(dir string, tests bool) []*ast.File {
+	var files []*ast.File
+	for _, file := range t.dirs[dir] {
+		if strings.HasSuffix(file.Name.Name, "_test") {
+			continue
+		}
+		if !tests && strings.HasSuffix(t.fset.File(file.Pos()).Name(), "_test.go") {
+			continue
+		}
+		files = append(files, file)
+	}
+	return files
+}
+
+// check type-checks files as a package, recording their problems. The
+// failed imports of other modules are not problems, and the code using them
+// is not checked.
+func (t *tree) check(importPath string, files []*ast.File) *types.Package {
+	var typeErrors []types.Error
+	conf := types.Config{
+		Importer: t,
+		Error: func(err error) {
+			var typeErr types.Error
+			if !errors.As(err, &typeErr) {
+				t.add(Problem{Msg: err.Error()})
+				return
+			}
+			typeErrors = append(typeErrors, typeErr)
+		},
+	}
+	info := &types.Info{Types: make(map[ast.Expr]types.TypeAndValue)}
+	pkg, _ := conf.Check(importPath, t.fset, files, info)
+
+	// The fields and methods promoted from the types of other modules are
+	// only known once the package is checked
+	selected := make(map[token.Pos]types.Type)
+	for _, file := range files {
+		ast.Inspect(file, func(node ast.Node) bool {
+			if sel, ok := node.(*ast.SelectorExpr); ok {
+				selected[sel.Sel.Pos()] = info.TypeOf(sel.X)
+			}
+			return true
+		})
+	}
+	for _, typeErr := range typeErrors {
+		pos := t.fset.Position(typeErr.Pos)
+		if strings.Contains(typeErr.Msg, errExternal.Error()) || t.external(files, pos.Filename, typeErr.Msg) {
+			continue
+		}
+		if strings.Contains(typeErr.Msg, "has no field or method") && embedsInvalid(selected[typeErr.Pos], nil) {
+			continue
+		}
+		t.add(Problem{File: pos.Filename, Line: pos.Line, Column: pos.Column, Msg: typeErr.Msg})
+	}
+	return pkg
+}
+
+// embedsInvalid reports whether a struct, or the struct a pointer points
+// to, embeds a type that is not known, such as the type of another module,
+// directly or through its embedded structs
+func embedsInvalid(typ types.Type, seen map[types.Type]bool) bool {
+	if typ == nil {
+		return false
+	}
+	if ptr, ok := typ.Underlying().(*types.Pointer); ok {
+		typ = ptr.Elem()
+	}
+	if seen[typ] {
+		return false
+	}
+	if seen == nil {
+		seen = make(map[types.Type]bool)
+	}
+	seen[typ] = true
+	st, ok := typ.Underlying().(*types.Struct)
+	if !ok {
+		return false
+	}
+	for i := range st.NumFields() {
+		field := st.Field(i)
+		if !field.Embedded() {
+			continue
+		}
+		if field.Type() == types.Typ[types.Invalid] || embedsInvalid(field.Type(), seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// external reports whether an error is for a name of a file that may be
+// declared by an import of another module. Without the package, its name is
+// unknown: it is assumed to be part of the last element of its path that is
+// not a major version, e.g. chi for github.com/go-chi/chi/v5.
+func (t *tree) external(files []*ast.File, filename, msg string) bool {
+	name, ok := strings.CutPrefix(msg, "undefined: ")
+	if !ok || strings.Contains(name, ".") {
+		return false
+	}
+	for _, file := range files {
+		if t.fset.File(file.Pos()).Name() != filename {
+			continue
+		}
+		for _, spec := range file.Imports {
+			importPath, err := strconv.Unquote(spec.Path.Value)
+			if err != nil || spec.Name != nil {
+				continue
+			}
+			if !t.otherModule(importPath) {
+				continue
+			}
+			elements := strings.Split(importPath, "/")
+			last := elements[len(elements)-1]
+			if majorVersion(last) && len(elements) > 1 {
+				last = elements[len(elements)-2]
+			}
+			if strings.Contains(strings.ToLower(last), strings.ToLower(name)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// majorVersion reports whether a path element is the major version suffix
+// of a module path, such as v2
+func majorVersion(element string) bool {
+	digits, ok := strings.CutPrefix(element, "v")
+	_, err := strconv.Atoi(digits)
+	return ok && err == nil
+}
+
+// add records a problem once
+func (t *tree) add(problem Problem) {
+	t.problems[problem.String()] = problem
+}
@@ -0,0 +1,136 @@
+// Package verify type-checks a generated Go module, including its tests,
+// as the compiler would. Dependencies are read from the module cache only:
+// nothing is downloaded and go.mod and go.sum are left untouched. Tree
+// checks a module held in memory without its dependencies instead.
+package verify
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Problem is an error of a package of the module: a type error, a syntax
+// error or a dependency that cannot be loaded
+type Problem struct {
+	File   string // Absolute path, or key with Tree, empty when the error has no position
+	Line   int
+	Column int
+	Msg    string
+}
+
+// String returns the position and message of the problem
+func (p Problem) String() string {
+	switch {
+	case p.File == "":
+		return p.Msg
+	case p.Column > 0:
+		return fmt.Sprintf("%s:%d:%d: %s", p.File, p.Line, p.Column, p.Msg)
+	default:
+		return fmt.Sprintf("%s:%d: %s", p.File, p.Line, p.Msg)
+	}
+}
+
+// loadMode loads the syntax and types of the packages of the module and of
+// their dependencies. Dependencies are type-checked from source, as the
+// format of export data follows the installed Go toolchain, which may be
+// newer than the x/tools release goapigen is built with.
+const loadMode = packages.NeedDeps | packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedSyntax | packages.NeedTypes
+
+// Module type-checks every package of the module in dir and returns its
+// problems ordered by position. The error is for a module that cannot be
+// loaded at all, such as a directory without go.mod.
+func Module(dir string) ([]Problem, error) {
+	cfg := &packages.Config{
+		Mode:  loadMode,
+		Dir:   dir,
+		Tests: true,
+		// Offline: a dependency missing from the module cache is a problem
+		// of the packages importing it
+		Env: append(os.Environ(), "GOPROXY=off", "GOFLAGS=-mod=readonly", "GOWORK=off"),
+	}
+	pkgs, err := packages.Load(cfg, "./...")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load packages: %w", err)
+	}
+
+	// Test variants type-check the files of their package again
+	seen := make(map[string]bool)
+	var problems []Problem
+	for _, pkg := range pkgs {
+		for _, pkgErr := range packageErrors(pkg) {
+			problem := newProblem(pkgErr)
+			if key := problem.String(); !seen[key] {
+				seen[key] = true
+				problems = append(problems, problem)
+			}
+		}
+	}
+
+	sortProblems(problems)
+	return problems, nil
+}
+
+// sortProblems orders problems by position
+func sortProblems(problems []Problem) {
+	sort.SliceStable(problems, func(i, j int) bool {
+		a, b := problems[i], problems[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+}
+
+// packageErrors returns the errors of a package. The errors of the go
+// command repeat the compiler output of the type and syntax errors, so they
+// are only kept for a package without any.
+func packageErrors(pkg *packages.Package) []packages.Error {
+	var checked []packages.Error
+	for _, pkgErr := range pkg.Errors {
+		if pkgErr.Kind != packages.ListError {
+			checked = append(checked, pkgErr)
+		}
+	}
+	if len(checked) == 0 {
+		return pkg.Errors
+	}
+	return checked
+}
+
+// newProblem converts a package error, whose position is file:line:col,
+// file:line, or empty or - when unknown
+func newProblem(err packages.Error) Problem {
+	problem := Problem{Msg: err.Msg}
+	if err.Pos == "" || err.Pos == "-" {
+		return problem
+	}
+
+	// Windows paths have a colon after the drive letter, so the position
+	// is split from the end
+	parts := strings.Split(err.Pos, ":")
+	var numbers []int
+	for len(parts) > 1 && len(numbers) < 2 {
+		n, convErr := strconv.Atoi(parts[len(parts)-1])
+		if convErr != nil {
+			break
+		}
+		numbers = append([]int{n}, numbers...)
+		parts = parts[:len(parts)-1]
+	}
+	problem.File = strings.Join(parts, ":")
+	if len(numbers) > 0 {
+		problem.Line = numbers[0]
+	}
+	if len(numbers) > 1 {
+		problem.Column = numbers[1]
+	}
+	return problem
+}
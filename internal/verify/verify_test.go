@@ -0,0 +1,176 @@
+package verify
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/packages"
+)
+
+// writeModule writes the files of a module named example.com/api
+func writeModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	files["go.mod"] = "module example.com/api\n\ngo 1.21\n"
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	return dir
+}
+
+func TestModule(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"domain/types.go": "package domain\n\ntype Pet struct {\n\tName string\n}\n",
+		"service/service.go": `package service
+
+import "example.com/api/domain"
+
+func Name(pet domain.Pet) string {
+	return pet.Tag
+}
+`,
+		"service/service_test.go": `package service
+
+import "testing"
+
+func TestName(t *testing.T) {
+	var count int = Name
+	_ = count
+}
+`,
+	})
+
+	problems, err := Module(dir)
+	require.NoError(t, err)
+	require.Len(t, problems, 2)
+
+	assert.Equal(t, filepath.Join(dir, "service", "service.go"), problems[0].File)
+	assert.Equal(t, 6, problems[0].Line)
+	assert.Equal(t, 13, problems[0].Column)
+	assert.Contains(t, problems[0].Msg, "pet.Tag undefined")
+
+	assert.Equal(t, filepath.Join(dir, "service", "service_test.go"), problems[1].File)
+	assert.Equal(t, 6, problems[1].Line)
+}
+
+func TestModule_Clean(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"domain/types.go":        "package domain\n\ntype Pet struct {\n\tName string\n}\n",
+		"domain/types_test.go":   "package domain\n\nimport \"testing\"\n\nfunc TestPet(t *testing.T) { _ = Pet{Name: \"Rex\"} }\n",
+		"cmd/api/main.go":        "package main\n\nimport \"example.com/api/domain\"\n\nfunc main() { _ = domain.Pet{} }\n",
+		"internal/config/doc.go": "// Package config is empty\npackage config\n",
+	})
+
+	problems, err := Module(dir)
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+}
+
+func TestModule_MissingDependency(t *testing.T) {
+	// The dependency is neither required by go.mod nor downloaded
+	dir := writeModule(t, map[string]string{
+		"main.go": "package main\n\nimport \"example.com/missing/chi\"\n\nfunc main() { chi.NewRouter() }\n",
+	})
+
+	problems, err := Module(dir)
+	require.NoError(t, err)
+	require.NotEmpty(t, problems)
+	assert.Contains(t, problems[0].Msg, "example.com/missing/chi")
+}
+
+func TestTree(t *testing.T) {
+	files := map[string][]byte{
+		"domain/types.go": []byte("package domain\n\ntype Pet struct {\n\tName string\n}\n"),
+		"service/service.go": []byte(`package service
+
+import (
+	"strings"
+
+	"example.com/api/domain"
+	"github.com/go-chi/chi/v5"
+)
+
+func Name(pet domain.Pet) string {
+	chi.NewRouter().Undefined()
+	return strings.ToUpper(pet.Tag)
+}
+`),
+		"service/service_test.go": []byte(`package service_test
+
+import (
+	"testing"
+
+	"example.com/api/service"
+)
+
+func TestName(t *testing.T) {
+	var count int = service.Name
+	_ = count
+}
+`),
+		"service/mocks/mock.go": []byte(`package mocks
+
+import "github.com/stretchr/testify/mock"
+
+type Repository struct {
+	mock.Mock
+}
+
+func (r *Repository) Get() error {
+	return r.Called().Error(0)
+}
+`),
+		"http/handler.go": []byte("package http\n\nimport \"example.com/api/services/\"\n\nvar _ = health.Service\n"),
+		"README.md":       []byte("Not Go"),
+	}
+
+	// Code using other modules, including the methods they promote, is not
+	// checked
+	problems, err := Tree("example.com/api", files)
+	require.NoError(t, err)
+	var described []string
+	for _, problem := range problems {
+		described = append(described, problem.String())
+	}
+	require.Len(t, problems, 4, "problems:\n%s", strings.Join(described, "\n"))
+	assert.Equal(t, "http/handler.go", problems[0].File)
+	assert.Contains(t, problems[0].Msg, "example.com/api/services/")
+	assert.Contains(t, problems[1].Msg, "undefined: health")
+	assert.Equal(t, Problem{File: "service/service.go", Line: 12, Column: 29, Msg: "pet.Tag undefined (type domain.Pet has no field or method Tag)"}, problems[2])
+	assert.Equal(t, "service/service_test.go", problems[3].File)
+
+	problems, err = Tree("example.com/api", map[string][]byte{"main.go": []byte("package main\n\nfunc main() {\n")})
+	require.NoError(t, err)
+	require.Len(t, problems, 1)
+	assert.Equal(t, "main.go", problems[0].File)
+}
+
+func TestNewProblem(t *testing.T) {
+	tests := []struct {
+		pos      string
+		expected Problem
+	}{
+		{"/src/api/main.go:12:5", Problem{File: "/src/api/main.go", Line: 12, Column: 5, Msg: "msg"}},
+		{"/src/api/main.go:12", Problem{File: "/src/api/main.go", Line: 12, Msg: "msg"}},
+		{`C:\src\api\main.go:3:1`, Problem{File: `C:\src\api\main.go`, Line: 3, Column: 1, Msg: "msg"}},
+		{"-", Problem{Msg: "msg"}},
+		{"", Problem{Msg: "msg"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pos, func(t *testing.T) {
+			assert.Equal(t, tt.expected, newProblem(packages.Error{Pos: tt.pos, Msg: "msg"}))
+		})
+	}
+}
+
+func TestProblem_String(t *testing.T) {
+	assert.Equal(t, "/api/main.go:3:7: undefined: x", Problem{File: "/api/main.go", Line: 3, Column: 7, Msg: "undefined: x"}.String())
+	assert.Equal(t, "/api/main.go:3: undefined: x", Problem{File: "/api/main.go", Line: 3, Msg: "undefined: x"}.String())
+	assert.Equal(t, "no Go files", Problem{Msg: "no Go files"}.String())
+}
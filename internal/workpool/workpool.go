@@ -0,0 +1,60 @@
+// Package workpool runs independent jobs on a bounded number of goroutines.
+// Jobs are identified by index and store their results by index, so that
+// the results keep the order of the inputs whatever the scheduling.
+package workpool
+
+import (
+	"runtime"
+	"sync"
+)
+
+// Size returns the number of workers to run: workers, or one per CPU when
+// workers is not positive
+func Size(workers int) int {
+	if workers > 0 {
+		return workers
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// Run calls job for every index below n on at most Size(workers)
+// goroutines. Every job runs even when another fails, and the error of the
+// lowest failing index is returned, so that the same input reports the same
+// error on every run.
+func Run(workers, n int, job func(i int) error) error {
+	errs := make([]error, n)
+	workers = min(Size(workers), n)
+	if workers <= 1 {
+		for i := range n {
+			errs[i] = job(i)
+		}
+		return firstError(errs)
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				errs[i] = job(i)
+			}
+		}()
+	}
+	for i := range n {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return firstError(errs)
+}
+
+func firstError(errs []error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package workpool
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	for _, workers := range []int{0, 1, 4, 100} {
+		t.Run(fmt.Sprintf("workers_%d", workers), func(t *testing.T) {
+			results := make([]int, 50)
+			var running, peak atomic.Int32
+			err := Run(workers, len(results), func(i int) error {
+				current := running.Add(1)
+				defer running.Add(-1)
+				for {
+					max := peak.Load()
+					if current <= max || peak.CompareAndSwap(max, current) {
+						break
+					}
+				}
+				results[i] = i * i
+				return nil
+			})
+			assert.NoError(t, err)
+			for i, result := range results {
+				assert.Equal(t, i*i, result)
+			}
+			assert.LessOrEqual(t, int(peak.Load()), Size(workers))
+		})
+	}
+}
+
+func TestRun_Errors(t *testing.T) {
+	var ran atomic.Int32
+	err := Run(4, 20, func(i int) error {
+		ran.Add(1)
+		if i%7 == 5 {
+			return fmt.Errorf("job %d failed", i)
+		}
+		return nil
+	})
+	assert.EqualError(t, err, "job 5 failed", "The error of the lowest index should be returned")
+	assert.Equal(t, int32(20), ran.Load(), "Every job should run")
+
+	assert.NoError(t, Run(4, 0, func(int) error { return fmt.Errorf("no jobs") }))
+}
@@ -0,0 +1,85 @@
+package integration
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	cli "github.com/zeek-r/goapigen/cmd/goapigen"
+)
+
+// enumSpec has a schema with an enum property, whose domain package needs
+// nothing but the standard library
+const enumSpec = `openapi: 3.0.0
+info:
+  title: Enum API
+  version: 1.0.0
+paths: {}
+components:
+  schemas:
+    Pet:
+      type: object
+      properties:
+        name:
+          type: string
+        status:
+          type: string
+          enum: [available, sold]
+`
+
+// enumTest decodes the enum of enumSpec from JSON, null included
+const enumTest = `package domain
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPetStatusJSON(t *testing.T) {
+	tests := []struct {
+		data    string
+		status  PetStatus
+		wantErr bool
+	}{
+		{data: ` + "`" + `{"status": "sold"}` + "`" + `, status: PetStatusSold},
+		{data: ` + "`" + `{"status": null}` + "`" + `},
+		{data: ` + "`" + `{"name": "Rex"}` + "`" + `},
+		{data: ` + "`" + `{"status": "lost"}` + "`" + `, wantErr: true},
+	}
+	for _, tt := range tests {
+		var pet Pet
+		err := json.Unmarshal([]byte(tt.data), &pet)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: unexpected error %v", tt.data, err)
+		}
+		if !tt.wantErr && pet.Status != tt.status {
+			t.Errorf("%s: expected status %q, got %q", tt.data, tt.status, pet.Status)
+		}
+	}
+}
+`
+
+// TestEnumJSON runs a test of the JSON decoding of a generated enum against
+// the generated domain package, offline
+func TestEnumJSON(t *testing.T) {
+	spec := filepath.Join(t.TempDir(), "enum.yaml")
+	require.NoError(t, os.WriteFile(spec, []byte(enumSpec), 0644))
+	files := planFiles(t, goldenCase{name: "enum", spec: spec, config: cli.GenerationConfig{GenTypes: true}})
+
+	dir := t.TempDir()
+	files["go.mod"] = []byte("module " + goldenModule + "\n\ngo 1.24\n")
+	files["internal/pkg/domain/enum_test.go"] = []byte(enumTest)
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, content, 0644))
+	}
+
+	cmd := exec.Command("go", "test", "./internal/pkg/domain")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off", "GOWORK=off")
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, "Generated enum should decode JSON. Output: %s", string(output))
+}
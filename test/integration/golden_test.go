@@ -0,0 +1,130 @@
+package integration
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	cli "github.com/zeek-r/goapigen/cmd/goapigen"
+	"github.com/zeek-r/goapigen/internal/config"
+	"github.com/zeek-r/goapigen/internal/generator"
+	"github.com/zeek-r/goapigen/internal/testutil"
+	"github.com/zeek-r/goapigen/internal/verify"
+)
+
+// goldenDir holds the snapshots of the golden cases, one directory each
+const goldenDir = "testdata/golden"
+
+// goldenModule is the module of the generated code, named after the output
+// directory
+const goldenModule = "api"
+
+// update rewrites the golden files with the current output instead of
+// comparing it with them
+var update = flag.Bool("update", false, "Rewrite the golden files with the current output")
+
+// goldenCase generates a spec of the corpus with a configuration
+type goldenCase struct {
+	name   string
+	spec   string
+	config cli.GenerationConfig
+}
+
+// goldenCases cover the petstore example with the default and alternative
+// options, and the edge cases of testdata/specs with every component
+var goldenCases = []goldenCase{
+	{
+		name: "petstore",
+		spec: "../../examples/petstore/openapi.yaml",
+		config: cli.GenerationConfig{
+			GenTypes: true, GenServices: true, GenMongo: true, GenHTTP: true, InitProject: true,
+		},
+	},
+	{
+		name: "petstore_flat_generic_split",
+		spec: "../../examples/petstore/openapi.yaml",
+		config: cli.GenerationConfig{
+			GenTypes: true, GenServices: true, GenHTTP: true,
+			OptionalStrategy: generator.OptionalGeneric,
+			SplitTypes:       true,
+			Layout:           config.LayoutConfig{Preset: "flat"},
+		},
+	},
+	{
+		name: "formats",
+		spec: "testdata/specs/formats.yaml",
+		config: cli.GenerationConfig{
+			GenTypes: true, GenServices: true, GenMongo: true, GenHTTP: true,
+			OptionalStrategy: generator.OptionalPointer,
+		},
+	},
+	{
+		name: "composition",
+		spec: "testdata/specs/composition.yaml",
+		config: cli.GenerationConfig{
+			GenTypes: true, GenServices: true, GenMongo: true, GenHTTP: true, InitProject: true,
+			Layout: config.LayoutConfig{Preset: "hexagonal"},
+		},
+	},
+}
+
+// TestGolden compares every file the pipeline plans for the corpus with its
+// snapshot in testdata/golden. After an intended change to the generated
+// code, go test ./test/integration -run TestGolden -update rewrites them.
+// The files must type-check, so that no snapshot accepts broken code.
+func TestGolden(t *testing.T) {
+	for _, tc := range goldenCases {
+		t.Run(tc.name, func(t *testing.T) {
+			files := planFiles(t, tc)
+			testutil.AssertGolden(t, filepath.Join(goldenDir, tc.name), files, *update)
+
+			problems, err := verify.Tree(goldenModule, files)
+			require.NoError(t, err)
+			for _, problem := range problems {
+				t.Errorf("generated code does not type-check: %s", problem)
+			}
+		})
+	}
+}
+
+// planFiles plans the generation of a case without writing anything. The
+// spec is copied next to the output directory, named api after the module,
+// so that the paths and imports of the plan are the same on every machine.
+func planFiles(t *testing.T, tc goldenCase) map[string][]byte {
+	t.Helper()
+
+	spec, err := os.ReadFile(tc.spec)
+	require.NoError(t, err)
+	root := t.TempDir()
+	specFile := filepath.Join(root, filepath.Base(tc.spec))
+	require.NoError(t, os.WriteFile(specFile, spec, 0644))
+
+	// goapigen.yaml records a relative spec path relative to the output
+	// directory, as ../<spec>
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	specPath, err := filepath.Rel(wd, specFile)
+	require.NoError(t, err)
+
+	cfg := tc.config
+	cfg.PackageName = config.DefaultAPIPackage
+	cfg.HTTPPackage = config.DefaultHandlerPackage
+	cfg.SpecFiles = []string{specPath}
+	cfg.OutputDir = filepath.Join(root, goldenModule)
+	cfg.DryRun = true
+
+	pipeline, err := cli.NewGenerationPipeline(&cfg, cli.Templates())
+	require.NoError(t, err)
+	pipeline.SetOutput(io.Discard)
+	plan, err := pipeline.Plan()
+	require.NoError(t, err)
+
+	files := make(map[string][]byte, len(plan.Files))
+	for _, file := range plan.Files {
+		files[file.Path] = file.Content
+	}
+	return files
+}